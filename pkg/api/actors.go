@@ -27,6 +27,7 @@ func (i ActorResource) WebService() *restful.WebService {
 	ws := new(restful.WebService)
 
 	ws.Path("/api/actor").
+		Filter(userFilter).
 		Consumes(restful.MIME_JSON).
 		Produces(restful.MIME_JSON)
 
@@ -217,14 +218,16 @@ func (i ActorResource) getActor(req *restful.Request, resp *restful.Response) {
 	db, _ := models.GetDB()
 
 	if strings.Contains(req.PathParameter("actor-id"), "-") {
-		actor.GetIfExist(req.PathParameter("actor-id"))
+		if actor.GetIfExist(req.PathParameter("actor-id")) == nil {
+			actor.ApplyUserState(getUserID(req))
+		}
 	} else {
 		id, err := strconv.Atoi(req.PathParameter("actor-id"))
 		if err != nil {
 			log.Error(err)
 			return
 		}
-		_ = actor.GetIfExistByPKWithSceneAvg(uint(id))
+		_ = actor.GetIfExistByPKWithSceneAvg(uint(id), getUserID(req))
 	}
 	db.Close()
 
@@ -239,6 +242,7 @@ func (i ActorResource) getActors(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
+	r.UserID = getUserID(req)
 	out := models.QueryActors(r, true)
 	resp.WriteHeaderAndEntity(http.StatusOK, out)
 }
@@ -261,8 +265,9 @@ func (i ActorResource) rateActor(req *restful.Request, resp *restful.Response) {
 	db, _ := models.GetDB()
 	err = db.Where(models.Actor{ID: uint(actorId)}).First(&actor).Error
 	if err == nil {
+		actor.ApplyUserState(getUserID(req))
 		actor.StarRating = r.Rating
-		actor.Save()
+		actor.SaveUserState(getUserID(req))
 	}
 	db.Close()
 
@@ -340,6 +345,7 @@ func (i ActorResource) toggleList(req *restful.Request, resp *restful.Response)
 		log.Error(err)
 		return
 	}
+	actor.ApplyUserState(getUserID(req))
 
 	switch r.List {
 	case "watchlist":
@@ -351,7 +357,7 @@ func (i ActorResource) toggleList(req *restful.Request, resp *restful.Response)
 		// case "is_hidden":
 		// 	actor.IsHidden = !actor.IsHidden
 	}
-	actor.Save()
+	actor.SaveUserState(getUserID(req))
 }
 
 func (i ActorResource) editActor(req *restful.Request, resp *restful.Response) {
@@ -423,6 +429,7 @@ func (i ActorResource) editActor(req *restful.Request, resp *restful.Response) {
 	checkStringArrayChanged("urls", &r.URLs, &actor.URLs, actor.ID)
 
 	actor.Save()
+	actor.ApplyUserState(getUserID(req))
 
 	resp.WriteHeaderAndEntity(http.StatusOK, actor)
 }
@@ -440,6 +447,7 @@ func (i ActorResource) deleteActor(req *restful.Request, resp *restful.Response)
 
 	db.Exec(`delete from actor_akas where actor_id=?`, id)
 	db.Where("actor_id = ?", uint(id)).Delete(&models.ActionActor{})
+	db.Where("actor_id = ?", uint(id)).Delete(&models.UserActor{})
 	db.Where("internal_table = 'actors' and internal_db_id = ?", uint(id)).Delete(&models.ExternalReferenceLink{})
 	db.Where("id = ?", uint(id)).Delete(&models.Actor{})
 
@@ -514,7 +522,7 @@ func (i ActorResource) setActorImage(req *restful.Request, resp *restful.Respons
 	defer db.Close()
 
 	var actor models.Actor
-	err = actor.GetIfExistByPKWithSceneAvg(r.ActorID)
+	err = actor.GetIfExistByPKWithSceneAvg(r.ActorID, getUserID(req))
 	if err != nil {
 		log.Error(err)
 		return
@@ -544,7 +552,7 @@ func (i ActorResource) deleteActorImage(req *restful.Request, resp *restful.Resp
 	defer db.Close()
 
 	var actor models.Actor
-	err = actor.GetIfExistByPKWithSceneAvg(r.ActorID)
+	err = actor.GetIfExistByPKWithSceneAvg(r.ActorID, getUserID(req))
 	if err != nil {
 		log.Error(err)
 		return
@@ -604,11 +612,11 @@ func (i ActorResource) getActorAkas(req *restful.Request, resp *restful.Response
 		var akagrp models.Aka
 		db.Preload("Akas").Preload("AkaActor").Where("aka_actor_id = ? ", actor.ID).Find(&akagrp)
 		// reread actor to get full Preloads
-		akagrp.AkaActor.GetIfExistByPKWithSceneAvg(akagrp.AkaActor.ID)
+		akagrp.AkaActor.GetIfExistByPKWithSceneAvg(akagrp.AkaActor.ID, getUserID(req))
 		for _, actor := range akagrp.Akas {
 			if actor.ID != uint(actor_id) {
 				// reread actor to get full Preloads
-				actor.GetIfExistByPKWithSceneAvg(actor.ID)
+				actor.GetIfExistByPKWithSceneAvg(actor.ID, getUserID(req))
 				akaresp.Actors = append(akaresp.Actors, actor)
 			}
 		}
@@ -618,12 +626,12 @@ func (i ActorResource) getActorAkas(req *restful.Request, resp *restful.Response
 			var akagrp models.Aka
 			db.Preload("Akas").Preload("AkaActor").Where("id = ?", grp.ID).Find(&akagrp)
 			// reread actor to get full Preloads
-			akagrp.AkaActor.GetIfExistByPKWithSceneAvg(akagrp.AkaActor.ID)
+			akagrp.AkaActor.GetIfExistByPKWithSceneAvg(akagrp.AkaActor.ID, getUserID(req))
 			akaresp.AkaGroups = append(akaresp.AkaGroups, akagrp.AkaActor)
 			for _, actor := range akagrp.Akas {
 				if actor.ID != uint(actor_id) {
 					// reread actor to get full Preloads
-					actor.GetIfExistByPKWithSceneAvg(actor.ID)
+					actor.GetIfExistByPKWithSceneAvg(actor.ID, getUserID(req))
 					akaresp.Actors = append(akaresp.Actors, actor)
 				}
 			}
@@ -656,7 +664,7 @@ func (i ActorResource) getActorAkas(req *restful.Request, resp *restful.Response
 			}
 		}
 		if !found {
-			possible.GetIfExistByPKWithSceneAvg(possible.ID)
+			possible.GetIfExistByPKWithSceneAvg(possible.ID, getUserID(req))
 			akaresp.PossibleAkas = append(akaresp.PossibleAkas, possible)
 		}
 	}
@@ -681,7 +689,7 @@ func (i ActorResource) getActorColleagues(req *restful.Request, resp *restful.Re
 		Find(&colleagues)
 
 	for idx, actor := range colleagues {
-		actor.GetIfExistByPKWithSceneAvg(actor.ID)
+		actor.GetIfExistByPKWithSceneAvg(actor.ID, getUserID(req))
 		colleagues[idx] = actor
 	}
 	resp.WriteHeaderAndEntity(http.StatusOK, colleagues)
@@ -191,11 +191,15 @@ func setDeoPlayerHost(req *restful.Request) {
 }
 
 func restfulAuthFilter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
-	if isDeoAuthEnabled() {
-		authState := "0"
+	username, _ := req.BodyParameter("login")
+	password, _ := req.BodyParameter("password")
+
+	// with authentication on, only profiles protected by a password may log in instead of the DeoVR account
+	user, isProfile := models.ResolveUser(username, password)
+	isProfile = isProfile && (user.Password != "" || !isDeoAuthEnabled())
 
-		username, _ := req.BodyParameter("login")
-		password, _ := req.BodyParameter("password")
+	if isDeoAuthEnabled() && !isProfile {
+		authState := "0"
 
 		if username != "" && password != "" {
 			cmpErr := bcrypt.CompareHashAndPassword([]byte(config.Config.Interfaces.DeoVR.Password), []byte(password))
@@ -224,6 +228,10 @@ func restfulAuthFilter(req *restful.Request, resp *restful.Response, chain *rest
 			return
 		}
 	}
+	if !isProfile {
+		user = models.GetDefaultUser()
+	}
+	setPlayerUser(req, user)
 	chain.ProcessFilter(req, resp)
 }
 
@@ -354,6 +362,7 @@ func (i DeoVRResource) getDeoScene(req *restful.Request, resp *restful.Response)
 			Preload("Files").
 			Where("id = ?", sceneID).First(&scene)
 	}
+	scene.ApplyUserState(getUserID(req))
 
 	var stereoMode string = ""
 	var screenType string = ""
@@ -410,6 +419,19 @@ func (i DeoVRResource) getDeoScene(req *restful.Request, resp *restful.Response)
 			sourcesSpatial = append(sourcesSpatial, source)
 		}
 
+		if variants := getHLSVariants(file); len(variants) > 0 {
+			hlsSource := DeoSceneEncoding{Name: fmt.Sprintf("File %v/%v HLS", i+1, len(videoFiles))}
+			for _, v := range variants {
+				hlsSource.VideoSources = append(hlsSource.VideoSources, DeoSceneVideoSource{
+					Resolution: v.Height,
+					Height:     v.Height,
+					Width:      v.Width,
+					URL:        session.DeoRequestHost + v.URL,
+				})
+			}
+			sources = append(sources, hlsSource)
+		}
+
 		videoLength = file.VideoDuration
 
 		// Test scene w/multiple videos for different projection types
@@ -418,6 +440,22 @@ func (i DeoVRResource) getDeoScene(req *restful.Request, resp *restful.Response)
 		}
 	}
 
+	mobileFiles, _ := scene.GetMobileFiles()
+	for _, file := range mobileFiles {
+		sources = append(sources, DeoSceneEncoding{
+			Name: fmt.Sprintf("Mobile %vp - %v", file.VideoHeight, humanize.Bytes(uint64(file.Size))),
+			VideoSources: []DeoSceneVideoSource{
+				{
+					Resolution: file.VideoHeight,
+					Height:     file.VideoHeight,
+					Width:      file.VideoWidth,
+					Size:       file.Size,
+					URL:        fmt.Sprintf("%v/api/dms/file/%v/%v%v", session.DeoRequestHost, file.ID, scene.GetFunscriptTitle(), dnt),
+				},
+			},
+		})
+	}
+
 	var deoScriptFiles []DeoSceneScriptFile
 	var scriptFiles []models.File
 	scriptFiles, err = scene.GetScriptFilesSorted(config.Config.Interfaces.Players.ScriptSortSeq)
@@ -457,6 +495,13 @@ func (i DeoVRResource) getDeoScene(req *restful.Request, resp *restful.Response)
 			Name: scene.Cuepoints[i].Name,
 		})
 	}
+	// DeoVR has no resume setting, the position is offered as a timestamp to jump to
+	if pos, ok := models.GetResumePosition(getUserID(req), scene.ID); ok {
+		cuepoints = append(cuepoints, DeoSceneTimestamp{
+			TS:   uint(pos.Position),
+			Name: "Resume",
+		})
+	}
 	sort.Slice(cuepoints, func(i, j int) bool {
 		return cuepoints[i].TS < cuepoints[j].TS
 	})
@@ -625,7 +670,7 @@ func (i DeoVRResource) getDeoLibrary(req *restful.Request, resp *restful.Respons
 	var sceneLists []DeoListScenes
 
 	var savedPlaylists []models.Playlist
-	db.Where("is_deo_enabled = ?", true).Order("ordering asc").Find(&savedPlaylists)
+	db.Scopes(models.VisibleToUser(getUserID(req))).Where("is_deo_enabled = ?", true).Order("ordering asc").Find(&savedPlaylists)
 
 	for i := range savedPlaylists {
 		var r models.RequestSceneList
@@ -633,6 +678,7 @@ func (i DeoVRResource) getDeoLibrary(req *restful.Request, resp *restful.Respons
 		if err := json.Unmarshal([]byte(savedPlaylists[i].SearchParams), &r); err == nil {
 			r.IsAccessible = optional.NewBool(true)
 			r.IsAvailable = optional.NewBool(true)
+			r.UserID = getUserID(req)
 
 			summaries := models.QuerySceneSummaries(r)
 			sceneLists = append(sceneLists, DeoListScenes{
@@ -642,6 +688,13 @@ func (i DeoVRResource) getDeoLibrary(req *restful.Request, resp *restful.Respons
 		}
 	}
 
+	if summaries := models.QuerySceneSummaries(continueWatchingRequest(req)); len(summaries) > 0 {
+		sceneLists = append([]DeoListScenes{{
+			Name: "Continue watching",
+			List: scenesToDeoList(req, summaries),
+		}}, sceneLists...)
+	}
+
 	// Add unmatched files at the end
 	var unmatched []models.File
 	db.Model(&unmatched).
@@ -662,6 +715,18 @@ func (i DeoVRResource) getDeoLibrary(req *restful.Request, resp *restful.Respons
 	})
 }
 
+// continueWatchingRequest lists the scenes the profile of the player stopped playing before the end
+func continueWatchingRequest(req *restful.Request) models.RequestSceneList {
+	return models.RequestSceneList{
+		Lists:        []optional.String{optional.NewString("continue_watching")},
+		IsAccessible: optional.NewBool(true),
+		IsAvailable:  optional.NewBool(true),
+		Sort:         optional.NewString("last_played_desc"),
+		UserID:       getUserID(req),
+		Limit:        optional.NewInt(50),
+	}
+}
+
 func scenesToDeoList(req *restful.Request, scenes []models.SceneSummary) []DeoListItem {
 	setDeoPlayerHost(req)
 
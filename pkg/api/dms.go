@@ -3,19 +3,83 @@ package api
 import (
 	"context"
 	"fmt"
+	"image/png"
+	"math"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	restfulspec "github.com/emicklei/go-restful-openapi/v2"
 	"github.com/emicklei/go-restful/v3"
 	"github.com/jinzhu/gorm"
 
 	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/engagement"
+	"github.com/xbapps/xbvr/pkg/hls"
 	"github.com/xbapps/xbvr/pkg/models"
 	"github.com/xbapps/xbvr/pkg/session"
+	"github.com/xbapps/xbvr/pkg/tasks"
 )
 
+const mimeHLSPlaylist = "application/vnd.apple.mpegurl"
+
+var (
+	hlsTranscoder     *hls.Transcoder
+	hlsTranscoderLock sync.Mutex
+)
+
+func getHLSTranscoder() *hls.Transcoder {
+	hlsTranscoderLock.Lock()
+	defer hlsTranscoderLock.Unlock()
+
+	if hlsTranscoder == nil {
+		hlsTranscoder = hls.NewTranscoder(
+			filepath.Join(common.CacheDir, "hls"),
+			tasks.GetBinPath("ffmpeg"),
+			config.Config.Interfaces.HLS.MaxTranscodes,
+			int64(config.Config.Interfaces.HLS.CacheSizeGB)<<30)
+	}
+	return hlsTranscoder
+}
+
+// updateHLSTranscoder applies changed HLS options to a running transcoder
+func updateHLSTranscoder() {
+	t := getHLSTranscoder()
+	t.SetMaxConcurrent(config.Config.Interfaces.HLS.MaxTranscodes)
+	t.SetMaxCacheSize(int64(config.Config.Interfaces.HLS.CacheSizeGB) << 30)
+}
+
+type HLSVariant struct {
+	Profile string
+	Width   int
+	Height  int
+	URL     string
+}
+
+// getHLSVariants lists the transcoded renditions offered for a video file, with URLs relative to
+// the server root
+func getHLSVariants(f models.File) []HLSVariant {
+	if !config.Config.Interfaces.HLS.Enabled || f.Type != "video" || f.VideoDuration <= 0 || f.Volume.Type == "putio" {
+		return nil
+	}
+
+	var out []HLSVariant
+	for _, p := range hls.ProfilesFor(f.VideoWidth) {
+		w, h := p.Size(f.VideoWidth, f.VideoHeight)
+		out = append(out, HLSVariant{
+			Profile: p.Name,
+			Width:   w,
+			Height:  h,
+			URL:     fmt.Sprintf("/api/dms/hls/%v/%v/index.m3u8", f.ID, p.Name),
+		})
+	}
+	return out
+}
+
 type DMSResource struct{}
 
 func (i DMSResource) WebService() *restful.WebService {
@@ -37,11 +101,46 @@ func (i DMSResource) WebService() *restful.WebService {
 		ContentEncodingEnabled(false).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.GET("/hls/{file-id}/master.m3u8").To(i.getHLSMaster).
+		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
+		ContentEncodingEnabled(false).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	ws.Route(ws.GET("/hls/{file-id}/{profile}/index.m3u8").To(i.getHLSPlaylist).
+		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
+		Param(ws.PathParameter("profile", "Profile name")).
+		ContentEncodingEnabled(false).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	ws.Route(ws.GET("/hls/{file-id}/{profile}/{segment}").To(i.getHLSSegment).
+		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
+		Param(ws.PathParameter("profile", "Profile name")).
+		Param(ws.PathParameter("segment", "Segment, eg 0.ts")).
+		ContentEncodingEnabled(false).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
 	ws.Route(ws.GET("/heatmap/{file-id}").To(i.getHeatmap).
 		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
 		ContentEncodingEnabled(false).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.GET("/engagement/{file-id}").To(i.getEngagement).
+		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes(ResponseEngagement{}))
+
+	ws.Route(ws.GET("/engagement/{file-id}/heatmap.png").To(i.getEngagementHeatmap).
+		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
+		Param(ws.QueryParameter("width", "Width of the image").DataType("int").DefaultValue("1000")).
+		Param(ws.QueryParameter("height", "Height of the image").DataType("int").DefaultValue("10")).
+		ContentEncodingEnabled(false).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	ws.Route(ws.POST("/engagement/{file-id}/cuepoints").To(i.addEngagementCuepoints).
+		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes(models.Scene{}))
+
 	ws.Route(ws.GET("/preview/{scene-id}").To(i.getPreview).
 		Param(ws.PathParameter("scene-id", "Scene ID")).
 		ContentEncodingEnabled(false).
@@ -60,6 +159,222 @@ func (i DMSResource) getHeatmap(req *restful.Request, resp *restful.Response) {
 	http.ServeFile(resp.ResponseWriter, req.Request, filepath.Join(common.ScriptHeatmapDir, fmt.Sprintf("heatmap-%v.png", fileID)))
 }
 
+type ResponseEngagement struct {
+	engagement.Heatmap
+	MostReplayed []engagement.Segment `json:"most_replayed"`
+}
+
+// number of most replayed segments offered as cuepoints
+const mostReplayedSegments = 5
+
+func (i DMSResource) getEngagement(req *restful.Request, resp *restful.Response) {
+	fileID, err := strconv.Atoi(req.PathParameter("file-id"))
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	h, err := engagement.Load(uint(fileID))
+	if err != nil {
+		APIError(req, resp, http.StatusInternalServerError, err)
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, ResponseEngagement{Heatmap: h, MostReplayed: h.MostReplayed(mostReplayedSegments)})
+}
+
+func (i DMSResource) getEngagementHeatmap(req *restful.Request, resp *restful.Response) {
+	fileID, err := strconv.Atoi(req.PathParameter("file-id"))
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	width, err := strconv.Atoi(req.QueryParameter("width"))
+	if err != nil || width <= 0 || width > 4000 {
+		width = 1000
+	}
+	height, err := strconv.Atoi(req.QueryParameter("height"))
+	if err != nil || height <= 0 || height > 500 {
+		height = 10
+	}
+
+	h, err := engagement.Load(uint(fileID))
+	if err != nil {
+		APIError(req, resp, http.StatusInternalServerError, err)
+		return
+	}
+	if h.Sessions == 0 {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "image/png")
+	png.Encode(resp.ResponseWriter, h.Render(width, height))
+}
+
+// addEngagementCuepoints adds the most replayed segments of a file to its scene as cuepoints. The
+// parts of a multipart scene are taken to play in the order of their filenames.
+func (i DMSResource) addEngagementCuepoints(req *restful.Request, resp *restful.Response) {
+	fileID, err := strconv.Atoi(req.PathParameter("file-id"))
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	var f models.File
+	if err := db.First(&f, fileID).Error; err != nil || f.SceneID == 0 {
+		APIError(req, resp, http.StatusNotFound, fmt.Errorf("file %v isn't matched to a scene", fileID))
+		return
+	}
+	var scene models.Scene
+	if err := db.Preload("Cuepoints").First(&scene, f.SceneID).Error; err != nil {
+		APIError(req, resp, http.StatusNotFound, err)
+		return
+	}
+	// cuepoints belong to the scene, the times of a part come after the parts before it
+	var offset float64
+	if scene.IsMultipart {
+		parts, _ := scene.GetVideoFilesSorted("filename")
+		for _, part := range parts {
+			if part.ID == f.ID {
+				break
+			}
+			if part.VideoDuration <= 0 {
+				APIError(req, resp, http.StatusBadRequest, fmt.Errorf("duration of part %v of scene %v is unknown", part.Filename, scene.ID))
+				return
+			}
+			offset += part.VideoDuration
+		}
+	}
+
+	h, err := engagement.Load(f.ID)
+	if err != nil {
+		APIError(req, resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, segment := range h.MostReplayed(mostReplayedSegments) {
+		start, end := offset+segment.Start, offset+segment.End
+		exists := false
+		for _, c := range scene.Cuepoints {
+			if math.Abs(c.TimeStart-start) < 5 {
+				exists = true
+			}
+		}
+		if !exists {
+			c := models.SceneCuepoint{SceneID: scene.ID, TimeStart: start, TimeEnd: end, Name: "Most replayed"}
+			c.Save()
+		}
+	}
+
+	scene.GetIfExistByPK(scene.ID)
+	scene.ApplyUserState(getUserID(req))
+	resp.WriteHeaderAndEntity(http.StatusOK, scene)
+}
+
+// getHLSFile loads a video file that can be transcoded, writing an error response if it can't
+func getHLSFile(req *restful.Request, resp *restful.Response) (models.File, bool) {
+	f := models.File{}
+	if !config.Config.Interfaces.HLS.Enabled {
+		resp.WriteHeader(http.StatusNotFound)
+		return f, false
+	}
+
+	id, err := strconv.Atoi(req.PathParameter("file-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return f, false
+	}
+
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	if err := db.Preload("Volume").First(&f, id).Error; err != nil || f.Type != "video" || f.VideoDuration <= 0 {
+		resp.WriteHeader(http.StatusNotFound)
+		return f, false
+	}
+	return f, true
+}
+
+func (i DMSResource) getHLSMaster(req *restful.Request, resp *restful.Response) {
+	f, ok := getHLSFile(req, resp)
+	if !ok {
+		return
+	}
+
+	resp.Header().Set("Content-Type", mimeHLSPlaylist)
+	resp.Write([]byte(hls.MasterPlaylist(hls.ProfilesFor(f.VideoWidth), f.VideoWidth, f.VideoHeight)))
+}
+
+func (i DMSResource) getHLSPlaylist(req *restful.Request, resp *restful.Response) {
+	f, ok := getHLSFile(req, resp)
+	if !ok {
+		return
+	}
+	if _, ok := hls.GetProfile(req.PathParameter("profile")); !ok {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	resp.Header().Set("Content-Type", mimeHLSPlaylist)
+	resp.Write([]byte(hls.MediaPlaylist(f.VideoDuration)))
+}
+
+func (i DMSResource) getHLSSegment(req *restful.Request, resp *restful.Response) {
+	f, ok := getHLSFile(req, resp)
+	if !ok {
+		return
+	}
+	profile, ok := hls.GetProfile(req.PathParameter("profile"))
+	if !ok {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	index, err := strconv.Atoi(strings.TrimSuffix(req.PathParameter("segment"), ".ts"))
+	if err != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	input, err := tasks.GetVideoInput(f)
+	if err != nil {
+		APIError(req, resp, http.StatusNotFound, err)
+		return
+	}
+
+	// the hash keeps a file replaced under the same id from getting stale segments
+	key := fmt.Sprintf("%v-%v", f.ID, f.OsHash)
+	t := getHLSTranscoder()
+	path, err := t.Segment(req.Request.Context(), key, input, profile, index, f.VideoDuration)
+	if err != nil {
+		if req.Request.Context().Err() == nil {
+			log.Errorf("Failed to transcode segment %v of file %v: %v", index, f.ID, err)
+			resp.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	t.Prefetch(key, input, profile, index+1, f.VideoDuration)
+
+	resp.Header().Set("Content-Type", "video/mp2t")
+	http.ServeFile(resp.ResponseWriter, req.Request, path)
+}
+
+// rangeOffset is the first byte of the range requested, 0 if the whole file is requested
+func rangeOffset(r *http.Request) int64 {
+	spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes=")
+	if !ok {
+		return 0
+	}
+	start, _, _ := strings.Cut(spec, "-")
+	offset, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return offset
+}
+
 func (i DMSResource) getFile(req *restful.Request, resp *restful.Response) {
 	doNotTrack := req.QueryParameter("dnt")
 	id, err := strconv.Atoi(req.PathParameter("file-id"))
@@ -75,11 +390,17 @@ func (i DMSResource) getFile(req *restful.Request, resp *restful.Response) {
 	f := models.File{}
 	err = db.Preload("Volume").First(&f, id).Error
 
-	switch f.Volume.Type {
+	volumeType := f.Volume.Type
+	if f.Type == "mobile" {
+		// renditions are local files without a volume
+		volumeType = "local"
+	}
+
+	switch volumeType {
 	case "local":
 		// Track current session
 		setDeoPlayerHost(req)
-		session.TrackSessionFromFile(f, doNotTrack)
+		session.TrackSessionFromFile(session.ClientKey(req.Request.RemoteAddr, req.Request.UserAgent()), f, rangeOffset(req.Request), doNotTrack)
 
 		if err == gorm.ErrRecordNotFound {
 			resp.WriteHeader(http.StatusNotFound)
@@ -90,7 +411,7 @@ func (i DMSResource) getFile(req *restful.Request, resp *restful.Response) {
 		http.ServeFile(resp.ResponseWriter, req.Request, f.GetPath())
 		select {
 		case <-ctx.Done():
-			session.FinishTrackingFromFile(doNotTrack)
+			session.FinishTrackingFromFile(session.ClientKey(req.Request.RemoteAddr, req.Request.UserAgent()), doNotTrack)
 			return
 		default:
 		}
@@ -105,5 +426,30 @@ func (i DMSResource) getFile(req *restful.Request, resp *restful.Response) {
 			return
 		}
 		http.Redirect(resp.ResponseWriter, req.Request, url, http.StatusFound)
+	case "s3":
+		client, meta, err := f.Volume.GetS3Client()
+		if err != nil {
+			return
+		}
+		// long enough for a player to keep seeking through a full scene
+		url, err := client.PresignedGetObject(context.Background(), meta.Bucket, f.Volume.S3ObjectKey(&f), 6*time.Hour, nil)
+		if err != nil {
+			return
+		}
+		http.Redirect(resp.ResponseWriter, req.Request, url.String(), http.StatusFound)
+	case "webdav":
+		// Track current session
+		setDeoPlayerHost(req)
+		session.TrackSessionFromFile(session.ClientKey(req.Request.RemoteAddr, req.Request.UserAgent()), f, rangeOffset(req.Request), doNotTrack)
+
+		// proxied instead of redirected, players can't authenticate against the WebDAV server
+		ctx := req.Request.Context()
+		f.Volume.GetWebDAVClient().Proxy(resp.ResponseWriter, req.Request, f.GetPath())
+		select {
+		case <-ctx.Done():
+			session.FinishTrackingFromFile(session.ClientKey(req.Request.RemoteAddr, req.Request.UserAgent()), doNotTrack)
+			return
+		default:
+		}
 	}
 }
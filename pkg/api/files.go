@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
@@ -15,7 +16,9 @@ import (
 	restfulspec "github.com/emicklei/go-restful-openapi/v2"
 	"github.com/emicklei/go-restful/v3"
 	"github.com/markphelps/optional"
+	"github.com/minio/minio-go/v7"
 	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/tasks"
 )
 
 type RequestMatchFile struct {
@@ -27,6 +30,24 @@ type RequestUnmatchFile struct {
 	FileID uint `json:"file_id"`
 }
 
+type RequestResolveDuplicates struct {
+	KeepFileID    uint   `json:"keep_file_id"`
+	RemoveFileIDs []uint `json:"remove_file_ids"`
+}
+
+type ResponseResolveDuplicates struct {
+	Removed []uint `json:"removed"`
+}
+
+type RequestOrganizeFiles struct {
+	SceneIDs []uint `json:"scene_ids"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+type RequestUndoOrganize struct {
+	Batch string `json:"batch"`
+}
+
 type RequestFileList struct {
 	State       optional.String   `json:"state"`
 	CreatedDate []optional.String `json:"createdDate"`
@@ -57,6 +78,36 @@ func (i FilesResource) WebService() *restful.WebService {
 	ws.Route(ws.POST("/unmatch").To(i.unmatchFile).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.GET("/suggest-matches").To(i.suggestMatches).
+		Param(ws.QueryParameter("file_id", "Only suggest scenes for this file").DataType("int")).
+		Param(ws.QueryParameter("limit", "Maximum number of files").DataType("int").DefaultValue("100")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]tasks.FileMatchSuggestion{}))
+
+	ws.Route(ws.GET("/duplicates").To(i.listDuplicates).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes(tasks.DuplicateReport{}))
+
+	ws.Route(ws.POST("/duplicates/resolve").To(i.resolveDuplicates).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestResolveDuplicates{}).
+		Writes(ResponseResolveDuplicates{}))
+
+	ws.Route(ws.POST("/organize").To(i.organizeFiles).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestOrganizeFiles{}).
+		Writes(tasks.OrganizeResult{}))
+
+	ws.Route(ws.POST("/organize/undo").To(i.undoOrganize).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestUndoOrganize{}).
+		Writes(tasks.OrganizeResult{}))
+
+	ws.Route(ws.GET("/organize/journal").To(i.organizeJournal).
+		Param(ws.QueryParameter("limit", "Maximum number of entries").DataType("int").DefaultValue("500")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]models.OrganizeJournal{}))
+
 	ws.Route(ws.GET("/file/{file-id}").To(i.getFile).
 		Param(ws.PathParameter("file-id", "File ID").DataType("int")).
 		Metadata(restfulspec.KeyOpenAPITags, tags).
@@ -265,6 +316,94 @@ func (i FilesResource) matchFile(req *restful.Request, resp *restful.Response) {
 	resp.WriteHeaderAndEntity(http.StatusOK, nil)
 }
 
+func (i FilesResource) suggestMatches(req *restful.Request, resp *restful.Response) {
+	fileID, _ := strconv.Atoi(req.QueryParameter("file_id"))
+	limit, err := strconv.Atoi(req.QueryParameter("limit"))
+	if err != nil {
+		limit = 100
+	}
+
+	resp.WriteHeaderAndEntity(http.StatusOK, tasks.SuggestFileMatches(uint(fileID), limit))
+}
+
+func (i FilesResource) listDuplicates(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeaderAndEntity(http.StatusOK, tasks.GetDuplicateReport())
+}
+
+// resolveDuplicates deletes the copies of a file the user chose not to keep. Only files the
+// duplicate report lists together with the kept file are removed.
+func (i FilesResource) resolveDuplicates(req *restful.Request, resp *restful.Response) {
+	var r RequestResolveDuplicates
+	err := req.ReadEntity(&r)
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	report := tasks.GetDuplicateReport()
+	for _, id := range r.RemoveFileIDs {
+		if id == r.KeepFileID || !report.IsDuplicateOf(r.KeepFileID, id) {
+			APIError(req, resp, http.StatusBadRequest, fmt.Errorf("file %v is not a duplicate of file %v", id, r.KeepFileID))
+			return
+		}
+	}
+
+	out := ResponseResolveDuplicates{Removed: []uint{}}
+	for _, id := range r.RemoveFileIDs {
+		removeFileByFileId(id)
+
+		var f models.File
+		if f.GetIfExistByPK(id) != nil {
+			out.Removed = append(out.Removed, id)
+		}
+	}
+
+	// refresh the report, so removed files no longer show up
+	go tasks.FindDuplicateFiles()
+
+	resp.WriteHeaderAndEntity(http.StatusOK, out)
+}
+
+func (i FilesResource) organizeFiles(req *restful.Request, resp *restful.Response) {
+	var r RequestOrganizeFiles
+	err := req.ReadEntity(&r)
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := tasks.OrganizeFiles(r.SceneIDs, r.DryRun)
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+func (i FilesResource) undoOrganize(req *restful.Request, resp *restful.Response) {
+	var r RequestUndoOrganize
+	err := req.ReadEntity(&r)
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := tasks.UndoOrganize(r.Batch)
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+func (i FilesResource) organizeJournal(req *restful.Request, resp *restful.Response) {
+	limit, err := strconv.Atoi(req.QueryParameter("limit"))
+	if err != nil {
+		limit = 500
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, tasks.GetOrganizeJournal(limit))
+}
+
 func (i FilesResource) unmatchFile(req *restful.Request, resp *restful.Response) {
 	db, _ := models.GetDB()
 	defer db.Close()
@@ -346,7 +485,11 @@ func removeFileByFileId(fileId uint) models.Scene {
 
 		log.Infof("Deleting file %s", filepath.Join(file.Path, file.Filename))
 		deleted := false
-		switch file.Volume.Type {
+		volumeType := file.Volume.Type
+		if file.Type == "mobile" {
+			volumeType = "local"
+		}
+		switch volumeType {
 		case "local":
 			err := os.Remove(filepath.Join(file.Path, file.Filename))
 			if err == nil || errors.Is(err, fs.ErrNotExist) {
@@ -366,6 +509,23 @@ func removeFileByFileId(fileId uint) models.Scene {
 			} else {
 				log.Errorf("error deleting file %v", err)
 			}
+		case "webdav":
+			err := file.Volume.GetWebDAVClient().Remove(file.GetPath())
+			if err == nil {
+				deleted = true
+			} else {
+				log.Errorf("error deleting file %v", err)
+			}
+		case "s3":
+			client, meta, err := file.Volume.GetS3Client()
+			if err == nil {
+				err = client.RemoveObject(context.Background(), meta.Bucket, file.Volume.S3ObjectKey(&file), minio.RemoveObjectOptions{})
+			}
+			if err == nil {
+				deleted = true
+			} else {
+				log.Errorf("error deleting file %v", err)
+			}
 		}
 
 		if deleted {
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,6 +29,7 @@ import (
 	"github.com/xbapps/xbvr/pkg/config"
 	"github.com/xbapps/xbvr/pkg/models"
 	"github.com/xbapps/xbvr/pkg/scrape"
+	"github.com/xbapps/xbvr/pkg/session"
 	"github.com/xbapps/xbvr/pkg/tasks"
 )
 
@@ -48,6 +52,8 @@ type HeresphereVideo struct {
 	DateReleased         string                         `json:"dateReleased"`
 	DateAdded            string                         `json:"dateAdded"`
 	DurationMilliseconds uint                           `json:"duration"`
+	StartMilliseconds    float64                        `json:"startTime,omitempty"`
+	EventServer          string                         `json:"eventServer,omitempty"`
 	Rating               float64                        `json:"rating,omitempty"`
 	IsFavorite           bool                           `json:"isFavorite"`
 	Projection           string                         `json:"projection"`
@@ -118,11 +124,18 @@ var RequestBody []byte
 
 func HeresphereAuthFilter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 	RequestBody, _ = io.ReadAll(req.Request.Body)
-	if isDeoAuthEnabled() {
+
+	var requestData HereSphereAuthRequest
+	jsonErr := json.Unmarshal(RequestBody, &requestData)
+
+	// with authentication on, only profiles protected by a password may log in instead of the DeoVR account
+	user, isProfile := models.ResolveUser(requestData.Username, requestData.Password)
+	isProfile = isProfile && (user.Password != "" || !isDeoAuthEnabled())
+
+	if isDeoAuthEnabled() && !isProfile {
 		authState := 0
-		var requestData HereSphereAuthRequest
 
-		if err := json.Unmarshal(RequestBody, &requestData); err == nil {
+		if jsonErr == nil {
 			if requestData.Username != "" && requestData.Password != "" {
 				cmpErr := bcrypt.CompareHashAndPassword([]byte(config.Config.Interfaces.DeoVR.Password), []byte(requestData.Password))
 				if requestData.Username == config.Config.Interfaces.DeoVR.Username && cmpErr == nil {
@@ -151,6 +164,10 @@ func HeresphereAuthFilter(req *restful.Request, resp *restful.Response, chain *r
 			return
 		}
 	}
+	if !isProfile {
+		user = models.GetDefaultUser()
+	}
+	setPlayerUser(req, user)
 	chain.ProcessFilter(req, resp)
 }
 
@@ -187,6 +204,14 @@ func (i HeresphereResource) WebService() *restful.WebService {
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Writes(DeoScene{}))
 
+	// HereSphere posts events without credentials, the url carries a key for the profile instead
+	ws.Route(ws.POST("/{scene-id}/event").To(i.heresphereEvent).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(ws.QueryParameter("user", "Profile of the player").DataType("int")).
+		Param(ws.QueryParameter("key", "Key of the profile").DataType("string")).
+		Param(ws.QueryParameter("file", "File the video plays, for multipart scenes the one streamed last if not set").DataType("int")).
+		Reads(session.HeresphereEvent{}))
+
 	ws.Route(ws.GET("/file/{file-id}").Filter(HeresphereAuthFilter).To(i.getHeresphereFile).
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Writes(DeoScene{}))
@@ -253,6 +278,9 @@ func (i HeresphereResource) getHeresphereFile(req *restful.Request, resp *restfu
 		DurationMilliseconds: uint(file.VideoDuration * 1000),
 		Media:                media,
 	}
+	if config.Config.Interfaces.DeoVR.TrackWatchTime && file.SceneID != 0 {
+		video.EventServer = heresphereEventServer(req, file.SceneID, file.ID)
+	}
 	if requestData.DeleteFiles != nil && config.Config.Interfaces.Heresphere.AllowFileDeletes {
 		log.Infof("Got request by HereSphere to delete file %v", file.Filename)
 		removeFileByFileId(file.ID)
@@ -302,6 +330,7 @@ func (i HeresphereResource) getHeresphereScene(req *restful.Request, resp *restf
 			Preload("Files").
 			Where("id = ?", sceneID).First(&scene)
 	}
+	scene.ApplyUserState(getUserID(req))
 
 	var videoFiles []models.File
 	videoFiles, err = scene.GetVideoFilesSorted(config.Config.Interfaces.Players.VideoSortSeq)
@@ -311,9 +340,9 @@ func (i HeresphereResource) getHeresphereScene(req *restful.Request, resp *restf
 	}
 
 	if len(videoFiles) == 0 {
-		ProcessHeresphereUpdates(&scene, requestData, models.File{})
+		ProcessHeresphereUpdates(&scene, getUserID(req), requestData, models.File{})
 	} else {
-		ProcessHeresphereUpdates(&scene, requestData, videoFiles[0])
+		ProcessHeresphereUpdates(&scene, getUserID(req), requestData, videoFiles[0])
 	}
 
 	features := make(map[string]bool, 30)
@@ -359,9 +388,38 @@ func (i HeresphereResource) getHeresphereScene(req *restful.Request, resp *restf
 		}
 
 		media = append(media, mediafile)
+
+		if variants := getHLSVariants(file); len(variants) > 0 {
+			hlsMedia := HeresphereMedia{Name: fmt.Sprintf("File %v/%v HLS", i+1, len(videoFiles))}
+			for _, v := range variants {
+				hlsMedia.Sources = append(hlsMedia.Sources, HeresphereSource{
+					Resolution: StringOrInt(strconv.Itoa(v.Height)),
+					Height:     v.Height,
+					Width:      v.Width,
+					URL:        fmt.Sprintf("%v://%v%v", getProto(req), req.Request.Host, v.URL),
+				})
+			}
+			media = append(media, hlsMedia)
+		}
 		videoLength = file.VideoDuration
 	}
 
+	mobileFiles, _ := scene.GetMobileFiles()
+	for _, file := range mobileFiles {
+		media = append(media, HeresphereMedia{
+			Name: fmt.Sprintf("Mobile %vp - %v", file.VideoHeight, humanize.Bytes(uint64(file.Size))),
+			Sources: []HeresphereSource{
+				{
+					Resolution: StringOrInt(strconv.Itoa(file.VideoHeight)),
+					Height:     file.VideoHeight,
+					Width:      file.VideoWidth,
+					Size:       file.Size,
+					URL:        fmt.Sprintf("%v://%v/api/dms/file/%v%v", getProto(req), req.Request.Host, file.ID, dnt),
+				},
+			},
+		})
+	}
+
 	if len(videoFiles) == 0 && config.Config.Web.SceneTrailerlist && requestData.NeedsMediaSource.OrElse(true) {
 		switch scene.TrailerType {
 		case "heresphere":
@@ -764,10 +822,96 @@ func (i HeresphereResource) getHeresphereScene(req *restful.Request, resp *restf
 	if scene.HasVideoPreview {
 		video.ThumbnailVideo = fmt.Sprintf("%v://%v/api/dms/preview/%v", getProto(req), req.Request.Host, scene.SceneID)
 	}
+	if pos, ok := models.GetResumePosition(getUserID(req), scene.ID); ok {
+		video.StartMilliseconds = pos.Position * 1000
+	}
+	if config.Config.Interfaces.DeoVR.TrackWatchTime {
+		// the parts of a multipart scene share the video, its events can't name the file
+		var fileID uint
+		if len(videoFiles) == 1 {
+			fileID = videoFiles[0].ID
+		}
+		video.EventServer = heresphereEventServer(req, scene.ID, fileID)
+	}
 
 	resp.WriteHeaderAndEntity(http.StatusOK, video)
 }
 
+func heresphereEventServer(req *restful.Request, sceneID uint, fileID uint) string {
+	userID := getUserID(req)
+	url := fmt.Sprintf("%v://%v/heresphere/%v/event?user=%v&key=%v", getProto(req), req.Request.Host, sceneID, userID, heresphereEventKey(userID))
+	if fileID != 0 {
+		url += fmt.Sprintf("&file=%v", fileID)
+	}
+	return url
+}
+
+// heresphereEventKey signs the event urls of a profile with its password and the DeoVR password
+func heresphereEventKey(userID uint) string {
+	var user models.User
+	user.GetIfExistByPK(userID)
+
+	mac := hmac.New(sha256.New, []byte(config.Config.Interfaces.DeoVR.Password+user.Password))
+	fmt.Fprintf(mac, "heresphere-events:%v", userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (i HeresphereResource) heresphereEvent(req *restful.Request, resp *restful.Response) {
+	if !config.Config.Interfaces.DeoVR.Enabled || !config.Config.Interfaces.DeoVR.TrackWatchTime {
+		return
+	}
+
+	userID, err := strconv.Atoi(req.QueryParameter("user"))
+	if err != nil || !hmac.Equal([]byte(req.QueryParameter("key")), []byte(heresphereEventKey(uint(userID)))) {
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var ev session.HeresphereEvent
+	if err := req.ReadEntity(&ev); err != nil {
+		log.Warnf("Error decoding heresphere event: %v", err)
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sceneID, err := strconv.Atoi(req.PathParameter("scene-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var scene models.Scene
+	if err := scene.GetIfExistByPK(uint(sceneID)); err != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	files, _ := scene.GetVideoFiles()
+	if len(files) == 0 {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// the url names the file, except for multipart scenes where the file requests of the player tell
+	// which part plays
+	client := session.ClientKey(req.Request.RemoteAddr, req.Request.UserAgent())
+	fileID, _ := strconv.Atoi(req.QueryParameter("file"))
+	if fileID == 0 {
+		fileID = int(session.StreamedFileID(client, scene.ID))
+	}
+	file := files[0]
+	for _, f := range files {
+		if f.ID == uint(fileID) {
+			file = f
+		}
+	}
+	if req.QueryParameter("file") != "" && file.ID != uint(fileID) {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	session.TrackSessionFromHeresphere(client, uint(userID), file, ev)
+	resp.WriteHeader(http.StatusOK)
+}
+
 func copyVideoSourceResponse(sources models.VideoSourceResponse, media []HeresphereMedia) []HeresphereMedia {
 	if len(sources.VideoSources) > 0 {
 		for _, source := range sources.VideoSources {
@@ -785,17 +929,18 @@ func copyVideoSourceResponse(sources models.VideoSourceResponse, media []Heresph
 
 var lockHeresphereUpdates sync.Mutex
 
-func ProcessHeresphereUpdates(scene *models.Scene, requestData HereSphereAuthRequest, videoFile models.File) {
+// ProcessHeresphereUpdates applies the changes made in HereSphere, personal state is changed for the profile userID
+func ProcessHeresphereUpdates(scene *models.Scene, userID uint, requestData HereSphereAuthRequest, videoFile models.File) {
 	db, _ := models.GetDB()
 	defer db.Close()
 
 	if requestData.IsFavorite != nil && *requestData.IsFavorite != scene.Favourite && config.Config.Interfaces.Heresphere.AllowFavoriteUpdates {
 		scene.Favourite = *requestData.IsFavorite
-		scene.Save()
+		scene.SaveUserState(userID)
 	}
 	if requestData.Rating != nil && *requestData.Rating != scene.StarRating && config.Config.Interfaces.Heresphere.AllowRatingUpdates {
 		scene.StarRating = *requestData.Rating
-		scene.Save()
+		scene.SaveUserState(userID)
 	}
 
 	if requestData.Tags != nil && (config.Config.Interfaces.Heresphere.AllowTagUpdates || config.Config.Interfaces.Heresphere.AllowCuepointUpdates || config.Config.Interfaces.Heresphere.AllowWatchlistUpdates || config.Config.Web.SceneTrailerlist) {
@@ -840,7 +985,7 @@ func ProcessHeresphereUpdates(scene *models.Scene, requestData HereSphereAuthReq
 		}
 		if scene.Watchlist != watchlist && config.Config.Interfaces.Heresphere.AllowWatchlistUpdates {
 			scene.Watchlist = watchlist
-			scene.Save()
+			scene.SaveUserState(userID)
 		}
 		if scene.Trailerlist != trailerlist && config.Config.Web.SceneTrailerlist {
 			scene.Trailerlist = trailerlist
@@ -990,7 +1135,7 @@ func (i HeresphereResource) getHeresphereLibrary(req *restful.Request, resp *res
 	var sceneLists []HeresphereListScenes
 
 	var savedPlaylists []models.Playlist
-	db.Where("is_deo_enabled = ?", true).Order("ordering asc").Find(&savedPlaylists)
+	db.Scopes(models.VisibleToUser(getUserID(req))).Where("is_deo_enabled = ?", true).Order("ordering asc").Find(&savedPlaylists)
 
 	for i := range savedPlaylists {
 		var r models.RequestSceneList
@@ -998,6 +1143,7 @@ func (i HeresphereResource) getHeresphereLibrary(req *restful.Request, resp *res
 		if err := json.Unmarshal([]byte(savedPlaylists[i].SearchParams), &r); err == nil {
 			r.IsAccessible = optional.NewBool(true)
 			r.IsAvailable = optional.NewBool(true)
+			r.UserID = getUserID(req)
 
 			list := models.QuerySceneIDs(r)
 
@@ -1012,6 +1158,16 @@ func (i HeresphereResource) getHeresphereLibrary(req *restful.Request, resp *res
 		}
 	}
 
+	if list := models.QuerySceneIDs(continueWatchingRequest(req)); len(list) > 0 {
+		for i := range list {
+			list[i] = fmt.Sprintf("%v://%v/heresphere/%v", getProto(req), req.Request.Host, list[i])
+		}
+		sceneLists = append([]HeresphereListScenes{{
+			Name: "Continue watching",
+			List: list,
+		}}, sceneLists...)
+	}
+
 	// Add unmatched files at the end
 	var unmatched []models.File
 	db.Model(&unmatched).
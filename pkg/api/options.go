@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,9 +33,17 @@ import (
 )
 
 type NewVolumeRequest struct {
-	Type  string `json:"type"`
-	Path  string `json:"path"`
-	Token string `json:"token"`
+	Type     string `json:"type"`
+	Path     string `json:"path"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	Region    string `json:"region"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
 }
 
 type VersionCheckResponse struct {
@@ -113,6 +122,9 @@ type RequestSaveOptionsDeoVR struct {
 	SubtitleSortSeq         string `json:"subtitle_sort_seq"`
 	MultitrackCastCuepoints bool   `json:"multitrack_cast_cuepoints"`
 	RetainNonHSPCuepoints   bool   `json:"retain_non_hsp_cuepoints"`
+	HLSEnabled              bool   `json:"hls_enabled"`
+	HLSMaxTranscodes        int    `json:"hls_max_transcodes"`
+	HLSCacheSizeGB          int    `json:"hls_cache_size_gb"`
 }
 
 type RequestSaveOptionsPreviews struct {
@@ -124,6 +136,12 @@ type RequestSaveOptionsPreviews struct {
 	ExtraSnippet  bool    `json:"extraSnippet"`
 }
 
+type RequestSaveOptionsMobile struct {
+	Playlists    []uint `json:"playlists"`
+	Width        int    `json:"width"`
+	VideoBitrate int    `json:"video_bitrate"`
+}
+
 type GetStateResponse struct {
 	CurrentState config.ObjectState  `json:"currentState"`
 	Config       config.ObjectConfig `json:"config"`
@@ -186,6 +204,14 @@ type RequestSaveOptionsTaskSchedule struct {
 	LinkScenesHourStart    int  `json:"linkScenesHourStart"`
 	LinkScenesHourEnd      int  `json:"linkScenesHourEnd"`
 	LinkScenesStartDelay   int  `json:"linkScenesStartDelay"`
+
+	MobileEnabled      bool `json:"mobileEnabled"`
+	MobileHourInterval int  `json:"mobileHourInterval"`
+	MobileUseRange     bool `json:"mobileUseRange"`
+	MobileMinuteStart  int  `json:"mobileMinuteStart"`
+	MobileHourStart    int  `json:"mobileHourStart"`
+	MobileHourEnd      int  `json:"mobileHourEnd"`
+	MobileStartDelay   int  `json:"mobileStartDelay"`
 }
 type RequestSaveSiteMatchParams struct {
 	SiteId      string                   `json:"site"`
@@ -207,13 +233,25 @@ type RequestSCustomSiteCreate struct {
 type GetStorageResponse struct {
 	Volumes           []models.Volume `json:"volumes"`
 	MatchOhash        bool            `json:"match_ohash"`
+	MatchContent      bool            `json:"match_content"`
+	WatchVolumes      bool            `json:"watch_volumes"`
+	ScanConcurrency   int             `json:"scan_concurrency"`
+	OrganizeTemplate  string          `json:"organize_template"`
+	OrganizeTarget    uint            `json:"organize_target"`
+	SidecarsEnabled   bool            `json:"sidecars_enabled"`
 	VideoExt          []string        `json:"video_ext"`
 	ForbiddenVideoExt []string        `json:"forbidden_video_ext"`
 	DefaultVideoExt   []string        `json:"default_video_ext"`
 }
 type RequestSaveOptionsStorage struct {
-	MatchOhash bool     `json:"match_ohash"`
-	VideoExt   []string `json:"video_ext"`
+	MatchOhash       bool     `json:"match_ohash"`
+	MatchContent     bool     `json:"match_content"`
+	WatchVolumes     bool     `json:"watch_volumes"`
+	ScanConcurrency  int      `json:"scan_concurrency"`
+	OrganizeTemplate string   `json:"organize_template"`
+	OrganizeTarget   uint     `json:"organize_target"`
+	SidecarsEnabled  bool     `json:"sidecars_enabled"`
+	VideoExt         []string `json:"video_ext"`
 }
 
 type RequestSaveCollectorConfig struct {
@@ -263,6 +301,16 @@ func (i ConfigResource) WebService() *restful.WebService {
 	ws.Route(ws.POST("/scraper/force-site-update").To(i.forceSiteUpdate).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.GET("/scraper/runs").To(i.latestScrapeRuns).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]models.ScrapeRun{}))
+
+	ws.Route(ws.GET("/scraper/runs/{site}").To(i.listScrapeRuns).
+		Param(ws.PathParameter("site", "Site ID").DataType("string")).
+		Param(ws.QueryParameter("limit", "Number of runs, newest first").DataType("int").DefaultValue("50")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]models.ScrapeRun{}))
+
 	ws.Route(ws.POST("/scraper/delete-scenes").To(i.deleteScenes).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
@@ -317,6 +365,9 @@ func (i ConfigResource) WebService() *restful.WebService {
 	ws.Route(ws.POST("/previews/test").To(i.generateTestPreview).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.PUT("/mobile").To(i.saveOptionsMobile).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
 	// "Funscripts" section endpoints
 	ws.Route(ws.GET("/funscripts/count").To(i.getFunscriptsCount).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
@@ -449,13 +500,41 @@ func (i ConfigResource) listSitesWithDB(req *restful.Request, resp *restful.Resp
 		scraperSet[scraper.ID] = true
 	}
 
+	latestRuns := models.GetLatestScrapeRuns()
+
 	for idx, site := range sites {
 		sites[idx].HasScraper = scraperSet[site.ID]
 		sites[idx].SceneCount = countMap[site.ID]
+		if run, ok := latestRuns[site.ID]; ok && run.Flagged {
+			sites[idx].HealthWarning = run.FlagReason
+		}
 	}
 	resp.WriteHeaderAndEntity(http.StatusOK, sites)
 }
 
+// latestScrapeRuns returns the last run of every scraped site, flagged runs first
+func (i ConfigResource) latestScrapeRuns(req *restful.Request, resp *restful.Response) {
+	runs := []models.ScrapeRun{}
+	for _, run := range models.GetLatestScrapeRuns() {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(a, b int) bool {
+		if runs[a].Flagged != runs[b].Flagged {
+			return runs[a].Flagged
+		}
+		return runs[a].SiteID < runs[b].SiteID
+	})
+	resp.WriteHeaderAndEntity(http.StatusOK, runs)
+}
+
+func (i ConfigResource) listScrapeRuns(req *restful.Request, resp *restful.Response) {
+	limit := 50
+	if l, err := strconv.Atoi(req.QueryParameter("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, models.GetScrapeRuns(req.PathParameter("site"), limit))
+}
+
 func (i ConfigResource) siteMatchParams(req *restful.Request, resp *restful.Response) {
 	db, _ := models.GetDB()
 	defer db.Close()
@@ -598,6 +677,14 @@ func (i ConfigResource) saveOptionsDeoVR(req *restful.Request, resp *restful.Res
 	config.Config.Interfaces.Players.SubtitleSortSeq = r.SubtitleSortSeq
 	config.Config.Interfaces.Heresphere.MultitrackCastCuepoints = r.MultitrackCastCuepoints
 	config.Config.Interfaces.Heresphere.RetainNonHSPCuepoints = r.RetainNonHSPCuepoints
+	config.Config.Interfaces.HLS.Enabled = r.HLSEnabled
+	if r.HLSMaxTranscodes > 0 {
+		config.Config.Interfaces.HLS.MaxTranscodes = r.HLSMaxTranscodes
+	}
+	if r.HLSCacheSizeGB > 0 {
+		config.Config.Interfaces.HLS.CacheSizeGB = r.HLSCacheSizeGB
+	}
+	updateHLSTranscoder()
 	if r.Password != config.Config.Interfaces.DeoVR.Password && r.Password != "" {
 		hash, _ := bcrypt.GenerateFromPassword([]byte(r.Password), bcrypt.DefaultCost)
 		config.Config.Interfaces.DeoVR.Password = string(hash)
@@ -618,9 +705,19 @@ func (i ConfigResource) listStorage(req *restful.Request, resp *restful.Response
        	(select sum(files.size) from files where files.volume_id = volumes.id) as total_size
 		from volumes order by last_scan desc;`).Scan(&vol)
 
+	for i := range vol {
+		vol[i].IsWatched = tasks.IsVolumeWatched(vol[i].ID)
+	}
+
 	var out GetStorageResponse
 	out.Volumes = vol
 	out.MatchOhash = config.Config.Storage.MatchOhash
+	out.MatchContent = config.Config.Storage.MatchContent
+	out.WatchVolumes = config.Config.Storage.WatchVolumes
+	out.ScanConcurrency = config.Config.Storage.ScanConcurrency
+	out.OrganizeTemplate = config.Config.Storage.Organizer.Template
+	out.OrganizeTarget = config.Config.Storage.Organizer.TargetVolume
+	out.SidecarsEnabled = config.Config.Storage.Sidecars.Enabled
 
 	// Fallback to default video extensions if none are set
 	if len(config.Config.Storage.VideoExt) == 0 {
@@ -695,6 +792,66 @@ func (i ConfigResource) addStorage(req *restful.Request, resp *restful.Response)
 		nv.Save()
 
 		tlog.Info("Added new cloud storage ", nv.Path)
+
+	case "webdav":
+		url := strings.TrimSuffix(strings.TrimSpace(r.Path), "/")
+		meta, _ := json.Marshal(models.WebDAVMetadata{URL: url, Username: r.Username, Password: r.Password})
+		nv := models.Volume{Path: url, IsEnabled: true, IsAvailable: true, Metadata: string(meta), Type: r.Type}
+
+		if _, err := nv.GetWebDAVClient().Stat("/"); err != nil {
+			tlog.Error("Can't connect to WebDAV server ", err)
+			APIError(req, resp, 400, errors.New("Can't connect to WebDAV server"))
+			return
+		}
+
+		var vol []models.Volume
+		db.Where(&models.Volume{Path: url, Type: r.Type}).Find(&vol)
+
+		if len(vol) > 0 {
+			tlog.Error("WebDAV storage already exists")
+			APIError(req, resp, 400, errors.New("WebDAV storage already exists"))
+			return
+		}
+
+		nv.Save()
+
+		tlog.Info("Added new WebDAV storage ", nv.Path)
+
+	case "s3":
+		endpoint := strings.TrimSuffix(strings.TrimSpace(r.Path), "/")
+		useSSL := !strings.HasPrefix(endpoint, "http://")
+		endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+
+		s3meta := models.S3Metadata{
+			Endpoint:  endpoint,
+			Region:    strings.TrimSpace(r.Region),
+			Bucket:    strings.TrimSpace(r.Bucket),
+			Prefix:    strings.Trim(strings.TrimSpace(r.Prefix), "/"),
+			AccessKey: strings.TrimSpace(r.AccessKey),
+			SecretKey: r.SecretKey,
+			UseSSL:    useSSL,
+		}
+		meta, _ := json.Marshal(s3meta)
+		nv := models.Volume{Path: s3meta.VolumePath(), IsEnabled: true, IsAvailable: true, Metadata: string(meta), Type: r.Type}
+
+		if !nv.IsMounted() {
+			tlog.Error("Can't access S3 bucket ", s3meta.Bucket)
+			APIError(req, resp, 400, errors.New("Can't access S3 bucket"))
+			return
+		}
+
+		var vol []models.Volume
+		db.Where(&models.Volume{Path: nv.Path, Type: r.Type}).Find(&vol)
+
+		if len(vol) > 0 {
+			tlog.Error("S3 storage already exists")
+			APIError(req, resp, 400, errors.New("S3 storage already exists"))
+			return
+		}
+
+		nv.Save()
+
+		tlog.Info("Added new S3 storage ", nv.Path)
 	}
 
 	// Inform UI about state change
@@ -721,6 +878,7 @@ func (i ConfigResource) removeStorage(req *restful.Request, resp *restful.Respon
 		return
 	}
 
+	tasks.StopVolumeWatcher(vol.ID)
 	db.Where("volume_id = ?", id).Delete(models.File{})
 	db.Delete(&vol)
 
@@ -899,6 +1057,26 @@ func (i ConfigResource) saveOptionsPreviews(req *restful.Request, resp *restful.
 	resp.WriteHeaderAndEntity(http.StatusOK, r)
 }
 
+func (i ConfigResource) saveOptionsMobile(req *restful.Request, resp *restful.Response) {
+	var r RequestSaveOptionsMobile
+	err := req.ReadEntity(&r)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	config.Config.Library.Mobile.Playlists = r.Playlists
+	if r.Width > 0 {
+		config.Config.Library.Mobile.Width = r.Width
+	}
+	if r.VideoBitrate > 0 {
+		config.Config.Library.Mobile.VideoBitrate = r.VideoBitrate
+	}
+	config.SaveConfig()
+
+	resp.WriteHeaderAndEntity(http.StatusOK, r)
+}
+
 func (i ConfigResource) generateTestPreview(req *restful.Request, resp *restful.Response) {
 	var r RequestSaveOptionsPreviews
 	err := req.ReadEntity(&r)
@@ -989,6 +1167,9 @@ func (i ConfigResource) saveOptionsTaskSchedule(req *restful.Request, resp *rest
 	if r.PreviewHourEnd > 23 {
 		r.PreviewHourEnd -= 24
 	}
+	if r.MobileHourEnd > 23 {
+		r.MobileHourEnd -= 24
+	}
 
 	config.Config.Cron.RescrapeSchedule.Enabled = r.RescrapeEnabled
 	config.Config.Cron.RescrapeSchedule.HourInterval = r.RescrapeHourInterval
@@ -1038,6 +1219,14 @@ func (i ConfigResource) saveOptionsTaskSchedule(req *restful.Request, resp *rest
 	config.Config.Cron.LinkScenesSchedule.HourEnd = r.LinkScenesHourEnd
 	config.Config.Cron.LinkScenesSchedule.RunAtStartDelay = r.LinkScenesStartDelay
 
+	config.Config.Cron.MobileSchedule.Enabled = r.MobileEnabled
+	config.Config.Cron.MobileSchedule.HourInterval = r.MobileHourInterval
+	config.Config.Cron.MobileSchedule.UseRange = r.MobileUseRange
+	config.Config.Cron.MobileSchedule.MinuteStart = r.MobileMinuteStart
+	config.Config.Cron.MobileSchedule.HourStart = r.MobileHourStart
+	config.Config.Cron.MobileSchedule.HourEnd = r.MobileHourEnd
+	config.Config.Cron.MobileSchedule.RunAtStartDelay = r.MobileStartDelay
+
 	config.SaveConfig()
 
 	resp.WriteHeaderAndEntity(http.StatusOK, r)
@@ -1148,6 +1337,20 @@ func (i ConfigResource) saveOptionsStorage(req *restful.Request, resp *restful.R
 	}
 
 	config.Config.Storage.MatchOhash = r.MatchOhash
+	config.Config.Storage.MatchContent = r.MatchContent
+	config.Config.Storage.WatchVolumes = r.WatchVolumes
+	if r.ScanConcurrency > 0 {
+		config.Config.Storage.ScanConcurrency = r.ScanConcurrency
+	}
+	if strings.TrimSpace(r.OrganizeTemplate) != "" {
+		config.Config.Storage.Organizer.Template = strings.TrimSpace(r.OrganizeTemplate)
+	}
+	config.Config.Storage.Organizer.TargetVolume = r.OrganizeTarget
+	config.Config.Storage.Sidecars.Enabled = r.SidecarsEnabled
+	if !r.WatchVolumes {
+		// unwatched volumes are picked up by the periodic rescan again
+		tasks.StopVolumeWatchers()
+	}
 
 	// Filter, normalize, and deduplicate extensions
 	var allowedExt []string
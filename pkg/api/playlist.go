@@ -26,6 +26,7 @@ func (i PlaylistResource) WebService() *restful.WebService {
 	ws := new(restful.WebService)
 
 	ws.Path("/api/playlist").
+		Filter(userFilter).
 		Consumes(restful.MIME_JSON).
 		Produces(restful.MIME_JSON)
 
@@ -62,7 +63,7 @@ func (i PlaylistResource) listPlaylists(req *restful.Request, resp *restful.Resp
 	}
 
 	var playlists []models.Playlist
-	db.Where("playlist_type = ?", playlistType).Order("ordering asc").Find(&playlists)
+	db.Scopes(models.VisibleToUser(getUserID(req))).Where("playlist_type = ?", playlistType).Order("ordering asc").Find(&playlists)
 
 	resp.WriteHeaderAndEntity(http.StatusOK, playlists)
 }
@@ -81,7 +82,7 @@ func (i PlaylistResource) createPlaylist(req *restful.Request, resp *restful.Res
 	if r.PlaylistType == "" {
 		r.PlaylistType = "scene"
 	}
-	nv := models.Playlist{Name: r.Name, IsDeoEnabled: r.IsDeoEnabled, IsSmart: r.IsSmart, PlaylistType: r.PlaylistType, SearchParams: r.SearchParams}
+	nv := models.Playlist{UserID: getUserID(req), Name: r.Name, IsDeoEnabled: r.IsDeoEnabled, IsSmart: r.IsSmart, PlaylistType: r.PlaylistType, SearchParams: r.SearchParams}
 	nv.Save()
 
 	resp.WriteHeaderAndEntity(http.StatusOK, nv)
@@ -105,7 +106,7 @@ func (i PlaylistResource) updatePlaylist(req *restful.Request, resp *restful.Res
 	defer db.Close()
 
 	playlist := models.Playlist{}
-	err = db.First(&playlist, id).Error
+	err = db.Scopes(models.VisibleToUser(getUserID(req))).First(&playlist, id).Error
 
 	if err == gorm.ErrRecordNotFound {
 		resp.WriteHeader(http.StatusNotFound)
@@ -131,7 +132,7 @@ func (i PlaylistResource) removePlaylist(req *restful.Request, resp *restful.Res
 	defer db.Close()
 
 	playlist := models.Playlist{}
-	err = db.First(&playlist, id).Error
+	err = db.Scopes(models.VisibleToUser(getUserID(req))).First(&playlist, id).Error
 
 	if err == gorm.ErrRecordNotFound {
 		resp.WriteHeader(http.StatusNotFound)
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	restfulspec "github.com/emicklei/go-restful-openapi/v2"
+	"github.com/emicklei/go-restful/v3"
+
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/session"
+)
+
+type RequestRemoteCommand struct {
+	Command  string  `json:"command"` // load, play, pause, seek or speed
+	SceneID  uint    `json:"scene_id"`
+	FileID   uint    `json:"file_id"`
+	Position float64 `json:"position"` // seconds, for load and seek
+	Speed    float64 `json:"speed"`
+}
+
+type ResponseRemoteCommand struct {
+	ID uint64 `json:"id"`
+}
+
+type RemoteResource struct{}
+
+func (i RemoteResource) WebService() *restful.WebService {
+	tags := []string{"Remote"}
+
+	ws := new(restful.WebService)
+
+	ws.Path("/api/remote").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.POST("/command").To(i.command).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestRemoteCommand{}).
+		Writes(ResponseRemoteCommand{}))
+
+	return ws
+}
+
+func (i RemoteResource) command(req *restful.Request, resp *restful.Response) {
+	if !config.Config.Interfaces.DeoVR.RemoteEnabled {
+		APIError(req, resp, http.StatusConflict, fmt.Errorf("the DeoVR remote is disabled"))
+		return
+	}
+
+	var r RequestRemoteCommand
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	c, err := r.deoCommand()
+	if err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := session.QueueDeoCommand(c)
+	if err != nil {
+		APIError(req, resp, http.StatusServiceUnavailable, err)
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, ResponseRemoteCommand{ID: id})
+}
+
+func (r RequestRemoteCommand) deoCommand() (session.DeoCommand, error) {
+	var c session.DeoCommand
+	switch r.Command {
+	case "load":
+		path, err := remoteFileURL(r.SceneID, r.FileID)
+		if err != nil {
+			return c, err
+		}
+		c.Path = &path
+		if r.Position > 0 {
+			c.CurrentTime = &r.Position
+		}
+	case "play":
+		state := session.PLAYING
+		c.PlayerState = &state
+	case "pause":
+		state := session.PAUSED
+		c.PlayerState = &state
+	case "seek":
+		if r.Position < 0 {
+			return c, fmt.Errorf("invalid position %v", r.Position)
+		}
+		c.CurrentTime = &r.Position
+	case "speed":
+		if r.Speed <= 0 {
+			return c, fmt.Errorf("invalid speed %v", r.Speed)
+		}
+		c.PlaybackSpeed = &r.Speed
+	default:
+		return c, fmt.Errorf("unknown command %q", r.Command)
+	}
+	return c, nil
+}
+
+// remoteFileURL is the url DeoVR streams a file from, for a scene the first of its video files
+func remoteFileURL(sceneID uint, fileID uint) (string, error) {
+	if session.DeoRequestHost == "" {
+		return "", fmt.Errorf("DeoVR hasn't connected to the library yet")
+	}
+
+	if fileID == 0 {
+		var scene models.Scene
+		if err := scene.GetIfExistByPK(sceneID); err != nil {
+			return "", fmt.Errorf("scene %v not found", sceneID)
+		}
+		files, _ := scene.GetVideoFiles()
+		if len(files) == 0 {
+			return "", fmt.Errorf("scene %v has no video files", sceneID)
+		}
+		fileID = files[0].ID
+	} else {
+		db, _ := models.GetCommonDB()
+		var f models.File
+		if err := db.First(&f, fileID).Error; err != nil {
+			return "", fmt.Errorf("file %v not found", fileID)
+		}
+	}
+
+	// the remote tracks the session, the file requests don't have to
+	return fmt.Sprintf("%v/api/dms/file/%v?dnt=true", session.DeoRequestHost, fileID), nil
+}
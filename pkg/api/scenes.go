@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -68,6 +69,31 @@ type RequestEditSceneDetails struct {
 	Duration     string   `json:"duration"`
 }
 
+type RequestSceneFieldLocks struct {
+	Fields []string `json:"fields"`
+	Locked bool     `json:"locked"`
+}
+
+type RequestBulkSceneFieldLocks struct {
+	Fields []string                `json:"fields"`
+	Locked bool                    `json:"locked"`
+	Filter models.RequestSceneList `json:"filter"`
+}
+
+type ResponseBulkSceneFieldLocks struct {
+	Scenes int `json:"scenes"`
+}
+
+type RequestBulkSceneEdit struct {
+	SceneIDs   []uint                      `json:"scene_ids"`
+	Filter     *models.RequestSceneList    `json:"filter"`
+	Operations []models.SceneBulkOperation `json:"operations"`
+}
+
+type ResponseBulkSceneEdit struct {
+	Scenes int `json:"scenes"`
+}
+
 type ResponseGetScenes struct {
 	Results int            `json:"results"`
 	Scenes  []models.Scene `json:"scenes"`
@@ -103,6 +129,7 @@ func (i SceneResource) WebService() *restful.WebService {
 	ws := new(restful.WebService)
 
 	ws.Path("/api/scene").
+		Filter(userFilter).
 		Consumes(restful.MIME_JSON).
 		Produces(restful.MIME_JSON)
 
@@ -149,6 +176,11 @@ func (i SceneResource) WebService() *restful.WebService {
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Writes(models.Scene{}))
 
+	ws.Route(ws.POST("/bulk").To(i.bulkEditScenes).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestBulkSceneEdit{}).
+		Writes(ResponseBulkSceneEdit{}))
+
 	ws.Route(ws.POST("/toggle").To(i.toggleList).
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Writes(ResponseGetScenes{}))
@@ -161,6 +193,27 @@ func (i SceneResource) WebService() *restful.WebService {
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Writes(ResponseGetAlternateSources{}))
 
+	ws.Route(ws.POST("/{scene-id}/locks").To(i.setSceneFieldLocks).
+		Param(ws.PathParameter("scene-id", "Scene ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestSceneFieldLocks{}).
+		Writes(models.Scene{}))
+
+	ws.Route(ws.POST("/locks").To(i.setBulkSceneFieldLocks).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestBulkSceneFieldLocks{}).
+		Writes(ResponseBulkSceneFieldLocks{}))
+
+	ws.Route(ws.GET("/{scene-id}/changes").To(i.getSceneChanges).
+		Param(ws.PathParameter("scene-id", "Scene ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]models.SceneChange{}))
+
+	ws.Route(ws.POST("/changes/{change-id}/revert").To(i.revertSceneChange).
+		Param(ws.PathParameter("change-id", "Change ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes(models.Scene{}))
+
 	return ws
 }
 
@@ -476,6 +529,7 @@ func (i SceneResource) getScene(req *restful.Request, resp *restful.Response) {
 		_ = scene.GetIfExistByPK(uint(id))
 	}
 	db.Close()
+	scene.ApplyUserState(getUserID(req))
 
 	resp.WriteHeaderAndEntity(http.StatusOK, scene)
 }
@@ -488,6 +542,7 @@ func (i SceneResource) getScenes(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
+	r.UserID = getUserID(req)
 	out := models.QueryScenes(r, true)
 	resp.WriteHeaderAndEntity(http.StatusOK, out)
 }
@@ -513,6 +568,7 @@ func (i SceneResource) toggleList(req *restful.Request, resp *restful.Response)
 		log.Error(err)
 		return
 	}
+	scene.ApplyUserState(getUserID(req))
 
 	if r.List == "watchlist" {
 		scene.Watchlist = !scene.Watchlist
@@ -543,6 +599,8 @@ func (i SceneResource) toggleList(req *restful.Request, resp *restful.Response)
 	}
 
 	scene.Save()
+	scene.SaveUserState(getUserID(req))
+	tasks.UpdateSceneSidecars(scene.ID)
 }
 
 func (i SceneResource) getSearchFields(req *restful.Request, resp *restful.Response) {
@@ -698,6 +756,7 @@ func (i SceneResource) searchSceneIndex(req *restful.Request, resp *restful.Resp
 		scene.Score = v.Score
 		scenes = append(scenes, scene)
 	}
+	models.ApplyUserSceneState(db, getUserID(req), scenes)
 
 	resp.WriteHeaderAndEntity(http.StatusOK, ResponseGetScenes{Results: len(scenes), Scenes: scenes})
 }
@@ -731,6 +790,7 @@ func (i SceneResource) addSceneCuepoint(req *restful.Request, resp *restful.Resp
 		t.Save()
 
 		scene.GetIfExistByPK(uint(sceneId))
+		scene.ApplyUserState(getUserID(req))
 	}
 	db.Close()
 
@@ -765,6 +825,7 @@ func (i SceneResource) deleteSceneCuepoint(req *restful.Request, resp *restful.R
 
 	var scene models.Scene
 	_ = scene.GetIfExistByPK(uint(sceneId))
+	scene.ApplyUserState(getUserID(req))
 	defer db.Close()
 
 	resp.WriteHeaderAndEntity(http.StatusOK, scene)
@@ -788,14 +849,109 @@ func (i SceneResource) rateScene(req *restful.Request, resp *restful.Response) {
 	db, _ := models.GetDB()
 	err = scene.GetIfExistByPK(uint(sceneId))
 	if err == nil {
+		scene.ApplyUserState(getUserID(req))
 		scene.StarRating = r.Rating
-		scene.Save()
+		scene.SaveUserState(getUserID(req))
+		tasks.UpdateSceneSidecars(scene.ID)
 	}
 	db.Close()
 
 	resp.WriteHeaderAndEntity(http.StatusOK, scene)
 }
 
+func (i SceneResource) setSceneFieldLocks(req *restful.Request, resp *restful.Response) {
+	sceneId, err := strconv.Atoi(req.PathParameter("scene-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var r RequestSceneFieldLocks
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	if err := models.ValidateSceneLocks(r.Fields); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var scene models.Scene
+	if scene.GetIfExistByPK(uint(sceneId)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	models.SetSceneFieldLocks([]uint{scene.ID}, r.Fields, r.Locked)
+
+	scene.GetIfExistByPK(uint(sceneId))
+	scene.ApplyUserState(getUserID(req))
+	resp.WriteHeaderAndEntity(http.StatusOK, scene)
+}
+
+// setBulkSceneFieldLocks locks or unlocks fields of all the scenes matching a scene list filter
+func (i SceneResource) setBulkSceneFieldLocks(req *restful.Request, resp *restful.Response) {
+	var r RequestBulkSceneFieldLocks
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	if err := models.ValidateSceneLocks(r.Fields); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	r.Filter.UserID = getUserID(req)
+	var ids []uint
+	for _, scene := range models.QueryScenesFull(r.Filter).Scenes {
+		ids = append(ids, scene.ID)
+	}
+	models.SetSceneFieldLocks(ids, r.Fields, r.Locked)
+
+	resp.WriteHeaderAndEntity(http.StatusOK, ResponseBulkSceneFieldLocks{Scenes: len(ids)})
+}
+
+// getSceneChanges returns the timeline of the changes scrapers made to a scene
+func (i SceneResource) getSceneChanges(req *restful.Request, resp *restful.Response) {
+	sceneId, err := strconv.Atoi(req.PathParameter("scene-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var scene models.Scene
+	if scene.GetIfExistByPK(uint(sceneId)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, models.GetSceneChanges(scene.SceneID))
+}
+
+func (i SceneResource) revertSceneChange(req *restful.Request, resp *restful.Response) {
+	changeId, err := strconv.Atoi(req.PathParameter("change-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var change models.SceneChange
+	if change.GetIfExistByPK(uint(changeId)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err := change.Revert(); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var scene models.Scene
+	scene.GetIfExist(change.SceneID)
+	scenes := []models.Scene{scene}
+	tasks.IndexScenes(&scenes)
+	scene.ApplyUserState(getUserID(req))
+
+	resp.WriteHeaderAndEntity(http.StatusOK, scene)
+}
+
 func (i SceneResource) selectScript(req *restful.Request, resp *restful.Response) {
 	sceneId, err := strconv.Atoi(req.PathParameter("scene-id"))
 	if err != nil {
@@ -828,6 +984,7 @@ func (i SceneResource) selectScript(req *restful.Request, resp *restful.Response
 			}
 		}
 		_ = scene.GetIfExistByPK(uint(sceneId))
+		scene.ApplyUserState(getUserID(req))
 	}
 	db.Close()
 
@@ -856,10 +1013,12 @@ func (i SceneResource) editScene(req *restful.Request, resp *restful.Response) {
 		if scene.Title != r.Title {
 			scene.Title = r.Title
 			models.AddAction(scene.SceneID, "edit", "title", r.Title)
+			scene.LockColumn("title")
 		}
 		if scene.Synopsis != r.Synopsis {
 			scene.Synopsis = r.Synopsis
 			models.AddAction(scene.SceneID, "edit", "synopsis", r.Synopsis)
+			scene.LockColumn("synopsis")
 		}
 		if scene.Studio != r.Studio {
 			scene.Studio = r.Studio
@@ -877,6 +1036,7 @@ func (i SceneResource) editScene(req *restful.Request, resp *restful.Response) {
 			scene.ReleaseDateText = r.ReleaseDate
 			scene.ReleaseDate, _ = time.Parse("2006-01-02", r.ReleaseDate)
 			models.AddAction(scene.SceneID, "edit", "release_date_text", r.ReleaseDate)
+			scene.LockColumn("release_date_text")
 		}
 		if scene.FilenamesArr != r.FilenamesArr {
 			scene.FilenamesArr = r.FilenamesArr
@@ -889,6 +1049,7 @@ func (i SceneResource) editScene(req *restful.Request, resp *restful.Response) {
 		if scene.CoverURL != r.CoverURL {
 			scene.CoverURL = r.CoverURL
 			models.AddAction(scene.SceneID, "edit", "cover_url", r.CoverURL)
+			scene.LockColumn("cover_url")
 		}
 		if scene.IsMultipart != r.IsMultipart {
 			scene.IsMultipart = r.IsMultipart
@@ -897,6 +1058,7 @@ func (i SceneResource) editScene(req *restful.Request, resp *restful.Response) {
 		if strconv.Itoa(scene.Duration) != r.Duration {
 			scene.Duration, _ = strconv.Atoi(r.Duration)
 			models.AddAction(scene.SceneID, "edit", "duration", r.Duration)
+			scene.LockColumn("duration")
 		}
 		ProcessTagChanges(&scene, &r.Tags, db)
 
@@ -928,11 +1090,49 @@ func (i SceneResource) editScene(req *restful.Request, resp *restful.Response) {
 		// Update search index with new data
 		scenes := []models.Scene{scene}
 		tasks.IndexScenes(&scenes)
+		tasks.UpdateSceneSidecars(scene.ID)
+		scene.ApplyUserState(getUserID(req))
 
 		resp.WriteHeaderAndEntity(http.StatusOK, scene)
 	}
 }
 
+// bulkEditScenes applies a set of operations to the listed scenes, or all the scenes matching a filter
+func (i SceneResource) bulkEditScenes(req *restful.Request, resp *restful.Response) {
+	var r RequestBulkSceneEdit
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	if len(r.Operations) == 0 {
+		APIError(req, resp, http.StatusBadRequest, errors.New("no operations given"))
+		return
+	}
+
+	ids := r.SceneIDs
+	if len(ids) == 0 {
+		if r.Filter == nil {
+			APIError(req, resp, http.StatusBadRequest, errors.New("scene_ids or a filter is required"))
+			return
+		}
+		r.Filter.UserID = getUserID(req)
+		for _, scene := range models.QueryScenesFull(*r.Filter).Scenes {
+			ids = append(ids, scene.ID)
+		}
+	}
+
+	if err := models.BulkEditScenes(ids, r.Operations, getUserID(req)); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	scenes := models.GetScenesByIDs(ids)
+	tasks.IndexScenes(&scenes)
+	tasks.UpdateSceneSidecars(ids...)
+
+	resp.WriteHeaderAndEntity(http.StatusOK, ResponseBulkSceneEdit{Scenes: len(scenes)})
+}
+
 func getTagDifferences(arr1, arr2 []models.Tag) []string {
 	output := make([]string, 0)
 	for _, v := range arr1 {
@@ -987,13 +1187,10 @@ func ProcessTagChanges(scene *models.Scene, tags *[]string, db *gorm.DB) {
 	var diffs []string
 
 	newTags := make([]models.Tag, 0)
-	for _, v := range *tags {
+	for _, v := range models.ConvertTags(*tags) {
 		nt := models.Tag{}
-		tagClean := models.ConvertTag(v)
-		if tagClean != "" {
-			db.Where(&models.Tag{Name: tagClean}).FirstOrCreate(&nt)
-			newTags = append(newTags, nt)
-		}
+		db.Where(&models.Tag{Name: v}).FirstOrCreate(&nt)
+		newTags = append(newTags, nt)
 	}
 
 	diffs = deep.Equal(scene.Tags, newTags)
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	restfulspec "github.com/emicklei/go-restful-openapi/v2"
+	"github.com/emicklei/go-restful/v3"
+	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/tasks"
+)
+
+type RequestSaveTagRule struct {
+	Ordering  int    `json:"ordering"`
+	IsEnabled bool   `json:"is_enabled"`
+	Kind      string `json:"kind"`
+	Match     string `json:"match"`
+	Replace   string `json:"replace"`
+}
+
+type TagRuleResource struct{}
+
+func (i TagRuleResource) WebService() *restful.WebService {
+	tags := []string{"TagRule"}
+
+	ws := new(restful.WebService)
+
+	ws.Path("/api/tag_rule").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/list").To(i.listRules).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]models.TagRule{}))
+
+	ws.Route(ws.GET("/preview").To(i.previewRules).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]models.TagRulePreview{}))
+
+	ws.Route(ws.POST("").To(i.createRule).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestSaveTagRule{}).
+		Writes(models.TagRule{}))
+
+	ws.Route(ws.PUT("/{rule-id}").To(i.updateRule).
+		Param(ws.PathParameter("rule-id", "Rule ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestSaveTagRule{}).
+		Writes(models.TagRule{}))
+
+	ws.Route(ws.DELETE("/{rule-id}").To(i.deleteRule).
+		Param(ws.PathParameter("rule-id", "Rule ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	ws.Route(ws.POST("/apply").To(i.applyRules).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	return ws
+}
+
+func (i TagRuleResource) listRules(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeaderAndEntity(http.StatusOK, models.GetTagRules())
+}
+
+func (i TagRuleResource) previewRules(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeaderAndEntity(http.StatusOK, models.PreviewTagRules(models.GetTagRules()))
+}
+
+func (i TagRuleResource) createRule(req *restful.Request, resp *restful.Response) {
+	var r RequestSaveTagRule
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var rule models.TagRule
+	i.saveRule(req, resp, rule, r)
+}
+
+func (i TagRuleResource) updateRule(req *restful.Request, resp *restful.Response) {
+	id, err := strconv.Atoi(req.PathParameter("rule-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var r RequestSaveTagRule
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var rule models.TagRule
+	if rule.GetIfExistByPK(uint(id)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	i.saveRule(req, resp, rule, r)
+}
+
+func (i TagRuleResource) saveRule(req *restful.Request, resp *restful.Response, rule models.TagRule, r RequestSaveTagRule) {
+	rule.Ordering = r.Ordering
+	rule.IsEnabled = r.IsEnabled
+	rule.Kind = r.Kind
+	rule.Match = r.Match
+	rule.Replace = r.Replace
+	if err := rule.Validate(); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	rule.Save()
+
+	resp.WriteHeaderAndEntity(http.StatusOK, rule)
+}
+
+func (i TagRuleResource) deleteRule(req *restful.Request, resp *restful.Response) {
+	id, err := strconv.Atoi(req.PathParameter("rule-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var rule models.TagRule
+	if rule.GetIfExistByPK(uint(id)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	rule.Delete()
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+// applyRules re-runs the rules over the tags of existing scenes
+func (i TagRuleResource) applyRules(req *restful.Request, resp *restful.Response) {
+	go tasks.CleanTags()
+}
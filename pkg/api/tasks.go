@@ -22,6 +22,11 @@ type RequestScrapeTPDB struct {
 	SceneUrl string `json:"sceneUrl"`
 }
 
+type RequestStashSync struct {
+	URL    string `json:"url"`
+	ApiKey string `json:"apiKey"`
+}
+
 type RequestSingleScrape struct {
 	Site           string                            `json:"site"`
 	SceneUrl       string                            `json:"sceneurl"`
@@ -81,6 +86,9 @@ func (i TaskResource) WebService() *restful.WebService {
 	ws.Route(ws.GET("/preview/generate").To(i.previewGenerate).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.GET("/mobile/generate").To(i.mobileGenerate).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
 	ws.Route(ws.GET("/funscript/export-all").To(i.exportAllFunscripts).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
@@ -100,6 +108,15 @@ func (i TaskResource) WebService() *restful.WebService {
 	ws.Route(ws.POST("/scrape-tpdb").To(i.scrapeTPDB).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 
+	ws.Route(ws.GET("/sidecars").To(i.writeSidecars).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	ws.Route(ws.POST("/stash-sync").To(i.stashSync).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	ws.Route(ws.GET("/dedup").To(i.dedup).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
 	ws.Route(ws.GET("/relink_alt_aource_scenes").To(i.relink_alt_aource_scenes).
 		Metadata(restfulspec.KeyOpenAPITags, tags))
 	return ws
@@ -205,6 +222,14 @@ func (i TaskResource) previewGenerate(req *restful.Request, resp *restful.Respon
 	go tasks.GeneratePreviews(nil)
 }
 
+func (i TaskResource) mobileGenerate(req *restful.Request, resp *restful.Response) {
+	go tasks.GenerateMobileRenditions(nil)
+}
+
+func (i TaskResource) dedup(req *restful.Request, resp *restful.Response) {
+	go tasks.FindDuplicateFiles()
+}
+
 func (i TaskResource) scrapeJAVR(req *restful.Request, resp *restful.Response) {
 	var r RequestScrapeJAVR
 	err := req.ReadEntity(&r)
@@ -230,6 +255,24 @@ func (i TaskResource) scrapeTPDB(req *restful.Request, resp *restful.Response) {
 		go tasks.ScrapeTPDB(strings.TrimSpace(r.ApiToken), strings.TrimSpace(r.SceneUrl))
 	}
 }
+
+func (i TaskResource) writeSidecars(req *restful.Request, resp *restful.Response) {
+	go tasks.WriteSidecars(nil)
+}
+
+func (i TaskResource) stashSync(req *restful.Request, resp *restful.Response) {
+	var r RequestStashSync
+	err := req.ReadEntity(&r)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if strings.TrimSpace(r.URL) != "" {
+		go tasks.StashSync(strings.TrimSpace(r.URL), strings.TrimSpace(r.ApiKey))
+	}
+}
+
 func (i TaskResource) relink_alt_aource_scenes(req *restful.Request, resp *restful.Response) {
 	go tasks.MatchAlternateSources()
 }
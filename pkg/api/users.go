@@ -0,0 +1,263 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	restfulspec "github.com/emicklei/go-restful-openapi/v2"
+	"github.com/emicklei/go-restful/v3"
+	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/session"
+)
+
+const userAttribute = "xbvr.user"
+
+type RequestSaveUser struct {
+	Name           string `json:"name"`
+	Password       string `json:"password"`
+	RemovePassword bool   `json:"remove_password"`
+}
+
+type ResponseUser struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	HasPassword bool   `json:"has_password"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+func newResponseUser(user models.User) ResponseUser {
+	return ResponseUser{ID: user.ID, Name: user.Name, HasPassword: user.Password != "", IsDefault: user.ID == models.DefaultUserID}
+}
+
+// userFilter resolves the profile of a web UI request from its basic auth credentials. Without
+// credentials the default profile is used, unless a profile is protected by a password, then
+// everyone has to log in.
+func userFilter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	user := models.GetDefaultUser()
+
+	name, password, ok := req.Request.BasicAuth()
+	if ok {
+		if profile, found := models.ResolveUser(name, password); found {
+			user = profile
+		} else {
+			// the login of the web UI itself stands for the default profile
+			ok = common.IsUIAuthEnabled() && name == common.EnvConfig.UIUsername && password == common.EnvConfig.UIPassword
+		}
+	}
+	if !ok && models.UsersHavePasswords() {
+		resp.AddHeader("WWW-Authenticate", `Basic realm="default"`)
+		resp.WriteErrorString(http.StatusUnauthorized, "401: Unauthorized")
+		return
+	}
+
+	req.SetAttribute(userAttribute, user)
+	chain.ProcessFilter(req, resp)
+}
+
+// setPlayerUser makes the profile a player logged in with the active one, watch sessions of the
+// player are recorded for it
+func setPlayerUser(req *restful.Request, user models.User) {
+	req.SetAttribute(userAttribute, user)
+	session.SetPlayerUser(req.Request.RemoteAddr, user.ID)
+}
+
+// getUserID returns the profile of the request, as resolved by userFilter or the player filters
+func getUserID(req *restful.Request) uint {
+	if user, ok := req.Attribute(userAttribute).(models.User); ok && user.ID != 0 {
+		return user.ID
+	}
+	return models.DefaultUserID
+}
+
+type UserResource struct{}
+
+func (i UserResource) WebService() *restful.WebService {
+	tags := []string{"User"}
+
+	ws := new(restful.WebService)
+
+	ws.Path("/api/users").
+		Filter(userFilter).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("").To(i.listUsers).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes([]ResponseUser{}))
+
+	ws.Route(ws.GET("/current").To(i.getCurrentUser).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Writes(ResponseUser{}))
+
+	ws.Route(ws.POST("").To(i.createUser).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestSaveUser{}).
+		Writes(ResponseUser{}))
+
+	ws.Route(ws.PUT("/{user-id}").To(i.updateUser).
+		Param(ws.PathParameter("user-id", "User ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Reads(RequestSaveUser{}).
+		Writes(ResponseUser{}))
+
+	ws.Route(ws.DELETE("/{user-id}").To(i.removeUser).
+		Param(ws.PathParameter("user-id", "User ID").DataType("int")).
+		Metadata(restfulspec.KeyOpenAPITags, tags))
+
+	return ws
+}
+
+func (i UserResource) listUsers(req *restful.Request, resp *restful.Response) {
+	out := []ResponseUser{}
+	for _, user := range models.GetUsers() {
+		out = append(out, newResponseUser(user))
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, out)
+}
+
+func (i UserResource) getCurrentUser(req *restful.Request, resp *restful.Response) {
+	var user models.User
+	user.GetIfExistByPK(getUserID(req))
+	resp.WriteHeaderAndEntity(http.StatusOK, newResponseUser(user))
+}
+
+func (i UserResource) createUser(req *restful.Request, resp *restful.Response) {
+	if getUserID(req) != models.DefaultUserID {
+		APIError(req, resp, http.StatusForbidden, errors.New("only the default profile can add profiles"))
+		return
+	}
+
+	var r RequestSaveUser
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	user := models.User{Name: strings.TrimSpace(r.Name)}
+	if err := i.validateName(user); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	if err := user.SetPassword(r.Password); err != nil {
+		APIError(req, resp, http.StatusInternalServerError, err)
+		return
+	}
+	if err := checkProfileProtection(user); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	user.Save()
+
+	resp.WriteHeaderAndEntity(http.StatusOK, newResponseUser(user))
+}
+
+func (i UserResource) updateUser(req *restful.Request, resp *restful.Response) {
+	id, err := strconv.Atoi(req.PathParameter("user-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// profiles can change their own name and password, the default profile can change all of them
+	if getUserID(req) != models.DefaultUserID && getUserID(req) != uint(id) {
+		APIError(req, resp, http.StatusForbidden, errors.New("profile can't be changed from this profile"))
+		return
+	}
+
+	var r RequestSaveUser
+	if err := req.ReadEntity(&r); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	var user models.User
+	if user.GetIfExistByPK(uint(id)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	user.Name = strings.TrimSpace(r.Name)
+	if err := i.validateName(user); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	if r.RemovePassword {
+		user.SetPassword("")
+	} else if r.Password != "" {
+		if err := user.SetPassword(r.Password); err != nil {
+			APIError(req, resp, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := checkProfileProtection(user); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	user.Save()
+
+	resp.WriteHeaderAndEntity(http.StatusOK, newResponseUser(user))
+}
+
+func (i UserResource) removeUser(req *restful.Request, resp *restful.Response) {
+	id, err := strconv.Atoi(req.PathParameter("user-id"))
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if getUserID(req) != models.DefaultUserID {
+		APIError(req, resp, http.StatusForbidden, errors.New("only the default profile can remove profiles"))
+		return
+	}
+
+	var user models.User
+	if user.GetIfExistByPK(uint(id)) != nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err := user.Delete(); err != nil {
+		APIError(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+// checkProfileProtection refuses to protect profiles while the default profile, which manages all
+// of them, can still be used without logging in
+func checkProfileProtection(changed models.User) error {
+	if common.IsUIAuthEnabled() {
+		return nil
+	}
+
+	users := models.GetUsers()
+	if changed.ID == 0 {
+		users = append(users, changed)
+	}
+	defaultProtected, othersProtected := false, false
+	for _, u := range users {
+		if u.ID == changed.ID {
+			u = changed
+		}
+		if u.ID == models.DefaultUserID {
+			defaultProtected = u.Password != ""
+		} else if u.Password != "" {
+			othersProtected = true
+		}
+	}
+	if othersProtected && !defaultProtected {
+		return errors.New("the default profile needs a password, or the web UI a login, before other profiles can be protected")
+	}
+	return nil
+}
+
+func (i UserResource) validateName(user models.User) error {
+	if user.Name == "" {
+		return errors.New("profile name is required")
+	}
+	var existing models.User
+	if existing.GetIfExist(user.Name) == nil && existing.ID != user.ID {
+		return errors.New("profile name is already taken")
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ var HeatmapDir string
 var IndexDirV2 string
 var ScrapeCacheDir string
 var VideoPreviewDir string
+var MobileDir string
 var VideoThumbnailDir string
 var ScriptHeatmapDir string
 var MyFilesDir string
@@ -41,24 +42,26 @@ func DirSize(path string) (int64, error) {
 	return size, err
 }
 
-func InitPaths() {
-
-	enableLocalStorage := flag.Bool("localstorage", false, "Optional: Use local folder to store application data")
-	app_dir := flag.String("app_dir", "", "Optional: path to the application directory")
-	cache_dir := flag.String("cache_dir", "", "Optional: path to the tempoarary scraper cache directory")
-	imgproxy_dir := flag.String("imgproxy_dir", "", "Optional: path to the imageproxy directory")
-	search_dir := flag.String("search_dir", "", "Optional: path to the Search Index directory")
-	preview_dir := flag.String("preview_dir", "", "Optional: path to the Scraper Cache directory")
-	scriptsheatmap_dir := flag.String("scripts_heatmap_dir", "", "Optional: path to the scripts_heatmap directory")
-	myfiles_dir := flag.String("myfiles_dir", "", "Optional: path to the myfiles directory for serving users own content (eg images")
-	databaseurl := flag.String("database_url", "", "Optional: override default database path")
-	web_port := flag.Int("web_port", 0, "Optional: override default Web Page port 9999")
-	ws_addr := flag.String("ws_addr", "", "Optional: override default Websocket address from the default 0.0.0.0:9998")
-	db_connection_pool_size := flag.Int("db_connection_pool_size", 0, "Optional: sets a limit to the number of db connections while scraping")
-	concurrentSscrapers := flag.Int("concurrent_scrapers", 0, "Optional: sets a limit to the number of concurrent scrapers")
-
-	flag.Parse()
+var (
+	enableLocalStorage      = flag.Bool("localstorage", false, "Optional: Use local folder to store application data")
+	app_dir                 = flag.String("app_dir", "", "Optional: path to the application directory")
+	cache_dir               = flag.String("cache_dir", "", "Optional: path to the tempoarary scraper cache directory")
+	imgproxy_dir            = flag.String("imgproxy_dir", "", "Optional: path to the imageproxy directory")
+	search_dir              = flag.String("search_dir", "", "Optional: path to the Search Index directory")
+	preview_dir             = flag.String("preview_dir", "", "Optional: path to the Scraper Cache directory")
+	mobile_dir              = flag.String("mobile_dir", "", "Optional: path to the mobile renditions directory")
+	scriptsheatmap_dir      = flag.String("scripts_heatmap_dir", "", "Optional: path to the scripts_heatmap directory")
+	myfiles_dir             = flag.String("myfiles_dir", "", "Optional: path to the myfiles directory for serving users own content (eg images")
+	databaseurl             = flag.String("database_url", "", "Optional: override default database path")
+	web_port                = flag.Int("web_port", 0, "Optional: override default Web Page port 9999")
+	ws_addr                 = flag.String("ws_addr", "", "Optional: override default Websocket address from the default 0.0.0.0:9998")
+	db_connection_pool_size = flag.Int("db_connection_pool_size", 0, "Optional: sets a limit to the number of db connections while scraping")
+	concurrentSscrapers     = flag.Int("concurrent_scrapers", 0, "Optional: sets a limit to the number of concurrent scrapers")
+)
 
+// InitPaths sets up the application directories, from the command line flags when they were parsed
+// before, otherwise from the environment
+func InitPaths() {
 	if *app_dir == "" {
 		tmp := os.Getenv("XBVR_APPDIR")
 		app_dir = &tmp
@@ -90,6 +93,7 @@ func InitPaths() {
 
 	VideoPreviewDir = getPath(*preview_dir, "XBVR_VIDEOPREVIEWDIR", "video_preview")
 	VideoThumbnailDir = filepath.Join(AppDir, "video_thumbnail")
+	MobileDir = getPath(*mobile_dir, "XBVR_MOBILEDIR", "mobile")
 	ScriptHeatmapDir = getPath(*scriptsheatmap_dir, "XBVR_SCRIPTHEATMAPDIR", "script_heatmap")
 
 	MyFilesDir = getPath(*myfiles_dir, "XBVR_MYFILESDIR", "myfiles")
@@ -140,6 +144,7 @@ func InitPaths() {
 	_ = os.MkdirAll(ScriptHeatmapDir, os.ModePerm)
 	_ = os.MkdirAll(MyFilesDir, os.ModePerm)
 	_ = os.MkdirAll(DownloadDir, os.ModePerm)
+	_ = os.MkdirAll(MobileDir, os.ModePerm)
 }
 func getPath(commandLinePath string, environmentName string, directoryName string) string {
 	if commandLinePath != "" {
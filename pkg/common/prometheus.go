@@ -0,0 +1,60 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// MetricsRegistry holds the metrics served at /metrics for Prometheus
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	taskRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xbvr_task_runs_total",
+		Help: "Task runs by task, site for scrapers, and outcome.",
+	}, []string{"task", "site", "outcome"})
+	taskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "xbvr_task_duration_seconds",
+		Help:    "Duration of task runs.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400},
+	}, []string{"task", "site"})
+	taskLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xbvr_task_last_run_timestamp_seconds",
+		Help: "Time the last run of a task finished.",
+	}, []string{"task", "site"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "xbvr_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by API group.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"group", "method", "code"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		taskRuns, taskDuration, taskLastRun, HTTPRequestDuration,
+	)
+}
+
+// TimeTask starts timing a run of a task, site is the scraper for scrape runs. The returned func
+// records the duration and outcome of the run, only the first call counts.
+func TimeTask(task string, site string) func(err error) {
+	t0 := time.Now()
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			outcome := "success"
+			if err != nil {
+				outcome = "error"
+			}
+			taskRuns.WithLabelValues(task, site, outcome).Inc()
+			taskDuration.WithLabelValues(task, site).Observe(time.Since(t0).Seconds())
+			taskLastRun.WithLabelValues(task, site).SetToCurrentTime()
+		})
+	}
+}
@@ -74,6 +74,10 @@ type ObjectConfig struct {
 		TPDB struct {
 			ApiToken string `default:"" json:"apiToken"`
 		} `json:"tpdb"`
+		Stash struct {
+			URL    string `default:"" json:"url"`
+			ApiKey string `default:"" json:"apiKey"`
+		} `json:"stash"`
 	} `json:"vendor"`
 	Interfaces struct {
 		DLNA struct {
@@ -108,6 +112,11 @@ type ObjectConfig struct {
 			ScriptSortSeq   string `default:"" json:"script_sort_seq"`
 			SubtitleSortSeq string `default:"" json:"subtitle_sort_seq"`
 		} `json:"players"`
+		HLS struct {
+			Enabled       bool `default:"true" json:"enabled"`
+			MaxTranscodes int  `default:"2" json:"max_transcodes"`
+			CacheSizeGB   int  `default:"20" json:"cache_size_gb"`
+		} `json:"hls"`
 	} `json:"interfaces"`
 	Library struct {
 		Preview struct {
@@ -118,6 +127,11 @@ type ObjectConfig struct {
 			Resolution    int     `default:"400" json:"resolution"`
 			ExtraSnippet  bool    `default:"false" json:"extraSnippet"`
 		} `json:"preview"`
+		Mobile struct {
+			Playlists    []uint `json:"playlists"`
+			Width        int    `default:"2880" json:"width"`
+			VideoBitrate int    `default:"10000" json:"video_bitrate"`
+		} `json:"mobile"`
 	} `json:"library"`
 	Cron struct {
 		RescrapeSchedule struct {
@@ -174,10 +188,29 @@ type ObjectConfig struct {
 			HourEnd         int  `default:"23" json:"hourEnd"`
 			RunAtStartDelay int  `default:"0" json:"runAtStartDelay"`
 		} `json:"linkScenesSchedule"`
+		MobileSchedule struct {
+			Enabled         bool `default:"false" json:"enabled"`
+			HourInterval    int  `default:"2" json:"hourInterval"`
+			UseRange        bool `default:"false" json:"useRange"`
+			MinuteStart     int  `default:"0" json:"minuteStart"`
+			HourStart       int  `default:"0" json:"hourStart"`
+			HourEnd         int  `default:"23" json:"hourEnd"`
+			RunAtStartDelay int  `default:"0" json:"runAtStartDelay"`
+		} `json:"mobileSchedule"`
 	} `json:"cron"`
 	Storage struct {
-		MatchOhash bool     `default:"false" json:"match_ohash"`
-		VideoExt   []string `json:"video_ext"`
+		MatchOhash      bool     `default:"false" json:"match_ohash"`
+		MatchContent    bool     `default:"true" json:"match_content"`
+		VideoExt        []string `json:"video_ext"`
+		WatchVolumes    bool     `default:"true" json:"watch_volumes"`
+		ScanConcurrency int      `default:"4" json:"scan_concurrency"`
+		Organizer       struct {
+			Template     string `default:"{site}/{title} - {cast}.{ext}" json:"template"`
+			TargetVolume uint   `default:"0" json:"target_volume"`
+		} `json:"organizer"`
+		Sidecars struct {
+			Enabled bool `default:"false" json:"enabled"`
+		} `json:"sidecars"`
 	} `json:"storage"`
 	ScraperSettings struct {
 		TMWVRNet struct {
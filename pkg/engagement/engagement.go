@@ -0,0 +1,123 @@
+// Package engagement aggregates which seconds of a video file its viewers watched, over all the
+// players sessions are tracked for.
+package engagement
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xbapps/xbvr/pkg/common"
+)
+
+// Heatmap counts the views of every second of a file
+type Heatmap struct {
+	FileID   uint    `json:"file_id"`
+	Duration float64 `json:"duration"`
+	Sessions int     `json:"sessions"`
+	Counts   []int   `json:"counts"`
+}
+
+// heatmaps are read, merged and written back by the sessions of a file
+var mu sync.Mutex
+
+func heatmapPath(fileID uint) string {
+	return filepath.Join(common.HeatmapDir, fmt.Sprintf("file-%v.json", fileID))
+}
+
+// Load returns the heatmap of a file, an empty one if nobody watched it yet
+func Load(fileID uint) (Heatmap, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return load(fileID)
+}
+
+func load(fileID uint) (Heatmap, error) {
+	h := Heatmap{FileID: fileID}
+	b, err := os.ReadFile(heatmapPath(fileID))
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(b, &h); err != nil {
+		return h, err
+	}
+	h.FileID = fileID
+	return h, nil
+}
+
+// Record adds the seconds of a file one session watched to its heatmap
+func Record(fileID uint, duration float64, counts []int) error {
+	if fileID == 0 || !hasViews(counts) {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	h, err := load(fileID)
+	if err != nil {
+		return err
+	}
+	if duration > 0 {
+		h.Duration = duration
+	}
+	for len(h.Counts) < len(counts) {
+		h.Counts = append(h.Counts, 0)
+	}
+	for k, v := range counts {
+		h.Counts[k] += v
+	}
+	h.Sessions++
+	return save(h)
+}
+
+func save(h Heatmap) error {
+	if err := os.MkdirAll(common.HeatmapDir, os.ModePerm); err != nil {
+		return err
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	// written aside and renamed, readers never see half a heatmap
+	tmp := heatmapPath(h.FileID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, heatmapPath(h.FileID))
+}
+
+// ImportLegacy moves the heatmap of a scene, from before heatmaps were kept per file, to the file
+// of the scene
+func ImportLegacy(sceneID uint, fileID uint) error {
+	path := filepath.Join(common.HeatmapDir, fmt.Sprintf("%v.json", sceneID))
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var counts []int
+	if err := json.Unmarshal(b, &counts); err != nil {
+		return err
+	}
+	if err := Record(fileID, 0, counts); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func hasViews(counts []int) bool {
+	for _, v := range counts {
+		if v > 0 {
+			return true
+		}
+	}
+	return false
+}
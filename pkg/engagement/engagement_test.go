@@ -0,0 +1,102 @@
+package engagement
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xbapps/xbvr/pkg/common"
+)
+
+func TestMain(m *testing.M) {
+	appDir, _ := os.MkdirTemp("", "xbvr-test")
+	os.Setenv("XBVR_APPDIR", appDir)
+	common.InitPaths()
+
+	code := m.Run()
+	os.RemoveAll(appDir)
+	os.Exit(code)
+}
+
+func TestRecord(t *testing.T) {
+	if err := Record(1, 4, []int{0, 1, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	// a longer session of the same file extends the heatmap
+	if err := Record(1, 6, []int{0, 1, 0, 0, 1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	// sessions that watched nothing aren't counted
+	Record(1, 6, []int{0, 0})
+
+	h, err := Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Sessions != 2 || h.Duration != 6 {
+		t.Errorf("%v sessions of %vs, want 2 of 6s", h.Sessions, h.Duration)
+	}
+	want := []int{0, 2, 1, 0, 1, 1}
+	for i := range want {
+		if i >= len(h.Counts) || h.Counts[i] != want[i] {
+			t.Fatalf("counts %v, want %v", h.Counts, want)
+		}
+	}
+}
+
+func TestImportLegacy(t *testing.T) {
+	legacy := filepath.Join(common.HeatmapDir, "7.json")
+	b, _ := json.Marshal([]int{0, 3, 3})
+	os.MkdirAll(common.HeatmapDir, os.ModePerm)
+	os.WriteFile(legacy, b, 0644)
+
+	if err := ImportLegacy(7, 70); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("legacy heatmap wasn't removed")
+	}
+	if h, _ := Load(70); h.Sessions != 1 || len(h.Counts) != 3 || h.Counts[1] != 3 {
+		t.Errorf("imported heatmap %+v", h)
+	}
+}
+
+func TestMostReplayed(t *testing.T) {
+	// ten viewers, most watched a minute once, some went back to 20-30s and many to 40-45s
+	counts := make([]int, 60)
+	for i := range counts {
+		counts[i] = 10
+	}
+	for i := 20; i < 30; i++ {
+		counts[i] = 16
+	}
+	for i := 40; i < 45; i++ {
+		counts[i] = 30
+	}
+	h := Heatmap{Duration: 60, Sessions: 10, Counts: counts}
+
+	segments := h.MostReplayed(1)
+	if len(segments) != 1 || segments[0].Start < 38 || segments[0].End > 47 {
+		t.Fatalf("segments %+v, want the one around 40-45s", segments)
+	}
+	if segments := h.MostReplayed(5); len(segments) != 2 || segments[0].Start > segments[1].Start {
+		t.Errorf("segments %+v, want both in playback order", segments)
+	}
+
+	// nothing stands out of a heatmap watched evenly
+	if segments := (Heatmap{Counts: []int{1, 1, 1, 1, 1, 1}}).MostReplayed(5); len(segments) != 0 {
+		t.Errorf("segments %+v of an even heatmap", segments)
+	}
+}
+
+func TestRender(t *testing.T) {
+	h := Heatmap{Counts: []int{0, 1, 5, 1}}
+	img := h.Render(100, 10)
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 10 {
+		t.Fatalf("image of %v", img.Bounds())
+	}
+	if img.At(0, 0) == img.At(60, 0) {
+		t.Error("most watched second drawn like an unwatched one")
+	}
+}
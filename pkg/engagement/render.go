@@ -0,0 +1,61 @@
+package engagement
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// colors from seconds nobody watched to the most watched ones
+var gradient = []colorful.Color{
+	{R: 0.05, G: 0.05, B: 0.15},
+	{R: 0.12, G: 0.3, B: 0.7},
+	{R: 0.95, G: 0.75, B: 0.1},
+	{R: 0.9, G: 0.1, B: 0.1},
+}
+
+// Render draws the heatmap as a strip, every column colored by the views of the seconds it covers
+func (h Heatmap) Render(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	max := 0
+	for _, v := range h.Counts {
+		if v > max {
+			max = v
+		}
+	}
+
+	for x := 0; x < width; x++ {
+		var c color.Color = gradient[0]
+		if max > 0 && len(h.Counts) > 0 {
+			from := x * len(h.Counts) / width
+			to := (x + 1) * len(h.Counts) / width
+			if to <= from {
+				to = from + 1
+			}
+			peak := 0
+			for _, v := range h.Counts[from:to] {
+				if v > peak {
+					peak = v
+				}
+			}
+			c = gradientColor(float64(peak) / float64(max))
+		}
+		draw.Draw(img, image.Rect(x, 0, x+1, height), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func gradientColor(t float64) color.Color {
+	if t <= 0 {
+		return gradient[0]
+	}
+	if t >= 1 {
+		return gradient[len(gradient)-1]
+	}
+	pos := t * float64(len(gradient)-1)
+	i := int(pos)
+	return gradient[i].BlendLab(gradient[i+1], pos-float64(i)).Clamped()
+}
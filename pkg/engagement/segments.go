@@ -0,0 +1,91 @@
+package engagement
+
+import (
+	"sort"
+)
+
+// Segment is a part of a file watched more often than the rest
+type Segment struct {
+	Start float64 `json:"start"` // seconds
+	End   float64 `json:"end"`
+	Views float64 `json:"views"` // at the peak of the segment
+	Score float64 `json:"score"` // views at the peak relative to the typical second
+}
+
+const (
+	// views are averaged over this many seconds, so single skipped seconds don't split segments
+	smoothingWindow = 5
+	// seconds watched this much more often than the typical one were replayed
+	replayedRatio    = 1.5
+	minSegmentLength = 3
+)
+
+// MostReplayed returns up to max segments viewers returned to the most, in playback order
+func (h Heatmap) MostReplayed(max int) []Segment {
+	smoothed := smooth(h.Counts, smoothingWindow)
+
+	// the typical second, peaks don't raise it the way they raise the mean
+	var watched []float64
+	for _, v := range smoothed {
+		if v > 0 {
+			watched = append(watched, v)
+		}
+	}
+	if len(watched) == 0 {
+		return nil
+	}
+	sort.Float64s(watched)
+	typical := watched[len(watched)/2]
+	threshold := typical * replayedRatio
+
+	var segments []Segment
+	start := -1
+	for i := 0; i <= len(smoothed); i++ {
+		// a second watched only once wasn't replayed, however few views the rest has
+		above := i < len(smoothed) && smoothed[i] >= threshold && smoothed[i] > 1
+		if above && start < 0 {
+			start = i
+		}
+		if !above && start >= 0 {
+			if i-start >= minSegmentLength {
+				s := Segment{Start: float64(start), End: float64(i)}
+				for _, v := range smoothed[start:i] {
+					if v > s.Views {
+						s.Views = v
+					}
+				}
+				s.Score = s.Views / typical
+				segments = append(segments, s)
+			}
+			start = -1
+		}
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Score > segments[j].Score })
+	if len(segments) > max {
+		segments = segments[:max]
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Start < segments[j].Start })
+	return segments
+}
+
+// smooth is the moving average of the counts over window seconds
+func smooth(counts []int, window int) []float64 {
+	out := make([]float64, len(counts))
+	for i := range counts {
+		from := i - window/2
+		if from < 0 {
+			from = 0
+		}
+		to := from + window
+		if to > len(counts) {
+			to = len(counts)
+		}
+		sum := 0
+		for _, v := range counts[from:to] {
+			sum += v
+		}
+		out[i] = float64(sum) / float64(to-from)
+	}
+	return out
+}
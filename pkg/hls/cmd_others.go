@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package hls
+
+import (
+	"context"
+	"os/exec"
+)
+
+func buildCmd(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, name, arg...)
+}
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package hls
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+func buildCmd(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd
+}
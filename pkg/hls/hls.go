@@ -0,0 +1,337 @@
+// Package hls transcodes videos to HLS on demand with software encoding. Every segment is encoded
+// on its own when a player asks for it and kept in a cache directory, so seeking only costs the
+// segments actually watched.
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SegmentDuration is the length of a segment in seconds
+const SegmentDuration = 6.0
+
+const (
+	audioBitrate     = 128
+	transcodeTimeout = 2 * time.Minute
+	pruneInterval    = time.Minute
+)
+
+// Profile is a rendition offered in the master playlist. Videos are scaled down to Width, keeping
+// the aspect ratio, but never scaled up.
+type Profile struct {
+	Name         string
+	Width        int
+	VideoBitrate int // kbit/s
+}
+
+var Profiles = []Profile{
+	{Name: "4k", Width: 4096, VideoBitrate: 20000},
+	{Name: "3k", Width: 2880, VideoBitrate: 12000},
+	{Name: "2k", Width: 1920, VideoBitrate: 6000},
+}
+
+func GetProfile(name string) (Profile, bool) {
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ProfilesFor returns the profiles worth offering for a video of the given width. Small videos
+// still get the smallest profile, as transcoding helps players that can't decode the source.
+func ProfilesFor(width int) []Profile {
+	var out []Profile
+	for _, p := range Profiles {
+		if p.Width <= width {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, Profiles[len(Profiles)-1])
+	}
+	return out
+}
+
+// Size returns the dimensions of the rendition of a width x height video
+func (p Profile) Size(width int, height int) (int, int) {
+	if width <= 0 || height <= 0 || width <= p.Width {
+		return width, height
+	}
+	h := int(math.Round(float64(height)*float64(p.Width)/float64(width)/2)) * 2
+	return p.Width, h
+}
+
+func segmentCount(duration float64) int {
+	return int(math.Ceil(duration / SegmentDuration))
+}
+
+// MasterPlaylist lists a variant playlist per profile, at "<profile>/index.m3u8" relative to the
+// master playlist
+func MasterPlaylist(profiles []Profile, width int, height int) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, p := range profiles {
+		w, h := p.Size(width, height)
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"avc1.640033,mp4a.40.2\"\n", (p.VideoBitrate+audioBitrate)*1000, w, h)
+		fmt.Fprintf(&b, "%v/index.m3u8\n", p.Name)
+	}
+	return b.String()
+}
+
+// MediaPlaylist lists the segments of a video, at "<index>.ts" relative to the playlist. The
+// playlist is complete right away, segments are only encoded once they are requested.
+func MediaPlaylist(duration float64) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n", int(math.Ceil(SegmentDuration)))
+	for i := 0; i < segmentCount(duration); i++ {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%d.ts\n", segmentLength(duration, i), i)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
+
+func segmentLength(duration float64, index int) float64 {
+	return math.Min(SegmentDuration, duration-float64(index)*SegmentDuration)
+}
+
+type segmentJob struct {
+	done chan struct{}
+	err  error
+}
+
+// Transcoder encodes segments, limiting the number of ffmpeg processes running at the same time
+type Transcoder struct {
+	CacheDir string
+	FFmpeg   string
+
+	mu            sync.Mutex
+	maxConcurrent int
+	maxCacheSize  int64
+	running       int
+	wake          chan struct{}
+	inflight      map[string]*segmentJob
+	lastPrune     time.Time
+}
+
+func NewTranscoder(cacheDir string, ffmpeg string, maxConcurrent int, maxCacheSize int64) *Transcoder {
+	return &Transcoder{
+		CacheDir:      cacheDir,
+		FFmpeg:        ffmpeg,
+		maxConcurrent: maxConcurrent,
+		maxCacheSize:  maxCacheSize,
+		wake:          make(chan struct{}),
+		inflight:      map[string]*segmentJob{},
+	}
+}
+
+func (t *Transcoder) SetMaxConcurrent(n int) {
+	t.mu.Lock()
+	t.maxConcurrent = n
+	t.broadcast()
+	t.mu.Unlock()
+}
+
+func (t *Transcoder) SetMaxCacheSize(size int64) {
+	t.mu.Lock()
+	t.maxCacheSize = size
+	t.mu.Unlock()
+}
+
+// broadcast wakes up everyone waiting for a transcode slot, t.mu must be held
+func (t *Transcoder) broadcast() {
+	close(t.wake)
+	t.wake = make(chan struct{})
+}
+
+// acquire waits for a transcode slot. Without wait it gives up right away if none is free.
+func (t *Transcoder) acquire(ctx context.Context, wait bool) bool {
+	for {
+		t.mu.Lock()
+		if t.running < t.maxConcurrent {
+			t.running++
+			t.mu.Unlock()
+			return true
+		}
+		wake := t.wake
+		t.mu.Unlock()
+
+		if !wait {
+			return false
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (t *Transcoder) release() {
+	t.mu.Lock()
+	t.running--
+	t.broadcast()
+	t.mu.Unlock()
+}
+
+func (t *Transcoder) segmentPath(key string, p Profile, index int) string {
+	return filepath.Join(t.CacheDir, key, p.Name, fmt.Sprintf("%05d.ts", index))
+}
+
+// Segment returns the path of a segment of input, encoding it first if it isn't cached. key
+// identifies the input in the cache.
+func (t *Transcoder) Segment(ctx context.Context, key string, input string, p Profile, index int, duration float64) (string, error) {
+	return t.segment(ctx, key, input, p, index, duration, true)
+}
+
+// Prefetch encodes a segment in the background if a transcode slot is free, so it is ready by
+// the time the player gets to it
+func (t *Transcoder) Prefetch(key string, input string, p Profile, index int, duration float64) {
+	if index >= segmentCount(duration) {
+		return
+	}
+	go t.segment(context.Background(), key, input, p, index, duration, false)
+}
+
+func (t *Transcoder) segment(ctx context.Context, key string, input string, p Profile, index int, duration float64, wait bool) (string, error) {
+	if index < 0 || index >= segmentCount(duration) {
+		return "", errors.New("hls: segment out of range")
+	}
+
+	dest := t.segmentPath(key, p, index)
+	if _, err := os.Stat(dest); err == nil {
+		// keep recently watched segments in the cache
+		now := time.Now()
+		os.Chtimes(dest, now, now)
+		return dest, nil
+	}
+
+	t.mu.Lock()
+	if job, ok := t.inflight[dest]; ok {
+		t.mu.Unlock()
+		if !wait {
+			return "", nil
+		}
+		select {
+		case <-job.done:
+			if job.err != nil {
+				// the job may have been a prefetch that found no free slot, try on our own
+				return t.segment(ctx, key, input, p, index, duration, wait)
+			}
+			return dest, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	job := &segmentJob{done: make(chan struct{})}
+	t.inflight[dest] = job
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.inflight, dest)
+		t.mu.Unlock()
+		close(job.done)
+	}()
+
+	if !t.acquire(ctx, wait) {
+		job.err = errors.New("hls: no transcode slot available")
+		if ctx.Err() != nil {
+			job.err = ctx.Err()
+		}
+		return "", job.err
+	}
+	job.err = t.transcode(input, p, index, duration, dest)
+	t.release()
+
+	if job.err == nil {
+		t.prune()
+	}
+	return dest, job.err
+}
+
+func (t *Transcoder) transcode(input string, p Profile, index int, duration float64, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	start := float64(index) * SegmentDuration
+	tmp := dest + ".tmp"
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-ss", fmt.Sprintf("%.3f", start),
+		"-i", input,
+		"-t", fmt.Sprintf("%.3f", segmentLength(duration, index)),
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", p.Width),
+		"-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p",
+		"-b:v", fmt.Sprintf("%dk", p.VideoBitrate),
+		"-maxrate", fmt.Sprintf("%dk", p.VideoBitrate*3/2),
+		"-bufsize", fmt.Sprintf("%dk", p.VideoBitrate*2),
+		"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", audioBitrate), "-ac", "2",
+		// keep timestamps continuous across separately encoded segments
+		"-output_ts_offset", fmt.Sprintf("%.3f", start),
+		"-muxdelay", "0",
+		"-f", "mpegts", tmp,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcodeTimeout)
+	defer cancel()
+	out, err := buildCmd(ctx, t.FFmpeg, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("hls: ffmpeg failed: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.Rename(tmp, dest)
+}
+
+// prune deletes the least recently used segments once the cache grows beyond the max cache size
+func (t *Transcoder) prune() {
+	t.mu.Lock()
+	maxCacheSize := t.maxCacheSize
+	if maxCacheSize <= 0 || time.Since(t.lastPrune) < pruneInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastPrune = time.Now()
+	t.mu.Unlock()
+
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cached
+	var total int64
+	filepath.Walk(t.CacheDir, func(path string, f os.FileInfo, err error) error {
+		if err == nil && !f.IsDir() && filepath.Ext(path) == ".ts" {
+			files = append(files, cached{path, f.Size(), f.ModTime()})
+			total += f.Size()
+		}
+		return nil
+	})
+	if total <= maxCacheSize {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= maxCacheSize*9/10 {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfilesFor(t *testing.T) {
+	if p := ProfilesFor(8192); len(p) != len(Profiles) {
+		t.Errorf("expected all profiles for 8K, got %v", p)
+	}
+	if p := ProfilesFor(2880); len(p) != 2 || p[0].Name != "3k" {
+		t.Errorf("unexpected profiles for 2880 wide videos: %v", p)
+	}
+	if p := ProfilesFor(1280); len(p) != 1 || p[0].Name != "2k" {
+		t.Errorf("expected the smallest profile for small videos, got %v", p)
+	}
+}
+
+func TestProfileSize(t *testing.T) {
+	p, _ := GetProfile("4k")
+	if w, h := p.Size(8192, 4096); w != 4096 || h != 2048 {
+		t.Errorf("unexpected size %vx%v", w, h)
+	}
+	if w, h := p.Size(1920, 960); w != 1920 || h != 960 {
+		t.Errorf("videos must not be scaled up, got %vx%v", w, h)
+	}
+}
+
+func TestPlaylists(t *testing.T) {
+	master := MasterPlaylist(ProfilesFor(5760), 5760, 2880)
+	if !strings.Contains(master, "RESOLUTION=4096x2048") || !strings.Contains(master, "3k/index.m3u8\n") {
+		t.Errorf("unexpected master playlist:\n%v", master)
+	}
+
+	media := MediaPlaylist(15)
+	for _, s := range []string{"#EXTINF:6.000,\n0.ts\n", "#EXTINF:6.000,\n1.ts\n", "#EXTINF:3.000,\n2.ts\n", "#EXT-X-ENDLIST"} {
+		if !strings.Contains(media, s) {
+			t.Errorf("expected %q in media playlist:\n%v", s, media)
+		}
+	}
+	if strings.Contains(media, "3.ts") {
+		t.Errorf("unexpected segment in media playlist:\n%v", media)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	tr := NewTranscoder(t.TempDir(), "ffmpeg", 1, 0)
+	if !tr.acquire(context.Background(), false) {
+		t.Fatal("expected a free slot")
+	}
+	if tr.acquire(context.Background(), false) {
+		t.Fatal("expected no free slot")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if tr.acquire(ctx, true) {
+		t.Fatal("expected waiting to stop with the context")
+	}
+
+	tr.release()
+	if !tr.acquire(context.Background(), false) {
+		t.Fatal("expected a free slot after release")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 4; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.ts", i))
+		os.WriteFile(path, make([]byte, 100), 0644)
+		modTime := time.Now().Add(time.Duration(i-4) * time.Hour)
+		os.Chtimes(path, modTime, modTime)
+	}
+
+	tr := NewTranscoder(dir, "ffmpeg", 1, 250)
+	// options can change while segments are pruned
+	done := make(chan struct{})
+	go func() {
+		tr.SetMaxCacheSize(250)
+		close(done)
+	}()
+	tr.prune()
+	<-done
+
+	// the oldest segments go until the cache is below 90% of its size
+	for i := 0; i < 4; i++ {
+		_, err := os.Stat(filepath.Join(dir, fmt.Sprintf("%d.ts", i)))
+		if exists := err == nil; exists != (i >= 2) {
+			t.Errorf("segment %d exists = %v", i, exists)
+		}
+	}
+}
+
+func TestSegmentCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of ffmpeg")
+	}
+
+	// stand-in for ffmpeg that counts its runs and writes the output file, the last argument
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\necho run >> " + counter + "\nfor last; do :; done\necho segment > \"$last\"\n"
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTranscoder(filepath.Join(dir, "cache"), ffmpeg, 2, 0)
+	p, _ := GetProfile("2k")
+	for i := 0; i < 2; i++ {
+		path, err := tr.Segment(context.Background(), "1", "input.mp4", p, 1, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "segment\n" {
+			t.Fatalf("unexpected segment content %q", data)
+		}
+	}
+	if runs, _ := os.ReadFile(counter); strings.Count(string(runs), "run") != 1 {
+		t.Errorf("expected a single transcode, got %v", strings.Count(string(runs), "run"))
+	}
+
+	if _, err := tr.Segment(context.Background(), "1", "input.mp4", p, 4, 20); err == nil {
+		t.Error("expected an error for a segment past the end")
+	}
+}
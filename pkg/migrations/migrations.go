@@ -24,6 +24,7 @@ import (
 
 	"github.com/xbapps/xbvr/pkg/common"
 	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/engagement"
 	"github.com/xbapps/xbvr/pkg/models"
 	"github.com/xbapps/xbvr/pkg/scrape"
 	"github.com/xbapps/xbvr/pkg/tasks"
@@ -201,7 +202,8 @@ func Migrate(migrateTo string) {
 					Ordering:     -100,
 					SearchParams: list.ToJSON(),
 				}
-				listDefault.Save()
+				// playlists only get their user_id column later, in 0089-user-profiles
+				tx.Omit("user_id").Create(&listDefault)
 
 				list = RequestSceneList{
 					IsAvailable:  optional.NewBool(true),
@@ -216,7 +218,7 @@ func Migrate(migrateTo string) {
 					Ordering:     -49,
 					SearchParams: list.ToJSON(),
 				}
-				listDeoRecent.Save()
+				tx.Omit("user_id").Create(&listDeoRecent)
 
 				list = RequestSceneList{
 					IsAvailable:  optional.NewBool(true),
@@ -232,7 +234,7 @@ func Migrate(migrateTo string) {
 					Ordering:     -48,
 					SearchParams: list.ToJSON(),
 				}
-				listDeoFav.Save()
+				tx.Omit("user_id").Create(&listDeoFav)
 
 				list = RequestSceneList{
 					IsAvailable:  optional.NewBool(true),
@@ -248,7 +250,7 @@ func Migrate(migrateTo string) {
 					Ordering:     -47,
 					SearchParams: list.ToJSON(),
 				}
-				listDeoWatch.Save()
+				tx.Omit("user_id").Create(&listDeoWatch)
 
 				return nil
 			},
@@ -847,6 +849,107 @@ func Migrate(migrateTo string) {
 				return tx.AutoMigrate(File{}).Error
 			},
 		},
+		{
+			ID: "0088-organize-journal",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.OrganizeJournal{}).Error
+			},
+		},
+		{
+			ID: "0089-user-profiles",
+			Migrate: func(tx *gorm.DB) error {
+				type History struct {
+					UserID uint `gorm:"index"`
+				}
+				type Playlist struct {
+					UserID uint `gorm:"index"`
+				}
+				err := tx.AutoMigrate(&models.User{}, &models.UserScene{}, &models.UserActor{}, History{}, Playlist{}).Error
+				if err != nil {
+					return err
+				}
+
+				// everything personal so far belongs to the default profile, the old columns are
+				// left in place but no longer used
+				err = tx.Save(&models.User{ID: models.DefaultUserID, Name: "default"}).Error
+				if err != nil {
+					return err
+				}
+				if tx.Dialect().HasColumn("scenes", "favourite") {
+					err = tx.Exec(`insert into user_scenes (user_id, scene_id, star_rating, favourite, watchlist, wishlist, is_watched, total_watch_time)
+						select ?, id, star_rating, favourite, watchlist, wishlist, is_watched, total_watch_time from scenes
+						where star_rating > 0 or favourite = ? or watchlist = ? or wishlist = ? or is_watched = ? or total_watch_time > 0`,
+						models.DefaultUserID, true, true, true, true).Error
+					if err != nil {
+						return err
+					}
+				}
+				if tx.Dialect().HasColumn("actors", "favourite") {
+					err = tx.Exec(`insert into user_actors (user_id, actor_id, star_rating, favourite, watchlist)
+						select ?, id, star_rating, favourite, watchlist from actors
+						where star_rating > 0 or favourite = ? or watchlist = ?`,
+						models.DefaultUserID, true, true).Error
+					if err != nil {
+						return err
+					}
+				}
+				err = tx.Exec("update histories set user_id = ?", models.DefaultUserID).Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("update playlists set user_id = ? where is_system = ?", models.DefaultUserID, false).Error
+			},
+		},
+
+		{
+			ID: "0090-tag-rules",
+			Migrate: func(tx *gorm.DB) error {
+				err := tx.AutoMigrate(&models.TagRule{}).Error
+				if err != nil {
+					return err
+				}
+				// start with the conversions that used to be hard-coded
+				for i, rule := range models.DefaultTagRules {
+					rule.Ordering = (i + 1) * 10
+					rule.IsEnabled = true
+					if err := tx.Create(&rule).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+
+		{
+			ID: "0091-scrape-runs",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ScrapeRun{}).Error
+			},
+		},
+
+		{
+			ID: "0092-scene-changes",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SceneChange{}).Error
+			},
+		},
+
+		{
+			ID: "0093-scene-locked-fields",
+			Migrate: func(tx *gorm.DB) error {
+				type Scene struct {
+					LockedFields string `json:"locked_fields" sql:"type:text;" xbvrbackup:"locked_fields"`
+				}
+				return tx.AutoMigrate(Scene{}).Error
+			},
+		},
+
+		{
+			ID: "0094-playback-positions",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.PlaybackPosition{}).Error
+			},
+		},
 
 		// ===============================================================================================
 		// Put DB Schema migrations above this line and migrations that rely on the updated schema below
@@ -2387,6 +2490,28 @@ func Migrate(migrateTo string) {
 				return tx.Table("scenes").AddIndex("idx_scenes_scraper_id", "scraper_id").Error
 			},
 		},
+		{
+			ID: "0095-engagement-heatmaps-per-file",
+			Migrate: func(tx *gorm.DB) error {
+				// viewer heatmaps used to be kept per scene, move them to the file of single file scenes
+				entries, _ := os.ReadDir(common.HeatmapDir)
+				for _, entry := range entries {
+					sceneID, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+					if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+						continue
+					}
+					var files []models.File
+					tx.Where("scene_id = ? and type = ?", sceneID, "video").Find(&files)
+					if len(files) != 1 {
+						continue
+					}
+					if err := engagement.ImportLegacy(uint(sceneID), files[0].ID); err != nil {
+						common.Log.Warnf("Heatmap of scene %v not converted: %v", sceneID, err)
+					}
+				}
+				return nil
+			},
+		},
 	}
 
 	// Wrap migrations to automatically track progress
@@ -2400,7 +2525,7 @@ func Migrate(migrateTo string) {
 			Migrate: func(tx *gorm.DB) error {
 				// Update status before running migration
 				msg := fmt.Sprintf("Running migration %s", migrations[currentIndex].ID)
-				tlog.Infof(msg)
+				tlog.Infof("%s", msg)
 				config.UpdateMigrationStatus(migrations[currentIndex].ID, currentIndex+1, totalMigrations, msg)
 
 				// Run the actual migration
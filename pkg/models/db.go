@@ -157,7 +157,9 @@ func RemoveAllLocks() {
 	}
 }
 
-func init() {
+// Init sets up the application directories, logging and the database, the command line flags
+// have to be parsed before
+func Init() {
 	common.InitPaths()
 	common.InitLogging()
 	parseDBConnString()
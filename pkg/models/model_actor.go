@@ -22,11 +22,13 @@ type Actor struct {
 
 	AvailCount int `json:"avail_count" xbvrbackup:"-"`
 
-	ImageUrl   string  `json:"image_url" xbvrbackup:"image_url"`
-	ImageArr   string  `json:"image_arr" sql:"type:text;" xbvrbackup:"image_arr"`
-	StarRating float64 `json:"star_rating" xbvrbackup:"star_rating"`
-	Favourite  bool    `json:"favourite" gorm:"default:false" xbvrbackup:"favourite"`
-	Watchlist  bool    `json:"watchlist" gorm:"default:false" xbvrbackup:"watchlist"`
+	ImageUrl string `json:"image_url" xbvrbackup:"image_url"`
+	ImageArr string `json:"image_arr" sql:"type:text;" xbvrbackup:"image_arr"`
+
+	// personal state, stored per profile in user_actors, see ApplyUserState
+	StarRating float64 `json:"star_rating" gorm:"-" xbvrbackup:"star_rating"`
+	Favourite  bool    `json:"favourite" gorm:"-" xbvrbackup:"favourite"`
+	Watchlist  bool    `json:"watchlist" gorm:"-" xbvrbackup:"watchlist"`
 
 	BirthDate   time.Time `json:"birth_date" xbvrbackup:"birth_date"`
 	Nationality string    `json:"nationality" xbvrbackup:"nationality"`
@@ -79,6 +81,9 @@ type RequestActorList struct {
 	MinSceneRating optional.Float64  `json:"min_scene_rating"`
 	MaxSceneRating optional.Float64  `json:"max_scene_rating"`
 	Sort           optional.String   `json:"sort"`
+
+	// UserID is the profile the personal filters apply to, the default profile if not set
+	UserID uint `json:"-"`
 }
 type ResponseActorList struct {
 	Results            int     `json:"results"`
@@ -175,12 +180,17 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 
 	var out ResponseActorList
 
+	userID := r.UserID
+	if userID == 0 {
+		userID = DefaultUserID
+	}
+
 	for _, i := range r.Lists {
 		if i.OrElse("") == "watchlist" {
-			tx = tx.Where("actors.watchlist = ?", true)
+			tx = tx.Where(userActorWhere(userID, "ua.watchlist = 1"))
 		}
 		if i.OrElse("") == "favourite" {
-			tx = tx.Where("actors.favourite = ?", true)
+			tx = tx.Where(userActorWhere(userID, "ua.favourite = 1"))
 		}
 	}
 
@@ -226,9 +236,9 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 		switch fieldName {
 		case "In Watchlist":
 			if truefalse {
-				where = "actors.watchlist = 1"
+				where = userActorWhere(userID, "ua.watchlist = 1")
 			} else {
-				where = "actors.watchlist = 0"
+				where = "not " + userActorWhere(userID, "ua.watchlist = 1")
 			}
 		case "Is Scripted":
 			if truefalse {
@@ -238,15 +248,15 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 			}
 		case "Is Favourite":
 			if truefalse {
-				where = "actors.favourite = 1"
+				where = userActorWhere(userID, "ua.favourite = 1")
 			} else {
-				where = "actors.favourite = 0"
+				where = "not " + userActorWhere(userID, "ua.favourite = 1")
 			}
 		case "Has Rating":
 			if truefalse {
-				where = "actors.star_rating > 0"
+				where = userActorWhere(userID, "ua.star_rating > 0")
 			} else {
-				where = "actors.star_rating = 0"
+				where = "not " + userActorWhere(userID, "ua.star_rating > 0")
 			}
 		case "Aka Group":
 			if truefalse {
@@ -288,9 +298,9 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 			}
 		case "Rating 0", "Rating .5", "Rating 1", "Rating 1.5", "Rating 2", "Rating 2.5", "Rating 3", "Rating 3.5", "Rating 4", "Rating 4.5", "Rating 5":
 			if truefalse {
-				where = "actors.star_rating = " + fieldName[7:]
+				where = userActorColumn(userID, "star_rating") + " = " + fieldName[7:]
 			} else {
-				where = "actors.star_rating <> " + fieldName[7:]
+				where = userActorColumn(userID, "star_rating") + " <> " + fieldName[7:]
 			}
 		case "Has Tattoo":
 			if truefalse {
@@ -378,16 +388,16 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 		tx = tx.Where("actors.avail_count <= ?", r.MinAvail.OrElse(150))
 	}
 	if r.MinRating.OrElse(0) > 0 {
-		tx = tx.Where("actors.star_rating >= ?", r.MinRating.OrElse(0))
+		tx = tx.Where(userActorColumn(userID, "star_rating")+" >= ?", r.MinRating.OrElse(0))
 	}
 	if r.MaxRating.OrElse(5) < 5 {
-		tx = tx.Where("actors.star_rating <= ?", r.MaxRating.OrElse(5))
+		tx = tx.Where(userActorColumn(userID, "star_rating")+" <= ?", r.MaxRating.OrElse(5))
 	}
 	if r.MinSceneRating.OrElse(0) > 0 {
-		tx = tx.Where("(select AVG(s.star_rating) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id and s.star_rating > 0 and is_hidden=0) >= ?", r.MinSceneRating.OrElse(0))
+		tx = tx.Where(sceneRatingAverageSQL(userID)+" >= ?", r.MinSceneRating.OrElse(0))
 	}
 	if r.MaxSceneRating.OrElse(5) < 5 {
-		tx = tx.Where("(select AVG(s.star_rating) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id and s.star_rating > 0 and is_hidden=0) <= ?", r.MaxSceneRating.OrElse(50))
+		tx = tx.Where(sceneRatingAverageSQL(userID)+" <= ?", r.MaxSceneRating.OrElse(50))
 	}
 	var sites []string
 	var mustHaveSites []string
@@ -427,15 +437,15 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 		tx = tx.Order("name desc")
 	case "rating_desc":
 		tx = tx.
-			Where("actors.star_rating > ?", 0).
-			Order("actors.star_rating desc")
+			Where(userActorWhere(userID, "ua.star_rating > 0")).
+			Order(userActorColumn(userID, "star_rating") + " desc")
 	case "rating_asc":
 		tx = tx.
-			Where("actors.star_rating > ?", 0).
-			Order("actors.star_rating asc")
+			Where(userActorWhere(userID, "ua.star_rating > 0")).
+			Order(userActorColumn(userID, "star_rating") + " asc")
 	case "scene_rating_desc":
 		tx = tx.
-			Order("(select AVG(s.star_rating) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id and s.star_rating > 0  and is_hidden=0) desc, (select count(*) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id and s.star_rating > 0 and is_hidden=0) desc, (select count(*) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id) desc")
+			Order(sceneRatingAverageSQL(userID) + " desc, " + sceneRatingCountSQL(userID) + " desc, (select count(*) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id) desc")
 	case "scene_release_desc":
 		tx = tx.
 			Order("IFNULL((select max(s.release_date) from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id),'1970-01-01') DESC, actors.avail_count desc, actors.`count` desc")
@@ -495,13 +505,15 @@ func QueryActors(r RequestActorList, enablePreload bool) ResponseActorList {
 	}
 	out.Offset = offset
 
-	tx = tx.Select(`distinct actors.*, 
-	(select AVG(s.star_rating) scene_avg from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id and s.star_rating > 0 and is_hidden=0) as scene_rating_average	
-	`)
+	tx = tx.Select(`distinct actors.*, ` + sceneRatingAverageSQL(userID) + ` as scene_rating_average`)
 
 	tx.Limit(limit).
 		Offset(offset).
 		Find(&out.Actors)
+	ApplyUserActorState(commonDb, userID, out.Actors)
+	for i := range out.Actors {
+		ApplyUserSceneState(commonDb, userID, out.Actors[i].Scenes)
+	}
 
 	return out
 }
@@ -526,18 +538,22 @@ func (o *Actor) GetIfExistByPK(id uint) error {
 		Where(&Actor{ID: id}).First(o).Error
 }
 
-func (o *Actor) GetIfExistByPKWithSceneAvg(id uint) error {
+// GetIfExistByPKWithSceneAvg loads the actor with the personal state of a profile
+func (o *Actor) GetIfExistByPKWithSceneAvg(id uint, userID uint) error {
 	commonDb, _ := GetCommonDB()
 
 	tx := commonDb.Model(&Actor{})
-	tx = tx.Select(`actors.*, 
-	(select AVG(s.star_rating) scene_avg from scene_cast sc join scenes s on s.id=sc.scene_id where sc.actor_id =actors.id and s.star_rating > 0 and is_hidden=0) as scene_rating_average`)
+	tx = tx.Select(`actors.*, ` + sceneRatingAverageSQL(userID) + ` as scene_rating_average`)
 
-	return tx.
+	err := tx.
 		Preload("Scenes", func(db *gorm.DB) *gorm.DB {
 			return db.Order("release_date DESC").Where("is_hidden = 0")
 		}).
 		Where(&Actor{ID: id}).First(o).Error
+	if err == nil {
+		o.ApplyUserState(userID)
+	}
+	return err
 }
 
 func (i *Actor) AddToImageArray(newValue string) bool {
@@ -3,6 +3,7 @@ package models
 import (
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -46,6 +47,14 @@ type File struct {
 }
 
 func (f *File) GetPath() string {
+	switch f.Volume.Type {
+	case "webdav":
+		// remote paths always use forward slashes
+		return path.Join(f.Path, f.Filename)
+	case "s3":
+		// path.Join would collapse the "s3://" scheme of the volume path
+		return f.Path + "/" + f.Filename
+	}
 	return filepath.Join(f.Path, f.Filename)
 }
 
@@ -70,6 +79,25 @@ func (f *File) Save() error {
 	return nil
 }
 
+// SaveFiles writes a batch of files in a single transaction
+func SaveFiles(files []File) error {
+	db, _ := GetDB()
+	defer db.Close()
+
+	return retry.Do(
+		func() error {
+			tx := db.Begin()
+			for i := range files {
+				if err := tx.Save(&files[i]).Error; err != nil {
+					tx.Rollback()
+					return err
+				}
+			}
+			return tx.Commit().Error
+		},
+	)
+}
+
 func (f *File) GetIfExistByPK(id uint) error {
 	db, _ := GetDB()
 	defer db.Close()
@@ -78,6 +106,12 @@ func (f *File) GetIfExistByPK(id uint) error {
 }
 
 func (f *File) Exists() bool {
+	if f.Type == "mobile" {
+		// mobile renditions are kept in common.MobileDir, outside of any volume
+		_, err := os.Stat(f.GetPath())
+		return err == nil
+	}
+
 	switch f.Volume.Type {
 	case "local":
 		if _, err := os.Stat(f.GetPath()); os.IsNotExist(err) {
@@ -87,6 +121,9 @@ func (f *File) Exists() bool {
 	case "putio":
 		// NOTE: we're assuming files weren't removed via Put.io web UI, so there's no need to check
 		return true
+	case "webdav", "s3":
+		// NOTE: removals on the server are picked up by the next rescan, avoid a request per file here
+		return true
 	default:
 		return false
 	}
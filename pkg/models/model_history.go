@@ -11,6 +11,7 @@ type History struct {
 	CreatedAt time.Time `json:"-" xbvrbackup:"created_at-"`
 	UpdatedAt time.Time `json:"-" xbvrbackup:"updated_at"`
 
+	UserID    uint      `gorm:"index" json:"user_id" xbvrbackup:"-"`
 	SceneID   uint      `json:"scene_id" xbvrbackup:"-"`
 	TimeStart time.Time `json:"time_start" xbvrbackup:"time_start"`
 	TimeEnd   time.Time `json:"time_end" xbvrbackup:"time_end"`
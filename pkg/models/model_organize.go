@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// OrganizeJournal records a file moved by the organizer, so the run it was part of can be undone
+type OrganizeJournal struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Batch        string     `gorm:"index" json:"batch"`
+	FileID       uint       `gorm:"index" json:"file_id"`
+	FromVolumeID uint       `json:"from_volume_id"`
+	FromPath     string     `sql:"type:varchar(1024);" json:"from_path"`
+	FromFilename string     `sql:"type:varchar(1024);" json:"from_filename"`
+	ToVolumeID   uint       `json:"to_volume_id"`
+	ToPath       string     `sql:"type:varchar(1024);" json:"to_path"`
+	ToFilename   string     `sql:"type:varchar(1024);" json:"to_filename"`
+	UndoneAt     *time.Time `json:"undone_at"`
+}
+
+func (o *OrganizeJournal) Save() error {
+	db, _ := GetDB()
+	defer db.Close()
+
+	return SaveWithRetry(db, o)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// PlaybackPosition is where a profile stopped playing a file, players offer to resume from it
+type PlaybackPosition struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID   uint    `gorm:"unique_index:idx_playback_position" json:"user_id"`
+	FileID   uint    `gorm:"unique_index:idx_playback_position" json:"file_id"`
+	SceneID  uint    `gorm:"index" json:"scene_id"`
+	Position float64 `json:"position"` // seconds
+	Duration float64 `json:"duration"` // seconds, 0 if unknown
+	Finished bool    `json:"finished"`
+}
+
+// a file played up to this share of its duration counts as watched to the end
+const playbackFinishedRatio = 0.95
+
+// SavePlaybackPosition records the position a profile reached in a file of a scene
+func SavePlaybackPosition(userID uint, sceneID uint, fileID uint, position float64, duration float64) error {
+	if sceneID == 0 || fileID == 0 || position <= 0 {
+		return nil
+	}
+
+	commonDb, _ := GetCommonDB()
+
+	var o PlaybackPosition
+	commonDb.Where(&PlaybackPosition{UserID: userID, FileID: fileID}).First(&o)
+	o.UserID = userID
+	o.FileID = fileID
+	o.SceneID = sceneID
+	o.Position = position
+	if duration > 0 {
+		o.Duration = duration
+	}
+	o.Finished = o.Duration > 0 && position >= o.Duration*playbackFinishedRatio
+	return SaveWithRetry(commonDb, &o)
+}
+
+// GetResumePosition returns the position a profile last stopped at in a scene, unless it was
+// watched to the end
+func GetResumePosition(userID uint, sceneID uint) (PlaybackPosition, bool) {
+	commonDb, _ := GetCommonDB()
+
+	var o PlaybackPosition
+	err := commonDb.Where("user_id = ? and scene_id = ?", userID, sceneID).Order("updated_at desc").First(&o).Error
+	if err != nil || o.Finished {
+		return PlaybackPosition{}, false
+	}
+	return o, true
+}
+
+// continueWatchingWhere matches the scenes a profile stopped playing before the end
+func continueWatchingWhere(userID uint) string {
+	return fmt.Sprintf(`exists (select 1 from playback_positions pp where pp.scene_id = scenes.id and pp.user_id = %d
+		and pp.updated_at = (select max(p2.updated_at) from playback_positions p2 where p2.scene_id = scenes.id and p2.user_id = %d)
+		and pp.finished = 0)`, userID, userID)
+}
+
+// lastPlayedColumn is the time a profile last played a scene, used for sorting
+func lastPlayedColumn(userID uint) string {
+	return fmt.Sprintf("(select max(pp.updated_at) from playback_positions pp where pp.scene_id = scenes.id and pp.user_id = %d)", userID)
+}
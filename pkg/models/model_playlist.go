@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/jinzhu/gorm"
 )
 
 // Playlist data model
@@ -12,6 +13,7 @@ type Playlist struct {
 	CreatedAt time.Time `json:"-" xbvrbackup:"-"`
 	UpdatedAt time.Time `json:"-" xbvrbackup:"-"`
 
+	UserID       uint   `gorm:"index" json:"user_id" xbvrbackup:"-"` // 0 for system playlists shared by all profiles
 	Name         string `json:"name" xbvrbackup:"name"`
 	Ordering     int    `json:"ordering" xbvrbackup:"ordering"`
 	IsSystem     bool   `json:"is_system" xbvrbackup:"is_system"`
@@ -41,3 +43,10 @@ func (o *Playlist) Save() error {
 
 	return nil
 }
+
+// VisibleToUser is a scope limiting playlists to those of a profile and the shared system ones
+func VisibleToUser(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("playlists.user_id in (?)", []uint{0, userID})
+	}
+}
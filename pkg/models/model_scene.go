@@ -79,21 +79,23 @@ type Scene struct {
 	SceneURL        string    `gorm:"size:500" json:"scene_url" xbvrbackup:"scene_url"`
 	MemberURL       string    `json:"members_url" xbvrbackup:"members_url"`
 	IsMultipart     bool      `json:"is_multipart" xbvrbackup:"is_multipart"`
+	LockedFields    string    `json:"locked_fields" sql:"type:text;" xbvrbackup:"locked_fields"`
 
-	StarRating     float64         `json:"star_rating" xbvrbackup:"star_rating"`
-	Favourite      bool            `json:"favourite" gorm:"default:false" xbvrbackup:"favourite"`
-	Watchlist      bool            `json:"watchlist" gorm:"default:false" xbvrbackup:"watchlist"`
-	Wishlist       bool            `json:"wishlist" gorm:"default:false" xbvrbackup:"wishlist"`
+	// personal state, stored per profile in user_scenes, see ApplyUserState
+	StarRating     float64         `json:"star_rating" gorm:"-" xbvrbackup:"star_rating"`
+	Favourite      bool            `json:"favourite" gorm:"-" xbvrbackup:"favourite"`
+	Watchlist      bool            `json:"watchlist" gorm:"-" xbvrbackup:"watchlist"`
+	Wishlist       bool            `json:"wishlist" gorm:"-" xbvrbackup:"wishlist"`
 	IsAvailable    bool            `json:"is_available" gorm:"default:false" xbvrbackup:"-"`
 	IsAccessible   bool            `json:"is_accessible" gorm:"default:false" xbvrbackup:"-"`
-	IsWatched      bool            `json:"is_watched" gorm:"default:false" xbvrbackup:"is_watched"`
+	IsWatched      bool            `json:"is_watched" gorm:"-" xbvrbackup:"is_watched"`
 	IsScripted     bool            `json:"is_scripted" gorm:"default:false" xbvrbackup:"-"`
 	Cuepoints      []SceneCuepoint `json:"cuepoints" xbvrbackup:"-"`
 	History        []History       `json:"history" xbvrbackup:"-"`
 	AddedDate      time.Time       `json:"added_date" xbvrbackup:"added_date"`
 	LastOpened     time.Time       `json:"last_opened" xbvrbackup:"last_opened"`
 	TotalFileSize  int64           `json:"total_file_size" xbvrbackup:"-"`
-	TotalWatchTime int             `json:"total_watch_time" gorm:"default:0" xbvrbackup:"total_watch_time"`
+	TotalWatchTime int             `json:"total_watch_time" gorm:"-" xbvrbackup:"total_watch_time"`
 
 	HasVideoPreview bool `json:"has_preview" gorm:"default:false" xbvrbackup:"-"`
 	// HasVideoThumbnail bool `json:"has_video_thumbnail" gorm:"default:false"`
@@ -226,11 +228,11 @@ func (o *Scene) GetFiles() ([]File, error) {
 	return files, nil
 }
 
-func (o *Scene) GetTotalWatchTime() int {
+func (o *Scene) GetTotalWatchTime(userID uint) int {
 	commonDb, _ := GetCommonDB()
 
 	totalResult := struct{ Total float64 }{}
-	commonDb.Raw(`select sum(duration) as total from histories where scene_id = ?`, o.ID).Scan(&totalResult)
+	commonDb.Raw(`select sum(duration) as total from histories where scene_id = ? and user_id = ?`, o.ID, userID).Scan(&totalResult)
 
 	return int(totalResult.Total)
 }
@@ -253,6 +255,16 @@ func (o *Scene) GetVideoFilesSorted(sort string) ([]File, error) {
 	return files, nil
 }
 
+// GetMobileFiles returns the lower resolution renditions of the scene, largest first
+func (o *Scene) GetMobileFiles() ([]File, error) {
+	commonDb, _ := GetCommonDB()
+
+	var files []File
+	commonDb.Where("scene_id = ? AND type = ?", o.ID, "mobile").Order("video_width desc").Find(&files)
+
+	return files, nil
+}
+
 func (o *Scene) GetScriptFiles() ([]File, error) {
 	files, err := o.GetScriptFilesSorted("is_selected_script DESC, created_time DESC")
 	return files, err
@@ -362,7 +374,9 @@ func (o *Scene) UpdateStatus() {
 
 		if videos > 0 && !o.IsAvailable {
 			o.IsAvailable = true
-			o.Wishlist = false
+			// nobody needs to wish for it anymore
+			commonDb, _ := GetCommonDB()
+			commonDb.Model(&UserScene{}).Where("scene_id = ? and wishlist = ?", o.ID, true).Update("wishlist", false)
 			changed = true
 		}
 
@@ -397,12 +411,6 @@ func (o *Scene) UpdateStatus() {
 		changed = true
 	}
 
-	totalWatchTime := o.GetTotalWatchTime()
-	if o.TotalWatchTime != totalWatchTime {
-		o.TotalWatchTime = totalWatchTime
-		changed = true
-	}
-
 	if changed {
 		o.Save()
 	}
@@ -413,10 +421,14 @@ func SceneCreateUpdateFromExternal(db *gorm.DB, ext ScrapedScene) error {
 		return nil
 	}
 
+	// the scene as it was, to log what the scraper changes
+	var before Scene
+	db.Preload("Tags").Preload("Cast").Where(&Scene{SceneID: ext.SceneID}).First(&before)
+
 	var o Scene
 	db.Where(&Scene{SceneID: ext.SceneID}).FirstOrCreate(&o)
 
-	if o.Title != ext.Title {
+	if o.Title != ext.Title && !o.IsFieldLocked("title") {
 		// reset scriptfile.IsExported state on title change
 		scriptfiles, err := o.GetScriptFiles()
 		if err == nil {
@@ -435,20 +447,28 @@ func SceneCreateUpdateFromExternal(db *gorm.DB, ext ScrapedScene) error {
 	o.IsSubscribed = site.Subscribed
 
 	// Clean & Associate Tags
-	var tags = o.Tags
-	db.Model(&o).Association("Tags").Clear()
-	for idx, tag := range tags {
-		tmpTag := Tag{}
-		db.Where(&Tag{Name: tag.Name}).FirstOrCreate(&tmpTag)
-		tags[idx] = tmpTag
+	if !o.IsFieldLocked("tags") {
+		var tags = o.Tags
+		db.Model(&o).Association("Tags").Clear()
+		for idx, tag := range tags {
+			tmpTag := Tag{}
+			db.Where(&Tag{Name: tag.Name}).FirstOrCreate(&tmpTag)
+			tags[idx] = tmpTag
+		}
+		o.Tags = tags
 	}
-	o.Tags = tags
 	SaveWithRetry(db, &o)
+	recordSceneChanges(db, before, o, ext.ScraperID)
 
 	// Clean & Associate Actors
-	db.Model(&o).Association("Cast").Clear()
+	cast := ext.Cast
+	if o.IsFieldLocked("cast") {
+		cast = nil
+	} else {
+		db.Model(&o).Association("Cast").Clear()
+	}
 	var tmpActor Actor
-	for _, name := range ext.Cast {
+	for _, name := range cast {
 		tmpActor = Actor{}
 		db.Where(&Actor{Name: strings.Replace(name, ".", "", -1)}).FirstOrCreate(&tmpActor)
 		saveActor := false
@@ -533,14 +553,19 @@ func (o *Scene) PopulateSceneFieldsFromExternal(db *gorm.DB, ext ScrapedScene) {
 	o.EditsApplied = false
 	o.SceneID = ext.SceneID
 	o.ScraperId = ext.ScraperID
-	o.Title = ext.Title
+	if !o.IsFieldLocked("title") {
+		o.Title = ext.Title
+	}
 	o.SceneType = ext.SceneType
 	o.Studio = ext.Studio
 	o.Site = ext.Site
-	o.Duration = ext.Duration
-	o.Synopsis = ext.Synopsis
-	o.ReleaseDateText = ext.Released
-	if ext.Covers != nil {
+	if !o.IsFieldLocked("duration") {
+		o.Duration = ext.Duration
+	}
+	if !o.IsFieldLocked("synopsis") {
+		o.Synopsis = ext.Synopsis
+	}
+	if ext.Covers != nil && !o.IsFieldLocked("cover") {
 		o.CoverURL = ext.Covers[0]
 	}
 	o.SceneURL = ext.HomepageURL
@@ -557,10 +582,13 @@ func (o *Scene) PopulateSceneFieldsFromExternal(db *gorm.DB, ext ScrapedScene) {
 	o.TrailerType = ext.TrailerType
 	o.TrailerSource = ext.TrailerSrc
 
-	if ext.Released != "" {
-		dateParsed, err := dateparse.ParseLocal(strings.Replace(ext.Released, ",", "", -1))
-		if err == nil {
-			o.ReleaseDate = dateParsed
+	if !o.IsFieldLocked("release_date") {
+		o.ReleaseDateText = ext.Released
+		if ext.Released != "" {
+			dateParsed, err := dateparse.ParseLocal(strings.Replace(ext.Released, ",", "", -1))
+			if err == nil {
+				o.ReleaseDate = dateParsed
+			}
 		}
 	}
 
@@ -600,24 +628,25 @@ func (o *Scene) PopulateSceneFieldsFromExternal(db *gorm.DB, ext ScrapedScene) {
 	db.Where("id = ?", o.ScraperId).FirstOrInit(&site)
 	o.IsSubscribed = site.Subscribed
 
-	var tags []Tag
-	for _, name := range ext.Tags {
-		tagClean := ConvertTag(name)
-		if tagClean != "" {
-			tags = append(tags, Tag{Name: tagClean})
+	if !o.IsFieldLocked("tags") {
+		var tags []Tag
+		for _, name := range ConvertTags(ext.Tags) {
+			tags = append(tags, Tag{Name: name})
 		}
+		o.Tags = tags
 	}
-	o.Tags = tags
 
 	// Clean & Associate Actors
-	var cast []Actor
-	var tmpActor Actor
-	for _, name := range ext.Cast {
-		tmpActor = Actor{}
-		db.Where(&Actor{Name: strings.Replace(name, ".", "", -1)}).FirstOrCreate(&tmpActor)
-		cast = append(cast, tmpActor)
+	if !o.IsFieldLocked("cast") {
+		var cast []Actor
+		var tmpActor Actor
+		for _, name := range ext.Cast {
+			tmpActor = Actor{}
+			db.Where(&Actor{Name: strings.Replace(name, ".", "", -1)}).FirstOrCreate(&tmpActor)
+			cast = append(cast, tmpActor)
+		}
+		o.Cast = cast
 	}
-	o.Cast = cast
 }
 
 func SceneUpdateScriptData(db *gorm.DB, ext ScrapedScene) {
@@ -671,6 +700,9 @@ type RequestSceneList struct {
 	Volume       optional.Int      `json:"volume"`
 	Released     optional.String   `json:"releaseMonth"`
 	Sort         optional.String   `json:"sort"`
+
+	// UserID is the profile the personal filters apply to, the default profile if not set
+	UserID uint `json:"-"`
 }
 
 type ResponseSceneList struct {
@@ -730,10 +762,18 @@ func QueryScenes(r RequestSceneList, enablePreload bool) ResponseSceneList {
 			Preload("Cuepoints")
 	}
 	finalTx.Find(&out.Scenes)
+	ApplyUserSceneState(db, r.userID(), out.Scenes)
 
 	return out
 }
 
+func (r RequestSceneList) userID() uint {
+	if r.UserID == 0 {
+		return DefaultUserID
+	}
+	return r.UserID
+}
+
 func QuerySceneIDs(r RequestSceneList) []string {
 	db, _ := GetDB()
 	defer db.Close()
@@ -771,9 +811,14 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 	config := getConfig(db)
 
 	tx := db.Model(&Scene{})
+	userID := r.userID()
 
 	if r.IsWatched.Present() {
-		tx = tx.Where("is_watched = ?", r.IsWatched.OrElse(true))
+		if r.IsWatched.OrElse(true) {
+			tx = tx.Where(userSceneWhere(userID, "us.is_watched = 1"))
+		} else {
+			tx = tx.Where("not " + userSceneWhere(userID, "us.is_watched = 1"))
+		}
 	}
 
 	if r.Volume.Present() && r.Volume.OrElse(0) != 0 {
@@ -784,17 +829,20 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 
 	for _, i := range r.Lists {
 		if i.OrElse("") == "watchlist" {
-			tx = tx.Where("scenes.watchlist = ?", true)
+			tx = tx.Where(userSceneWhere(userID, "us.watchlist = 1"))
 		}
 		if i.OrElse("") == "favourite" {
-			tx = tx.Where("scenes.favourite = ?", true)
+			tx = tx.Where(userSceneWhere(userID, "us.favourite = 1"))
 		}
 		if i.OrElse("") == "wishlist" {
-			tx = tx.Where("wishlist = ?", true)
+			tx = tx.Where(userSceneWhere(userID, "us.wishlist = 1"))
 		}
 		if i.OrElse("") == "scripted" {
 			tx = tx.Where("is_scripted = ?", true)
 		}
+		if i.OrElse("") == "continue_watching" {
+			tx = tx.Where(continueWatchingWhere(userID))
+		}
 	}
 
 	// handle Attribute selections
@@ -843,7 +891,7 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 		case "Has Subtitles File":
 			where = "exists (select 1 from files where files.scene_id = scenes.id and files.`type` = 'subtitles')"
 		case "Has Rating":
-			where = "scenes.star_rating > 0"
+			where = userSceneWhere(userID, "us.star_rating > 0")
 		case "Has Cuepoints":
 			where = "exists (select 1 from scene_cuepoints where scene_cuepoints.scene_id = scenes.id)"
 		case "Has Simple Cuepoints":
@@ -857,7 +905,7 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 		case "Has Subscription":
 			where = "is_subscribed = 1"
 		case "Rating":
-			where = "scenes.star_rating = " + value
+			where = userSceneWhere(userID, "us.star_rating = "+value)
 		case "No Actor/Cast":
 			where = "exists (select 1 from scenes s left join scene_cast sc on sc.scene_id =s.id where s.id=scenes.id and  sc.scene_id is NULL)"
 		case "Cast 6+":
@@ -915,11 +963,11 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 		case "Codec":
 			where = "exists (select 1 from files where files.scene_id = scenes.id and files.`type` = 'video' and files.video_codec_name = '" + value + "')"
 		case "In Watchlist":
-			where = "scenes.watchlist = 1"
+			where = userSceneWhere(userID, "us.watchlist = 1")
 		case "Is Scripted":
 			where = "is_scripted = 1"
 		case "Is Favourite":
-			where = "scenes.favourite = 1"
+			where = userSceneWhere(userID, "us.favourite = 1")
 		case "Missing":
 			where = "scenes.is_accessible = 0"
 		case "Is Passthrough":
@@ -927,7 +975,7 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 		case "Is Alpha Passthrough":
 			where = `((chroma_key <> '' and chroma_key like '%"hasAlpha":true%') or ` + "exists (select 1 from files where files.scene_id = scenes.id and files.`type` = 'video' and files.has_alpha = true))"
 		case "In Wishlist":
-			where = "wishlist = 1"
+			where = userSceneWhere(userID, "us.wishlist = 1")
 		case "Stashdb Linked":
 			where = "exists (select 1 from external_reference_links erl where erl.internal_db_id = scenes.id and erl.external_source = 'stashdb scene')"
 		case "POVR Scraper":
@@ -964,9 +1012,9 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 				where = "scenes.human_script = 1"
 			}
 		case "Has Favourite Actor":
-			where = "exists (select * from scene_cast join actors on actors.id=scene_cast.actor_id where actors.favourite=1 and scene_cast.scene_id=scenes.id)"
+			where = fmt.Sprintf("exists (select * from scene_cast join user_actors ua on ua.actor_id=scene_cast.actor_id and ua.user_id=%d where ua.favourite=1 and scene_cast.scene_id=scenes.id)", userID)
 		case "Has Actor in Watchlist":
-			where = "exists (select * from scene_cast join actors on actors.id=scene_cast.actor_id where actors.watchlist=1 and scene_cast.scene_id=scenes.id)"
+			where = fmt.Sprintf("exists (select * from scene_cast join user_actors ua on ua.actor_id=scene_cast.actor_id and ua.user_id=%d where ua.watchlist=1 and scene_cast.scene_id=scenes.id)", userID)
 		case "Available from POVR":
 			where = "exists (select 1 from external_reference_links where external_source like 'alternate scene %' and external_id like 'povr-%' and internal_db_id = scenes.id)"
 		case "Available from VRPorn":
@@ -1167,17 +1215,17 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 	case "total_file_size_asc":
 		tx = tx.Order("total_file_size asc")
 	case "total_watch_time_desc":
-		tx = tx.Order("total_watch_time desc")
+		tx = tx.Order(userSceneColumn(userID, "total_watch_time") + " desc")
 	case "total_watch_time_asc":
-		tx = tx.Order("total_watch_time asc")
+		tx = tx.Order(userSceneColumn(userID, "total_watch_time") + " asc")
 	case "rating_desc":
 		tx = tx.
-			Where("scenes.star_rating > 0").
-			Order("scenes.star_rating desc")
+			Where(userSceneWhere(userID, "us.star_rating > 0")).
+			Order(userSceneColumn(userID, "star_rating") + " desc")
 	case "rating_asc":
 		tx = tx.
-			Where("scenes.star_rating > 0").
-			Order("scenes.star_rating asc")
+			Where(userSceneWhere(userID, "us.star_rating > 0")).
+			Order(userSceneColumn(userID, "star_rating") + " asc")
 	case "last_opened_desc":
 		tx = tx.
 			Where("last_opened > '0001-01-01 00:00:00+00:00'").
@@ -1186,6 +1234,10 @@ func queryScenes(db *gorm.DB, r RequestSceneList) (*gorm.DB, *gorm.DB) {
 		tx = tx.
 			Where("last_opened > '0001-01-01 00:00:00+00:00'").
 			Order("last_opened asc")
+	case "last_played_desc":
+		tx = tx.
+			Where("exists (select 1 from playback_positions pp where pp.scene_id = scenes.id and pp.user_id = ?)", userID).
+			Order(lastPlayedColumn(userID) + " desc")
 	case "scene_added_desc":
 		tx = tx.Order("created_at desc")
 	case "scene_updated_desc":
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SceneBulkOperation is one change applied to every scene of a bulk edit:
+//   - add_tag, remove_tag, add_cast, remove_cast take a name
+//   - set_site, set_studio take the new value
+//   - set_hidden and the profile state set_watched, set_favourite, set_watchlist, set_wishlist take true or false
+//
+// Tag, cast, site and studio changes are recorded as Actions, as edits of a single scene are.
+type SceneBulkOperation struct {
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+func (o SceneBulkOperation) Validate() error {
+	switch o.Op {
+	case "add_tag", "remove_tag", "add_cast", "remove_cast", "set_site", "set_studio":
+		if strings.TrimSpace(o.Value) == "" {
+			return fmt.Errorf("%v needs a value", o.Op)
+		}
+	case "set_hidden", "set_watched", "set_favourite", "set_watchlist", "set_wishlist":
+		if _, err := strconv.ParseBool(o.Value); err != nil {
+			return fmt.Errorf("%v needs true or false", o.Op)
+		}
+	default:
+		return fmt.Errorf("unknown operation %v", o.Op)
+	}
+	return nil
+}
+
+// BulkEditScenes applies the operations to the scenes in a single transaction, nothing is changed if
+// one of them fails. The profile state operations apply to the given profile.
+func BulkEditScenes(sceneIDs []uint, operations []SceneBulkOperation, userID uint) error {
+	for _, op := range operations {
+		if err := op.Validate(); err != nil {
+			return err
+		}
+	}
+
+	db, _ := GetDB()
+	defer db.Close()
+
+	tx := db.Begin()
+	// keep clear of the variable limit of sqlite
+	for start := 0; start < len(sceneIDs); start += 500 {
+		end := start + 500
+		if end > len(sceneIDs) {
+			end = len(sceneIDs)
+		}
+
+		var scenes []Scene
+		if err := tx.Preload("Tags").Preload("Cast").Where("id in (?)", sceneIDs[start:end]).Find(&scenes).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		for i := range scenes {
+			for _, op := range operations {
+				if err := bulkEditScene(tx, &scenes[i], op, userID); err != nil {
+					tx.Rollback()
+					return fmt.Errorf("scene %v: %v", scenes[i].SceneID, err)
+				}
+			}
+		}
+	}
+	return tx.Commit().Error
+}
+
+func bulkEditScene(tx *gorm.DB, scene *Scene, op SceneBulkOperation, userID uint) error {
+	addAction := func(column string, value string) error {
+		return tx.Create(&Action{SceneID: scene.SceneID, ActionType: "edit", ChangedColumn: column, NewValue: value}).Error
+	}
+
+	switch op.Op {
+	case "add_tag":
+		for _, name := range ConvertTag(op.Value) {
+			if sceneHasTag(scene, name) {
+				continue
+			}
+			var tag Tag
+			if err := tx.Where(&Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(scene).Association("Tags").Append(&tag).Error; err != nil {
+				return err
+			}
+			if err := addAction("tags", "+"+name); err != nil {
+				return err
+			}
+		}
+	case "remove_tag":
+		// the association keeps scene.Tags up to date, so go through a copy
+		for _, tag := range append([]Tag{}, scene.Tags...) {
+			if !strings.EqualFold(tag.Name, strings.TrimSpace(op.Value)) {
+				continue
+			}
+			tag := tag
+			if err := tx.Model(scene).Association("Tags").Delete(&tag).Error; err != nil {
+				return err
+			}
+			if err := addAction("tags", "-"+tag.Name); err != nil {
+				return err
+			}
+		}
+	case "add_cast":
+		name := strings.Replace(strings.TrimSpace(op.Value), ".", "", -1)
+		for _, actor := range scene.Cast {
+			if actor.Name == name {
+				return nil
+			}
+		}
+		var actor Actor
+		if err := tx.Where(&Actor{Name: name}).FirstOrCreate(&actor).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(scene).Association("Cast").Append(&actor).Error; err != nil {
+			return err
+		}
+		return addAction("cast", "+"+name)
+	case "remove_cast":
+		for _, actor := range scene.Cast {
+			if actor.Name == strings.TrimSpace(op.Value) {
+				if err := tx.Model(scene).Association("Cast").Delete(&actor).Error; err != nil {
+					return err
+				}
+				return addAction("cast", "-"+actor.Name)
+			}
+		}
+	case "set_site", "set_studio":
+		column := strings.TrimPrefix(op.Op, "set_")
+		if (column == "site" && scene.Site == op.Value) || (column == "studio" && scene.Studio == op.Value) {
+			return nil
+		}
+		if err := tx.Model(scene).UpdateColumn(column, op.Value).Error; err != nil {
+			return err
+		}
+		return addAction(column, op.Value)
+	case "set_hidden":
+		value, _ := strconv.ParseBool(op.Value)
+		return tx.Model(scene).UpdateColumn("is_hidden", value).Error
+	default:
+		value, _ := strconv.ParseBool(op.Value)
+		s := UserScene{UserID: userID, SceneID: scene.ID}
+		if err := tx.Where("user_id = ? and scene_id = ?", userID, scene.ID).FirstOrInit(&s).Error; err != nil {
+			return err
+		}
+		switch op.Op {
+		case "set_watched":
+			s.IsWatched = value
+		case "set_favourite":
+			s.Favourite = value
+		case "set_watchlist":
+			s.Watchlist = value
+		case "set_wishlist":
+			// as with toggling it, only scenes that are not available can be wished for
+			s.Wishlist = value && !scene.IsAvailable
+		}
+		return tx.Save(&s).Error
+	}
+	return nil
+}
+
+func sceneHasTag(scene *Scene, name string) bool {
+	for _, tag := range scene.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetScenesByIDs loads scenes with their tags and cast
+func GetScenesByIDs(sceneIDs []uint) []Scene {
+	commonDb, _ := GetCommonDB()
+
+	var out []Scene
+	for start := 0; start < len(sceneIDs); start += 500 {
+		end := start + 500
+		if end > len(sceneIDs) {
+			end = len(sceneIDs)
+		}
+		var scenes []Scene
+		commonDb.Preload("Tags").Preload("Cast").Where("id in (?)", sceneIDs[start:end]).Find(&scenes)
+		out = append(out, scenes...)
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/thoas/go-funk"
+)
+
+// SceneChange records a scene field a scraper changed, with the value it had before, so the change
+// can be reviewed and reverted. Field is the column name user edits use in Action, tags and cast are
+// stored as json lists of names.
+type SceneChange struct {
+	ID         uint       `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SceneID    string     `gorm:"index" json:"scene_id"`
+	Field      string     `json:"field"`
+	OldValue   string     `gorm:"type:text" json:"old_value"`
+	NewValue   string     `gorm:"type:text" json:"new_value"`
+	Source     string     `json:"source"`
+	RevertedAt *time.Time `json:"reverted_at"`
+}
+
+var sceneChangeFields = []struct {
+	name  string
+	value func(Scene) string
+}{
+	{"title", func(s Scene) string { return s.Title }},
+	{"synopsis", func(s Scene) string { return s.Synopsis }},
+	{"studio", func(s Scene) string { return s.Studio }},
+	{"site", func(s Scene) string { return s.Site }},
+	{"scene_url", func(s Scene) string { return s.SceneURL }},
+	{"release_date_text", func(s Scene) string { return s.ReleaseDateText }},
+	{"duration", func(s Scene) string { return strconv.Itoa(s.Duration) }},
+	{"cover_url", func(s Scene) string { return s.CoverURL }},
+	{"images", func(s Scene) string { return s.Images }},
+	{"filenames_arr", func(s Scene) string { return s.FilenamesArr }},
+}
+
+func (o *SceneChange) GetIfExistByPK(id uint) error {
+	commonDb, _ := GetCommonDB()
+
+	return commonDb.Where(&SceneChange{ID: id}).First(o).Error
+}
+
+// GetSceneChanges returns the timeline of a scene, newest first
+func GetSceneChanges(sceneID string) []SceneChange {
+	commonDb, _ := GetCommonDB()
+
+	var changes []SceneChange
+	commonDb.Where(&SceneChange{SceneID: sceneID}).Order("created_at desc, id desc").Find(&changes)
+	return changes
+}
+
+// recordSceneChanges logs the fields a scraper changed on an existing scene. Fields with user edits
+// are left out, ReapplyEdits puts the edit back, as are the tag group and aka entries xbvr maintains.
+func recordSceneChanges(db *gorm.DB, before Scene, after Scene, source string) {
+	if before.ID == 0 {
+		return
+	}
+
+	var actions []Action
+	db.Where(&Action{SceneID: before.SceneID}).Find(&actions)
+	editedFields := map[string]bool{}
+	editedNames := map[string]bool{}
+	for _, a := range actions {
+		editedFields[a.ChangedColumn] = true
+		if (a.ChangedColumn == "tags" || a.ChangedColumn == "cast") && len(a.NewValue) > 1 {
+			editedNames[a.ChangedColumn+":"+strings.ToLower(a.NewValue[1:])] = true
+		}
+	}
+
+	add := func(field string, oldValue string, newValue string) {
+		change := SceneChange{SceneID: before.SceneID, Field: field, OldValue: oldValue, NewValue: newValue, Source: source}
+		db.Create(&change)
+	}
+
+	for _, f := range sceneChangeFields {
+		if editedFields[f.name] || after.IsColumnLocked(f.name) {
+			continue
+		}
+		if oldValue, newValue := f.value(before), f.value(after); oldValue != newValue {
+			add(f.name, oldValue, newValue)
+		}
+	}
+
+	names := func(field string, list []string) []string {
+		out := []string{}
+		for _, name := range list {
+			if strings.HasPrefix(name, "tag group:") || strings.HasPrefix(name, "aka:") || editedNames[field+":"+strings.ToLower(name)] {
+				continue
+			}
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	compareLists := func(field string, oldList []string, newList []string) {
+		if after.IsColumnLocked(field) {
+			return
+		}
+		oldNames, newNames := names(field, oldList), names(field, newList)
+		if strings.Join(oldNames, "\n") != strings.Join(newNames, "\n") {
+			oldValue, _ := json.Marshal(oldNames)
+			newValue, _ := json.Marshal(newNames)
+			add(field, string(oldValue), string(newValue))
+		}
+	}
+	compareLists("tags", tagNames(before.Tags), tagNames(after.Tags))
+	compareLists("cast", actorNames(before.Cast), actorNames(after.Cast))
+}
+
+func tagNames(tags []Tag) []string {
+	var out []string
+	for _, t := range tags {
+		out = append(out, t.Name)
+	}
+	return out
+}
+
+func actorNames(actors []Actor) []string {
+	var out []string
+	for _, a := range actors {
+		out = append(out, a.Name)
+	}
+	return out
+}
+
+// Revert puts back the value the field had before the change. The revert is stored as a user edit,
+// so ReapplyEdits keeps it after the next scrape. For tags and cast only the names this change
+// added or removed are reverted.
+func (o *SceneChange) Revert() error {
+	if o.RevertedAt != nil {
+		return errors.New("change has already been reverted")
+	}
+
+	var scene Scene
+	if err := scene.GetIfExist(o.SceneID); err != nil {
+		return err
+	}
+
+	commonDb, _ := GetCommonDB()
+	switch o.Field {
+	case "tags", "cast":
+		var oldNames, newNames []string
+		json.Unmarshal([]byte(o.OldValue), &oldNames)
+		json.Unmarshal([]byte(o.NewValue), &newNames)
+		for _, name := range newNames {
+			if !funk.ContainsString(oldNames, name) {
+				o.revertListItem(commonDb, &scene, "-", name)
+			}
+		}
+		for _, name := range oldNames {
+			if !funk.ContainsString(newNames, name) {
+				o.revertListItem(commonDb, &scene, "+", name)
+			}
+		}
+	case "duration":
+		duration, _ := strconv.Atoi(o.OldValue)
+		commonDb.Model(&scene).Update("duration", duration)
+		AddAction(scene.SceneID, "edit", o.Field, o.OldValue)
+	case "release_date_text":
+		releaseDate, _ := time.Parse("2006-01-02", o.OldValue)
+		commonDb.Model(&scene).Updates(map[string]interface{}{"release_date_text": o.OldValue, "release_date": releaseDate})
+		AddAction(scene.SceneID, "edit", o.Field, o.OldValue)
+	default:
+		commonDb.Model(&scene).Update(o.Field, o.OldValue)
+		AddAction(scene.SceneID, "edit", o.Field, o.OldValue)
+	}
+
+	// a reverted field is kept as it is now, like a field edited by hand
+	if o.Field != "tags" && o.Field != "cast" && !scene.IsColumnLocked(o.Field) {
+		scene.LockColumn(o.Field)
+		commonDb.Model(&scene).UpdateColumn("locked_fields", scene.LockedFields)
+	}
+
+	now := time.Now()
+	o.RevertedAt = &now
+	return SaveWithRetry(commonDb, o)
+}
+
+func (o *SceneChange) revertListItem(db *gorm.DB, scene *Scene, prefix string, name string) {
+	if o.Field == "tags" {
+		var tag Tag
+		db.Where(&Tag{Name: name}).FirstOrCreate(&tag)
+		if prefix == "-" {
+			db.Model(scene).Association("Tags").Delete(&tag)
+		} else {
+			db.Model(scene).Association("Tags").Append(&tag)
+		}
+	} else {
+		var actor Actor
+		db.Where(&Actor{Name: name}).FirstOrCreate(&actor)
+		if prefix == "-" {
+			db.Model(scene).Association("Cast").Delete(&actor)
+		} else {
+			db.Model(scene).Association("Cast").Append(&actor)
+		}
+	}
+	AddAction(scene.SceneID, "edit", o.Field, prefix+name)
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/thoas/go-funk"
+)
+
+// LockableSceneFields are the scene fields a user can lock, scrapers leave locked fields as they are
+var LockableSceneFields = []string{"title", "synopsis", "cast", "tags", "release_date", "cover", "duration"}
+
+// lock guarding each scene column scrapers write, by the column name used in Action and SceneChange
+var sceneColumnLocks = map[string]string{
+	"title":             "title",
+	"synopsis":          "synopsis",
+	"cast":              "cast",
+	"tags":              "tags",
+	"release_date_text": "release_date",
+	"cover_url":         "cover",
+	"duration":          "duration",
+}
+
+func ValidateSceneLocks(fields []string) error {
+	for _, field := range fields {
+		if !funk.ContainsString(LockableSceneFields, field) {
+			return fmt.Errorf("field %v can not be locked", field)
+		}
+	}
+	return nil
+}
+
+func (o *Scene) GetLockedFields() []string {
+	var fields []string
+	if o.LockedFields != "" {
+		json.Unmarshal([]byte(o.LockedFields), &fields)
+	}
+	return fields
+}
+
+func (o *Scene) IsFieldLocked(field string) bool {
+	return funk.ContainsString(o.GetLockedFields(), field)
+}
+
+// IsColumnLocked tells whether the lock guarding a column, if any, is set
+func (o *Scene) IsColumnLocked(column string) bool {
+	lock, ok := sceneColumnLocks[column]
+	return ok && o.IsFieldLocked(lock)
+}
+
+// SetFieldLocks locks or unlocks fields, the scene still needs saving
+func (o *Scene) SetFieldLocks(fields []string, locked bool) {
+	current := o.GetLockedFields()
+	for _, field := range fields {
+		if locked && !funk.ContainsString(current, field) {
+			current = append(current, field)
+		}
+		if !locked {
+			current = funk.SubtractString(current, []string{field})
+		}
+	}
+	sort.Strings(current)
+
+	o.LockedFields = ""
+	if len(current) > 0 {
+		b, _ := json.Marshal(current)
+		o.LockedFields = string(b)
+	}
+}
+
+// LockColumn sets the lock guarding a column, if there is one
+func (o *Scene) LockColumn(column string) {
+	if lock, ok := sceneColumnLocks[column]; ok {
+		o.SetFieldLocks([]string{lock}, true)
+	}
+}
+
+// SetSceneFieldLocks locks or unlocks fields of several scenes at once
+func SetSceneFieldLocks(sceneIDs []uint, fields []string, locked bool) {
+	commonDb, _ := GetCommonDB()
+
+	for _, id := range sceneIDs {
+		var scene Scene
+		if commonDb.Where("id = ?", id).First(&scene).Error != nil {
+			continue
+		}
+		scene.SetFieldLocks(fields, locked)
+		commonDb.Model(&scene).UpdateColumn("locked_fields", scene.LockedFields)
+	}
+}
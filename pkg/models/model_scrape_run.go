@@ -0,0 +1,199 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScrapeRun records one run of a site scraper, so a scraper that silently stops producing scenes
+// can be told apart from a site that simply published nothing new.
+//
+// Scrapers skip scenes that are already known, so ScenesFound is usually small on incremental
+// runs; Pages, the number of pages fetched successfully, is the steadier measure of a site's output.
+type ScrapeRun struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	SiteID     string    `gorm:"index" json:"site_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	Pages         int    `json:"pages"`
+	ScenesFound   int    `json:"scenes_found"`
+	ScenesNew     int    `json:"scenes_new"`
+	ScenesUpdated int    `json:"scenes_updated"`
+	HttpErrors    string `gorm:"type:text" json:"-"`
+	Error         string `gorm:"type:text" json:"error"`
+
+	// scenes of this run missing a field
+	MissingCover    int `json:"missing_cover"`
+	MissingCast     int `json:"missing_cast"`
+	MissingTitle    int `json:"missing_title"`
+	MissingReleased int `json:"missing_released"`
+	MissingTags     int `json:"missing_tags"`
+	MissingSynopsis int `json:"missing_synopsis"`
+	MissingDuration int `json:"missing_duration"`
+
+	Flagged    bool   `json:"flagged"`
+	FlagReason string `gorm:"type:text" json:"flag_reason"`
+
+	HttpErrorCounts map[string]int `gorm:"-" json:"http_errors"`
+}
+
+// number of earlier runs a run is compared with
+const scrapeRunHistory = 10
+
+func (o *ScrapeRun) Save() error {
+	commonDb, _ := GetCommonDB()
+
+	if len(o.HttpErrorCounts) > 0 {
+		b, _ := json.Marshal(o.HttpErrorCounts)
+		o.HttpErrors = string(b)
+	}
+	return SaveWithRetry(commonDb, o)
+}
+
+func (o *ScrapeRun) decode() {
+	o.HttpErrorCounts = map[string]int{}
+	if o.HttpErrors != "" {
+		json.Unmarshal([]byte(o.HttpErrors), &o.HttpErrorCounts)
+	}
+}
+
+// AddScene counts a scraped scene and the fields it is missing
+func (o *ScrapeRun) AddScene(scene ScrapedScene, isNew bool) {
+	o.ScenesFound++
+	if isNew {
+		o.ScenesNew++
+	} else {
+		o.ScenesUpdated++
+	}
+	if len(scene.Covers) == 0 {
+		o.MissingCover++
+	}
+	if len(scene.Cast) == 0 {
+		o.MissingCast++
+	}
+	if scene.Title == "" {
+		o.MissingTitle++
+	}
+	if scene.Released == "" {
+		o.MissingReleased++
+	}
+	if len(scene.Tags) == 0 {
+		o.MissingTags++
+	}
+	if scene.Synopsis == "" {
+		o.MissingSynopsis++
+	}
+	if scene.Duration == 0 {
+		o.MissingDuration++
+	}
+}
+
+func (o *ScrapeRun) ErrorCount() int {
+	count := 0
+	for _, c := range o.HttpErrorCounts {
+		count += c
+	}
+	return count
+}
+
+// CheckHealth flags the run when it looks broken on its own, or its output dropped sharply compared
+// with the earlier runs of the site
+func (o *ScrapeRun) CheckHealth(history []ScrapeRun) {
+	var reasons []string
+
+	if o.Error != "" {
+		reasons = append(reasons, "scraper failed: "+o.Error)
+	}
+	if errors := o.ErrorCount(); errors > 0 && errors >= o.Pages {
+		reasons = append(reasons, fmt.Sprintf("%v failed requests against %v pages fetched", errors, o.Pages))
+	}
+
+	var pages, found []int
+	var historyFound, historyCover, historyCast, historyTitle int
+	for _, run := range history {
+		if run.Error != "" {
+			continue
+		}
+		pages = append(pages, run.Pages)
+		found = append(found, run.ScenesFound)
+		historyFound += run.ScenesFound
+		historyCover += run.MissingCover
+		historyCast += run.MissingCast
+		historyTitle += run.MissingTitle
+	}
+
+	if len(pages) >= 3 {
+		if median := medianInt(pages); median > 0 && o.Pages == 0 {
+			reasons = append(reasons, "no pages fetched")
+		} else if median >= 4 && o.Pages*2 < median {
+			reasons = append(reasons, fmt.Sprintf("%v pages fetched, usually %v", o.Pages, median))
+		}
+		// incremental runs find few scenes, only compare sites that consistently bring in many
+		if median := medianInt(found); median >= 4 && o.ScenesFound*4 < median {
+			reasons = append(reasons, fmt.Sprintf("%v scenes found, usually %v", o.ScenesFound, median))
+		}
+	}
+
+	if o.ScenesFound >= 4 {
+		checkMissing := func(field string, missing int, historyMissing int) {
+			if missing*2 < o.ScenesFound {
+				return
+			}
+			if historyFound == 0 || historyMissing*4 < historyFound {
+				reasons = append(reasons, fmt.Sprintf("%v%% of scenes missing %v", missing*100/o.ScenesFound, field))
+			}
+		}
+		checkMissing("cover", o.MissingCover, historyCover)
+		checkMissing("cast", o.MissingCast, historyCast)
+		checkMissing("title", o.MissingTitle, historyTitle)
+	}
+
+	o.Flagged = len(reasons) > 0
+	o.FlagReason = strings.Join(reasons, ", ")
+}
+
+func medianInt(values []int) int {
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// GetScrapeRuns returns the latest runs of a site, newest first
+func GetScrapeRuns(siteID string, limit int) []ScrapeRun {
+	commonDb, _ := GetCommonDB()
+
+	var runs []ScrapeRun
+	commonDb.Where(&ScrapeRun{SiteID: siteID}).Order("started_at desc").Limit(limit).Find(&runs)
+	for i := range runs {
+		runs[i].decode()
+	}
+	return runs
+}
+
+// GetLatestScrapeRuns returns the most recent run of every site that has been scraped
+func GetLatestScrapeRuns() map[string]ScrapeRun {
+	commonDb, _ := GetCommonDB()
+
+	var runs []ScrapeRun
+	commonDb.Where("id in (select max(id) from scrape_runs group by site_id)").Find(&runs)
+
+	out := map[string]ScrapeRun{}
+	for _, run := range runs {
+		run.decode()
+		out[run.SiteID] = run
+	}
+	return out
+}
+
+// FinishScrapeRun checks the run against the history of its site and saves it
+func FinishScrapeRun(run *ScrapeRun) {
+	run.CheckHealth(GetScrapeRuns(run.SiteID, scrapeRunHistory))
+	if run.Flagged {
+		log.Warnf("Scraper %v may be broken: %v", run.SiteID, run.FlagReason)
+	}
+	run.Save()
+}
@@ -70,6 +70,25 @@ type TrailerScrape struct {
 	KVHttpConfig   string `json:"kv_http_config"`
 }
 
+// GenericSceneScraperConfig describes a scene scraper for a site without any Go code. List pages
+// are walked from ListURL following NextPageSelector, every link matching SceneLinkSelector is
+// scraped with Rules. With IsJson the pages are json and selectors are gjson paths.
+type GenericSceneScraperConfig struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	AvatarURL    string `json:"avatar_url"`
+	Domain       string `json:"domain"`
+	Studio       string `json:"studio"`
+	SceneType    string `json:"scene_type"` // VR or 2D, VR if empty
+	MasterSiteId string `json:"master_site_id"`
+	IsJson       bool   `json:"is_json"`
+
+	ListURL           string                    `json:"list_url"`
+	NextPageSelector  string                    `json:"next_page_selector"`  // link to the next list page, the href is followed
+	SceneLinkSelector string                    `json:"scene_link_selector"` // links to the scene pages on a list page
+	Rules             []GenericActorScraperRule `json:"rules"`               // xbvr_field is a field of ScrapedScene, eg title, tags or duration
+}
+
 func (s *ScrapedScene) ToJSON() ([]byte, error) {
 	return json.Marshal(s)
 }
@@ -21,6 +21,7 @@ type Site struct {
 	MatchingParams string    `json:"matching_params" gorm:"size:1000" xbvrbackup:"matching_params"`
 	ScrapeStash    bool      `json:"scrape_stash" xbvrbackup:"scrape_stash"`
 	SceneCount     int       `gorm:"-" json:"scene_count" xbvrbackup:"-"`
+	HealthWarning  string    `gorm:"-" json:"health_warning" xbvrbackup:"-"`
 }
 
 func (i *Site) Save() error {
@@ -0,0 +1,329 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagRule normalizes the tags scrapers bring in. Rules are tried in order and the first one
+// matching a tag decides what becomes of it:
+//   - exact matches a single tag name
+//   - synonym matches any of a comma separated list of tag names
+//   - regex matches the whole tag against a regular expression, Replace may refer to its groups as $1
+//
+// An empty Replace drops the tag, a comma separated Replace splits it into several tags. Tags are
+// matched lowercased and trimmed.
+type TagRule struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Ordering  int    `json:"ordering"`
+	IsEnabled bool   `json:"is_enabled"`
+	Kind      string `json:"kind"`
+	Match     string `gorm:"type:text" json:"match"`
+	Replace   string `json:"replace"`
+}
+
+type compiledTagRule struct {
+	TagRule
+	names   map[string]bool
+	re      *regexp.Regexp
+	replace []string
+}
+
+var tagRuleCache struct {
+	sync.Mutex
+	rules  []compiledTagRule
+	loaded bool
+}
+
+func (o *TagRule) GetIfExistByPK(id uint) error {
+	commonDb, _ := GetCommonDB()
+
+	return commonDb.Where(&TagRule{ID: id}).First(o).Error
+}
+
+func (o *TagRule) Save() error {
+	commonDb, _ := GetCommonDB()
+
+	err := SaveWithRetry(commonDb, o)
+	InvalidateTagRules()
+	return err
+}
+
+func (o *TagRule) Delete() error {
+	commonDb, _ := GetCommonDB()
+
+	err := commonDb.Delete(o).Error
+	InvalidateTagRules()
+	return err
+}
+
+// Validate checks the rule can be compiled
+func (o *TagRule) Validate() error {
+	_, err := o.compile()
+	return err
+}
+
+func (o TagRule) compile() (compiledTagRule, error) {
+	c := compiledTagRule{TagRule: o, replace: splitTagList(o.Replace)}
+	switch o.Kind {
+	case "exact", "synonym":
+		c.names = map[string]bool{}
+		for _, name := range splitTagList(o.Match) {
+			c.names[name] = true
+		}
+		if len(c.names) == 0 {
+			return c, fmt.Errorf("rule needs a tag to match")
+		}
+		if o.Kind == "exact" && len(c.names) > 1 {
+			return c, fmt.Errorf("exact rules match a single tag, use a synonym rule for several")
+		}
+	case "regex":
+		if strings.TrimSpace(o.Match) == "" {
+			return c, fmt.Errorf("rule needs a regular expression to match")
+		}
+		re, err := regexp.Compile(`^(?:` + strings.TrimSpace(o.Match) + `)$`)
+		if err != nil {
+			return c, fmt.Errorf("invalid regular expression: %v", err)
+		}
+		c.re = re
+	default:
+		return c, fmt.Errorf("unknown rule kind %q", o.Kind)
+	}
+	return c, nil
+}
+
+// apply returns the tags replacing t, ok is false if the rule doesn't match
+func (o compiledTagRule) apply(t string) (tags []string, ok bool) {
+	if o.re == nil {
+		if !o.names[t] {
+			return nil, false
+		}
+		return o.replace, true
+	}
+	if !o.re.MatchString(t) {
+		return nil, false
+	}
+	return splitTagList(o.re.ReplaceAllString(t, o.Replace)), true
+}
+
+func splitTagList(s string) []string {
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func GetTagRules() []TagRule {
+	commonDb, _ := GetCommonDB()
+
+	var rules []TagRule
+	commonDb.Order("ordering").Order("id").Find(&rules)
+	return rules
+}
+
+// InvalidateTagRules makes the next conversion pick up changed rules
+func InvalidateTagRules() {
+	tagRuleCache.Lock()
+	tagRuleCache.loaded = false
+	tagRuleCache.Unlock()
+}
+
+func loadTagRules() []compiledTagRule {
+	tagRuleCache.Lock()
+	defer tagRuleCache.Unlock()
+
+	if !tagRuleCache.loaded {
+		tagRuleCache.rules = compileTagRules(GetTagRules())
+		tagRuleCache.loaded = true
+	}
+	return tagRuleCache.rules
+}
+
+func compileTagRules(rules []TagRule) []compiledTagRule {
+	var out []compiledTagRule
+	for _, rule := range rules {
+		if !rule.IsEnabled {
+			continue
+		}
+		c, err := rule.compile()
+		if err != nil {
+			log.Warnf("Skipping tag rule %v: %v", rule.ID, err)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// ConvertTag returns the tags a scraped tag becomes under the tag rules, none if it is dropped
+func ConvertTag(t string) []string {
+	return convertTag(loadTagRules(), t)
+}
+
+func convertTag(rules []compiledTagRule, t string) []string {
+	t = strings.TrimSpace(strings.ToLower(t))
+	if t == "" {
+		return nil
+	}
+	for _, rule := range rules {
+		if tags, ok := rule.apply(t); ok {
+			return tags
+		}
+	}
+	return []string{t}
+}
+
+// ConvertTags converts a list of tags, leaving out duplicates
+func ConvertTags(names []string) []string {
+	rules := loadTagRules()
+
+	var out []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, t := range convertTag(rules, name) {
+			if !seen[t] {
+				seen[t] = true
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
+
+// TagRulePreview lists the existing tags a rule changes, and the number of scenes carrying them
+type TagRulePreview struct {
+	RuleID uint             `json:"rule_id"`
+	Scenes int              `json:"scenes"`
+	Tags   []TagRuleChanged `json:"tags"`
+}
+
+type TagRuleChanged struct {
+	Name   string   `json:"name"`
+	Result []string `json:"result"`
+	Scenes int      `json:"scenes"`
+}
+
+// PreviewTagRules works out what the rules would do to the tags of the library. A tag only counts
+// for the first rule matching it, as later rules never get to see it.
+func PreviewTagRules(rules []TagRule) []TagRulePreview {
+	commonDb, _ := GetCommonDB()
+
+	type tagCount struct {
+		Name string
+		Cnt  int
+	}
+	var tags []tagCount
+	commonDb.Model(&Tag{}).
+		Select("tags.name, count(distinct scenes.id) as cnt").
+		Joins("join scene_tags on scene_tags.tag_id = tags.id").
+		Joins("join scenes on scenes.id = scene_tags.scene_id and scenes.deleted_at is null").
+		Where("tags.name not like ?", "tag group:%").
+		Group("tags.name").
+		Scan(&tags)
+
+	out := make([]TagRulePreview, 0, len(rules))
+	byRule := map[uint]int{}
+	for _, rule := range rules {
+		byRule[rule.ID] = len(out)
+		out = append(out, TagRulePreview{RuleID: rule.ID, Tags: []TagRuleChanged{}})
+	}
+
+	compiled := compileTagRules(rules)
+	for _, tag := range tags {
+		for _, rule := range compiled {
+			result, ok := rule.apply(tag.Name)
+			if !ok {
+				continue
+			}
+			if len(result) != 1 || result[0] != tag.Name {
+				p := &out[byRule[rule.ID]]
+				p.Tags = append(p.Tags, TagRuleChanged{Name: tag.Name, Result: result, Scenes: tag.Cnt})
+				p.Scenes += tag.Cnt
+			}
+			break
+		}
+	}
+	for i := range out {
+		sort.Slice(out[i].Tags, func(a, b int) bool { return out[i].Tags[a].Scenes > out[i].Tags[b].Scenes })
+	}
+	return out
+}
+
+// DefaultTagRules is the rule set a new install starts with
+var DefaultTagRules = []TagRule{
+	{Kind: "synonym", Match: "180, 60fps, 60 fps, 5k, 5k+, big dick, big cocks, axaxqxrrysrwqua, girl-boy, virtual reality, sex, new, virtual reality porn, vr porn, 8k-vr-porn, 7k-vr-porn, 6k-vr-porn, 5k-vr-porn, 4k-vr-porn, 180 vr porn, xxxsex vr, xxx vr porn, vrconk, sex onbed, pornstars, vr, vrp, bg, coming soon, vr 1080p porn", Replace: ""},
+	{Kind: "exact", Match: "sixty-nine", Replace: "69"},
+	{Kind: "exact", Match: "anal", Replace: "anal sex"},
+	{Kind: "exact", Match: "butt plug", Replace: "anal toys"},
+	{Kind: "synonym", Match: "cum in ass, creampie - ass", Replace: "anal creampie"},
+	{Kind: "exact", Match: "athletic", Replace: "athletic body"},
+	{Kind: "synonym", Match: "threesome bgg, bgg, girl-girl-boy, ffm threesome", Replace: "threesome ffm"},
+	{Kind: "synonym", Match: "threesome bbg, bbg, mmf", Replace: "threesome fmm"},
+	{Kind: "synonym", Match: "busty, big boobs, big tits porn, big-tits", Replace: "big tits"},
+	{Kind: "synonym", Match: "blow job, blowjobs", Replace: "blowjob"},
+	{Kind: "synonym", Match: "boobs job, titty fucking, tittyfuck, titjob", Replace: "titty fuck"},
+	{Kind: "exact", Match: "catsuite", Replace: "catsuit"},
+	{Kind: "exact", Match: "cum swapping", Replace: "cum swap"},
+	{Kind: "synonym", Match: "cum shot, cum-shot", Replace: "cumshot"},
+	{Kind: "exact", Match: "curvy woman", Replace: "curvy"},
+	{Kind: "exact", Match: "cowgirl reverse", Replace: "reverse cowgirl"},
+	{Kind: "synonym", Match: "deepthroat, deepthroating", Replace: "deep throat"},
+	{Kind: "exact", Match: "dominating", Replace: "dominant"},
+	{Kind: "exact", Match: "double penetration", Replace: "dp"},
+	{Kind: "synonym", Match: "doggy, doggy style", Replace: "doggystyle"},
+	{Kind: "synonym", Match: "face cumshot, facial cumshot, facial", Replace: "cum on face"},
+	{Kind: "exact", Match: "girlfrien", Replace: "girlfriend"},
+	{Kind: "synonym", Match: "hand job, hand jobs, handjobs", Replace: "handjob"},
+	{Kind: "synonym", Match: "latin, latin babe", Replace: "latina"},
+	{Kind: "synonym", Match: "lesbian love, lesbians, girlgirl, girl-on-girl", Replace: "lesbian"},
+	{Kind: "synonym", Match: "milfs, cougar, mother, mom, british mom", Replace: "milf"},
+	{Kind: "exact", Match: "european", Replace: "euro"},
+	{Kind: "exact", Match: "red head", Replace: "redhead"},
+	{Kind: "exact", Match: "role playing", Replace: "role play"},
+	{Kind: "exact", Match: "shaved", Replace: "shaved pussy"},
+	{Kind: "exact", Match: "squirt", Replace: "squirting"},
+	{Kind: "synonym", Match: "teens, 18", Replace: "teen"},
+	{Kind: "exact", Match: "trimmed", Replace: "trimmed pussy"},
+	{Kind: "exact", Match: "voayer", Replace: "voyeur"},
+	{Kind: "synonym", Match: "small boobs, small natural tits, small-tits", Replace: "small tits"},
+	{Kind: "synonym", Match: "natural boobs, natural-tits", Replace: "natural tits"},
+	{Kind: "exact", Match: "medium boobs", Replace: "medium tits"},
+	{Kind: "exact", Match: "pussy eating", Replace: "pussy licking"},
+	{Kind: "synonym", Match: "pussy cumshot, cum-on-pussy", Replace: "cum on pussy"},
+	{Kind: "synonym", Match: "tits cumshoot, tits cumshot", Replace: "cum on tits"},
+	{Kind: "exact", Match: "body-cumshot", Replace: "cum on body"},
+	{Kind: "synonym", Match: "hairy, hairy bush", Replace: "hairy pussy"},
+	{Kind: "exact", Match: "no tattoo", Replace: "no tattoos"},
+	{Kind: "synonym", Match: "tattoo, tatoos, tattoo(s)", Replace: "tattoos"},
+	{Kind: "synonym", Match: "piercing, pirced pussy, pierced navel", Replace: "piercings"},
+	{Kind: "exact", Match: "russian girl", Replace: "russian"},
+	{Kind: "exact", Match: "spanish girl", Replace: "spanish"},
+	{Kind: "exact", Match: "stepbro", Replace: "step brother"},
+	{Kind: "exact", Match: "stepsis", Replace: "step sister"},
+	{Kind: "synonym", Match: "toys, vibrator", Replace: "sex toys"},
+	{Kind: "exact", Match: "ass cumshot", Replace: "cum on ass"},
+	{Kind: "exact", Match: "big-ass", Replace: "big ass"},
+	{Kind: "exact", Match: "mature mother", Replace: "mature"},
+	{Kind: "exact", Match: "latin step sister", Replace: "latina"},
+	{Kind: "exact", Match: "group", Replace: "group sex"},
+	{Kind: "exact", Match: "lesbian mom", Replace: "lesbian"},
+	{Kind: "exact", Match: "twin sisters", Replace: "twins"},
+	{Kind: "exact", Match: "threesomes", Replace: "threesome"},
+	{Kind: "exact", Match: "feet cumshot", Replace: "cum on feet"},
+	{Kind: "exact", Match: "black female", Replace: "black"},
+	{Kind: "synonym", Match: "pov fucking, pov vr", Replace: "pov"},
+	{Kind: "synonym", Match: "xxx parody, xxx parody vr porn", Replace: "parody"},
+	{Kind: "synonym", Match: "fingering, masterbation", Replace: "masturbation"},
+	{Kind: "exact", Match: "solo models", Replace: "solo"},
+}
@@ -0,0 +1,287 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultUserID is the profile used when nobody logged in, it owns everything created before
+// profiles existed
+const DefaultUserID = 1
+
+// User is a profile with its own ratings, lists, watch history and playlists. The library itself
+// is shared by all profiles.
+type User struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+
+	Name     string `gorm:"unique_index" json:"name"`
+	Password string `json:"-"` // bcrypt hash, empty if the profile isn't protected
+}
+
+// UserScene holds the personal state of a scene for a profile
+type UserScene struct {
+	ID             uint    `gorm:"primary_key"`
+	UserID         uint    `gorm:"unique_index:idx_user_scene"`
+	SceneID        uint    `gorm:"unique_index:idx_user_scene;index"`
+	StarRating     float64 `gorm:"default:0"`
+	Favourite      bool    `gorm:"default:false"`
+	Watchlist      bool    `gorm:"default:false"`
+	Wishlist       bool    `gorm:"default:false"`
+	IsWatched      bool    `gorm:"default:false"`
+	TotalWatchTime int     `gorm:"default:0"`
+}
+
+// UserActor holds the personal state of an actor for a profile
+type UserActor struct {
+	ID         uint    `gorm:"primary_key"`
+	UserID     uint    `gorm:"unique_index:idx_user_actor"`
+	ActorID    uint    `gorm:"unique_index:idx_user_actor;index"`
+	StarRating float64 `gorm:"default:0"`
+	Favourite  bool    `gorm:"default:false"`
+	Watchlist  bool    `gorm:"default:false"`
+}
+
+func (o *User) GetIfExist(name string) error {
+	commonDb, _ := GetCommonDB()
+
+	return commonDb.Where("lower(name) = ?", strings.ToLower(name)).First(o).Error
+}
+
+func (o *User) GetIfExistByPK(id uint) error {
+	commonDb, _ := GetCommonDB()
+
+	return commonDb.Where(&User{ID: id}).First(o).Error
+}
+
+func (o *User) Save() error {
+	commonDb, _ := GetCommonDB()
+
+	return SaveWithRetry(commonDb, o)
+}
+
+// Delete removes the profile along with its personal state, histories and playlists
+func (o *User) Delete() error {
+	if o.ID == DefaultUserID {
+		return fmt.Errorf("the default profile can't be deleted")
+	}
+
+	db, _ := GetDB()
+	defer db.Close()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []interface{}{&UserScene{}, &UserActor{}, &History{}, &Playlist{}, &PlaybackPosition{}} {
+			if err := tx.Where("user_id = ?", o.ID).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(o).Error
+	})
+}
+
+func (o *User) SetPassword(password string) error {
+	if password == "" {
+		o.Password = ""
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	o.Password = string(hash)
+	return nil
+}
+
+// CheckPassword accepts any password for a profile that isn't protected
+func (o *User) CheckPassword(password string) bool {
+	if o.Password == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(o.Password), []byte(password)) == nil
+}
+
+func GetUsers() []User {
+	commonDb, _ := GetCommonDB()
+
+	var users []User
+	commonDb.Order("id").Find(&users)
+	return users
+}
+
+// UsersHavePasswords reports whether any profile is protected, profiles then have to log in
+func UsersHavePasswords() bool {
+	commonDb, _ := GetCommonDB()
+
+	var count int
+	commonDb.Model(&User{}).Where("password <> ''").Count(&count)
+	return count > 0
+}
+
+// ResolveUser returns the profile matching the credentials. Once profiles are protected, the
+// ones without a password can't be logged in with by name, any password would do for them.
+func ResolveUser(name string, password string) (User, bool) {
+	var user User
+	if name == "" || user.GetIfExist(name) != nil || !user.CheckPassword(password) {
+		return User{}, false
+	}
+	if user.Password == "" && UsersHavePasswords() {
+		return User{}, false
+	}
+	return user, true
+}
+
+func GetDefaultUser() User {
+	var user User
+	user.GetIfExistByPK(DefaultUserID)
+	return user
+}
+
+// userSceneWhere is a condition on the personal state of a scene, cond refers to user_scenes as "us"
+func userSceneWhere(userID uint, cond string) string {
+	return fmt.Sprintf("exists (select 1 from user_scenes us where us.scene_id = scenes.id and us.user_id = %d and %s)", userID, cond)
+}
+
+// userSceneColumn returns a column of the personal state of a scene, for sorting
+func userSceneColumn(userID uint, column string) string {
+	return fmt.Sprintf("coalesce((select us.%s from user_scenes us where us.scene_id = scenes.id and us.user_id = %d), 0)", column, userID)
+}
+
+// userActorWhere is a condition on the personal state of an actor, cond refers to user_actors as "ua"
+func userActorWhere(userID uint, cond string) string {
+	return fmt.Sprintf("exists (select 1 from user_actors ua where ua.actor_id = actors.id and ua.user_id = %d and %s)", userID, cond)
+}
+
+func userActorColumn(userID uint, column string) string {
+	return fmt.Sprintf("coalesce((select ua.%s from user_actors ua where ua.actor_id = actors.id and ua.user_id = %d), 0)", column, userID)
+}
+
+// sceneRatingAverageSQL averages the ratings the user gave to the visible scenes of an actor
+func sceneRatingAverageSQL(userID uint) string {
+	return fmt.Sprintf("(select AVG(us.star_rating) from scene_cast sc join scenes s on s.id=sc.scene_id join user_scenes us on us.scene_id=s.id and us.user_id=%d where sc.actor_id=actors.id and us.star_rating > 0 and s.is_hidden=0)", userID)
+}
+
+func sceneRatingCountSQL(userID uint) string {
+	return fmt.Sprintf("(select count(*) from scene_cast sc join scenes s on s.id=sc.scene_id join user_scenes us on us.scene_id=s.id and us.user_id=%d where sc.actor_id=actors.id and us.star_rating > 0 and s.is_hidden=0)", userID)
+}
+
+// ApplyUserSceneState fills the personal fields of the scenes with the state of a profile and
+// drops the watch history of other profiles
+func ApplyUserSceneState(db *gorm.DB, userID uint, scenes []Scene) {
+	if len(scenes) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(scenes))
+	for i := range scenes {
+		ids[i] = scenes[i].ID
+	}
+	var states []UserScene
+	db.Where("user_id = ? and scene_id in (?)", userID, ids).Find(&states)
+	byScene := map[uint]UserScene{}
+	for _, s := range states {
+		byScene[s.SceneID] = s
+	}
+
+	for i := range scenes {
+		scenes[i].setUserState(userID, byScene[scenes[i].ID])
+	}
+}
+
+func (o *Scene) setUserState(userID uint, s UserScene) {
+	o.StarRating = s.StarRating
+	o.Favourite = s.Favourite
+	o.Watchlist = s.Watchlist
+	o.Wishlist = s.Wishlist
+	o.IsWatched = s.IsWatched
+	o.TotalWatchTime = s.TotalWatchTime
+
+	var history []History
+	for _, h := range o.History {
+		if h.UserID == userID {
+			history = append(history, h)
+		}
+	}
+	if o.History != nil && history == nil {
+		history = []History{}
+	}
+	o.History = history
+}
+
+// ApplyUserState fills the personal fields of the scene with the state of a profile
+func (o *Scene) ApplyUserState(userID uint) {
+	commonDb, _ := GetCommonDB()
+
+	var s UserScene
+	commonDb.Where("user_id = ? and scene_id = ?", userID, o.ID).First(&s)
+	o.setUserState(userID, s)
+}
+
+// SaveUserState stores the personal fields of the scene as the state of a profile
+func (o *Scene) SaveUserState(userID uint) {
+	commonDb, _ := GetCommonDB()
+
+	var s UserScene
+	commonDb.Where(UserScene{UserID: userID, SceneID: o.ID}).FirstOrInit(&s)
+	s.StarRating = o.StarRating
+	s.Favourite = o.Favourite
+	s.Watchlist = o.Watchlist
+	s.Wishlist = o.Wishlist
+	s.IsWatched = o.IsWatched
+	s.TotalWatchTime = o.TotalWatchTime
+	SaveWithRetry(commonDb, &s)
+}
+
+func ApplyUserActorState(db *gorm.DB, userID uint, actors []Actor) {
+	if len(actors) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(actors))
+	for i := range actors {
+		ids[i] = actors[i].ID
+	}
+	var states []UserActor
+	db.Where("user_id = ? and actor_id in (?)", userID, ids).Find(&states)
+	byActor := map[uint]UserActor{}
+	for _, s := range states {
+		byActor[s.ActorID] = s
+	}
+
+	for i := range actors {
+		actors[i].setUserState(byActor[actors[i].ID])
+	}
+}
+
+func (o *Actor) setUserState(s UserActor) {
+	o.StarRating = s.StarRating
+	o.Favourite = s.Favourite
+	o.Watchlist = s.Watchlist
+}
+
+// ApplyUserState fills the personal fields of the actor, and of the scenes loaded with it, with
+// the state of a profile
+func (o *Actor) ApplyUserState(userID uint) {
+	commonDb, _ := GetCommonDB()
+
+	var s UserActor
+	commonDb.Where("user_id = ? and actor_id = ?", userID, o.ID).First(&s)
+	o.setUserState(s)
+	ApplyUserSceneState(commonDb, userID, o.Scenes)
+}
+
+// SaveUserState stores the personal fields of the actor as the state of a profile
+func (o *Actor) SaveUserState(userID uint) {
+	commonDb, _ := GetCommonDB()
+
+	var s UserActor
+	commonDb.Where(UserActor{UserID: userID, ActorID: o.ID}).FirstOrInit(&s)
+	s.StarRating = o.StarRating
+	s.Favourite = o.Favourite
+	s.Watchlist = o.Watchlist
+	SaveWithRetry(commonDb, &s)
+}
@@ -2,12 +2,19 @@ package models
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/putdotio/go-putio"
+	"github.com/xbapps/xbvr/pkg/webdav"
 	"golang.org/x/oauth2"
 )
 
@@ -22,6 +29,7 @@ type Volume struct {
 	LastScan       time.Time `json:"last_scan" xbvrbackup:""`
 	IsEnabled      bool      `json:"-" xbvrbackup:""`
 	IsAvailable    bool      `json:"is_available" xbvrbackup:"-"`
+	IsWatched      bool      `gorm:"-" json:"is_watched" xbvrbackup:"-"`
 	FileCount      int       `gorm:"-" json:"file_count" xbvrbackup:"-"`
 	UnmatchedCount int       `gorm:"-" json:"unmatched_count" xbvrbackup:"-"`
 	TotalSize      int64     `gorm:"-" json:"total_size" xbvrbackup:"-"`
@@ -53,6 +61,18 @@ func (o *Volume) IsMounted() bool {
 		return true
 	case "putio":
 		return true
+	case "webdav":
+		_, err := o.GetWebDAVClient().Stat("/")
+		return err == nil
+	case "s3":
+		client, meta, err := o.GetS3Client()
+		if err != nil {
+			return false
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		exists, err := client.BucketExists(ctx, meta.Bucket)
+		return err == nil && exists
 	default:
 		return false
 	}
@@ -93,6 +113,58 @@ func (o *Volume) GetPutIOClient() *putio.Client {
 	return putio.NewClient(oauthClient)
 }
 
+// WebDAVMetadata is stored as JSON in Volume.Metadata for volumes of type "webdav"
+type WebDAVMetadata struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (o *Volume) GetWebDAVClient() *webdav.Client {
+	var meta WebDAVMetadata
+	json.Unmarshal([]byte(o.Metadata), &meta)
+	return webdav.NewClient(meta.URL, meta.Username, meta.Password)
+}
+
+// S3Metadata is stored as JSON in Volume.Metadata for volumes of type "s3"
+type S3Metadata struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	UseSSL    bool   `json:"use_ssl"`
+}
+
+// VolumePath is used as Volume.Path, eg "s3://s3.wasabisys.com/bucket/prefix". The paths of files
+// on the volume start with it, so backups can link files to restored volumes by path.
+func (m S3Metadata) VolumePath() string {
+	return strings.TrimSuffix(fmt.Sprintf("s3://%v/%v", m.Endpoint, path.Join(m.Bucket, m.Prefix)), "/")
+}
+
+func (o *Volume) GetS3Metadata() S3Metadata {
+	var meta S3Metadata
+	json.Unmarshal([]byte(o.Metadata), &meta)
+	return meta
+}
+
+func (o *Volume) GetS3Client() (*minio.Client, S3Metadata, error) {
+	meta := o.GetS3Metadata()
+	client, err := minio.New(meta.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(meta.AccessKey, meta.SecretKey, ""),
+		Secure: meta.UseSSL,
+		Region: meta.Region,
+	})
+	return client, meta, err
+}
+
+// S3ObjectKey returns the key of the object a file on an s3 volume is stored as
+func (o *Volume) S3ObjectKey(f *File) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(f.Path, o.Path), "/")
+	return path.Join(o.GetS3Metadata().Prefix, rel, f.Filename)
+}
+
 func CheckVolumes() {
 	db, _ := GetDB()
 	defer db.Close()
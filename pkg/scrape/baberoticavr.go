@@ -20,7 +20,7 @@ func BaberoticaVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, ou
 	scraperID := "baberoticavr"
 	siteID := "BaberoticaVR"
 	logScrapeStart(scraperID, siteID)
-	additionalDetailCollector := createCollector("baberoticavr.com")
+	additionalDetailCollector := createCollector(scraperID, "baberoticavr.com")
 
 	additionalDetailCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := e.Request.Ctx.GetAny("scene").(models.ScrapedScene)
@@ -25,10 +25,10 @@ func BadoinkSite(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("badoinkvr.com", "babevr.com", "vrcosplayx.com", "18vr.com", "realvr.com")
-	siteCollector := createCollector("badoinkvr.com", "babevr.com", "vrcosplayx.com", "18vr.com", "realvr.com")
+	sceneCollector := createCollector(scraperID, "badoinkvr.com", "babevr.com", "vrcosplayx.com", "18vr.com", "realvr.com")
+	siteCollector := createCollector(scraperID, "badoinkvr.com", "babevr.com", "vrcosplayx.com", "18vr.com", "realvr.com")
 
-	trailerCollector := cloneCollector(sceneCollector)
+	trailerCollector := cloneCollector(sceneCollector, scraperID)
 
 	commonDb, _ := models.GetCommonDB()
 
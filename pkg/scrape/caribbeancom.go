@@ -20,9 +20,9 @@ func CariVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 	siteID := "CaribbeanCom VR"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("en.caribbeancom.com", "www.caribbeancom.com")
-	siteCollector := createCollector("en.caribbeancom.com", "www.caribbeancom.com")
-	sceneCollectorJap := cloneCollector(sceneCollector)
+	sceneCollector := createCollector(scraperID, "en.caribbeancom.com", "www.caribbeancom.com")
+	siteCollector := createCollector(scraperID, "en.caribbeancom.com", "www.caribbeancom.com")
+	sceneCollectorJap := cloneCollector(sceneCollector, scraperID)
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 
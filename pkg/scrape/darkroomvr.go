@@ -18,8 +18,8 @@ func DarkRoomVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out
 	siteID := "DarkRoomVR"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("darkroomvr.com")
-	siteCollector := createCollector("darkroomvr.com")
+	sceneCollector := createCollector(scraperID, "darkroomvr.com")
+	siteCollector := createCollector(scraperID, "darkroomvr.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
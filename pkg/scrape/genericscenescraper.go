@@ -0,0 +1,337 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/mozillazg/go-slugify"
+	"github.com/thoas/go-funk"
+	"github.com/tidwall/gjson"
+	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+var genericSceneFields = []string{"site_id", "title", "synopsis", "released", "duration", "studio", "covers", "gallery",
+	"tags", "cast", "filenames", "homepage_url", "members_url", "trailer_url"}
+
+func GenericSceneScraper(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, conf models.GenericSceneScraperConfig, limitScraping bool) error {
+	defer wg.Done()
+	logScrapeStart(conf.ID, conf.Name)
+
+	domains := []string{conf.Domain}
+	if !strings.HasPrefix(conf.Domain, "www.") {
+		domains = append(domains, "www."+conf.Domain)
+	}
+	sceneCollector := createCollector(conf.ID, domains...)
+	siteCollector := createCollector(conf.ID, domains...)
+
+	visitScene := func(sceneURL string) {
+		// If scene exist in database, there's no need to scrape
+		if !funk.ContainsString(knownScenes, sceneURL) {
+			WaitBeforeVisit(conf.Domain, sceneCollector.Visit, sceneURL)
+		}
+	}
+	visitPage := func(pageURL string) {
+		if !limitScraping {
+			WaitBeforeVisit(conf.Domain, siteCollector.Visit, pageURL)
+		}
+	}
+
+	if conf.IsJson {
+		sceneCollector.OnResponse(func(r *colly.Response) {
+			if r.StatusCode != 200 {
+				return
+			}
+			resp := gjson.ParseBytes(r.Body)
+			sc := newGenericScene(conf, r.Request)
+			for _, rule := range conf.Rules {
+				var results []string
+				value := resp.Get(rule.Selector)
+				if value.IsArray() {
+					for _, v := range value.Array() {
+						results = append(results, v.String())
+					}
+				} else if value.Exists() {
+					results = append(results, value.String())
+				}
+				for _, result := range results {
+					if len(rule.PostProcessing) > 0 {
+						result = postProcessing(rule, result, nil)
+					}
+					assignSceneField(&sc, rule.XbvrField, result, r.Request)
+				}
+			}
+			if finishGenericScene(&sc) {
+				out <- sc
+			}
+		})
+
+		siteCollector.OnResponse(func(r *colly.Response) {
+			if r.StatusCode != 200 {
+				return
+			}
+			resp := gjson.ParseBytes(r.Body)
+			for _, link := range resp.Get(conf.SceneLinkSelector).Array() {
+				visitScene(r.Request.AbsoluteURL(link.String()))
+			}
+			if conf.NextPageSelector != "" {
+				if next := resp.Get(conf.NextPageSelector).String(); next != "" {
+					visitPage(r.Request.AbsoluteURL(next))
+				}
+			}
+		})
+	} else {
+		sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
+			sc := newGenericScene(conf, e.Request)
+			for _, rule := range conf.Rules {
+				e.ForEach(rule.Selector, func(id int, e *colly.HTMLElement) {
+					if (rule.First.Present() && id < rule.First.OrElse(0)) || (rule.Last.Present() && id > rule.Last.OrElse(0)) {
+						return
+					}
+					var result string
+					switch rule.ResultType {
+					case "text", "":
+						result = strings.TrimSpace(e.Text)
+					case "attr":
+						result = strings.TrimSpace(e.Attr(rule.Attribute))
+					case "html":
+						result, _ = e.DOM.Html()
+					}
+					if len(rule.PostProcessing) > 0 {
+						result = postProcessing(rule, result, e)
+					}
+					assignSceneField(&sc, rule.XbvrField, result, e.Request)
+				})
+			}
+			if finishGenericScene(&sc) {
+				out <- sc
+			}
+		})
+
+		siteCollector.OnHTML(conf.SceneLinkSelector, func(e *colly.HTMLElement) {
+			visitScene(e.Request.AbsoluteURL(e.Attr("href")))
+		})
+		if conf.NextPageSelector != "" {
+			siteCollector.OnHTML(conf.NextPageSelector, func(e *colly.HTMLElement) {
+				visitPage(e.Request.AbsoluteURL(e.Attr("href")))
+			})
+		}
+	}
+
+	if singleSceneURL != "" {
+		WaitBeforeVisit(conf.Domain, sceneCollector.Visit, singleSceneURL)
+	} else {
+		WaitBeforeVisit(conf.Domain, siteCollector.Visit, conf.ListURL)
+	}
+
+	if updateSite {
+		updateSiteLastUpdate(conf.ID)
+	}
+	logScrapeFinished(conf.ID, conf.Name)
+	return nil
+}
+
+func newGenericScene(conf models.GenericSceneScraperConfig, req *colly.Request) models.ScrapedScene {
+	sc := models.ScrapedScene{}
+	sc.ScraperID = conf.ID
+	sc.SceneType = conf.SceneType
+	if sc.SceneType == "" {
+		sc.SceneType = "VR"
+	}
+	sc.Studio = conf.Studio
+	sc.Site = conf.Name
+	sc.MasterSiteId = conf.MasterSiteId
+	sc.HomepageURL = strings.Split(req.URL.String(), "?")[0]
+	return sc
+}
+
+func finishGenericScene(sc *models.ScrapedScene) bool {
+	if sc.SiteID == "" {
+		log.Warnf("Unable to process %s - no scene id", sc.HomepageURL)
+		return false
+	}
+	if sc.Studio == "" {
+		sc.Studio = sc.Site
+	}
+	sc.SceneID = slugify.Slugify(sc.Site) + "-" + sc.SiteID
+	return true
+}
+
+func assignSceneField(sc *models.ScrapedScene, field string, value string, req *colly.Request) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	switch field {
+	case "site_id":
+		sc.SiteID = value
+	case "title":
+		sc.Title = value
+	case "synopsis":
+		sc.Synopsis = value
+	case "released":
+		sc.Released = value
+	case "duration":
+		// "hh:mm:ss" or "mm:ss", otherwise the first number as minutes
+		if strings.Contains(value, ":") {
+			sc.Duration = parseDurationMinutes(value)
+		} else {
+			sc.Duration, _ = strconv.Atoi(regexp.MustCompile(`\d+`).FindString(value))
+		}
+	case "studio":
+		sc.Studio = value
+	case "covers":
+		sc.Covers = append(sc.Covers, req.AbsoluteURL(value))
+	case "gallery":
+		sc.Gallery = append(sc.Gallery, req.AbsoluteURL(value))
+	case "tags":
+		sc.Tags = append(sc.Tags, value)
+	case "cast":
+		sc.Cast = append(sc.Cast, value)
+	case "filenames":
+		sc.Filenames = append(sc.Filenames, value)
+	case "homepage_url":
+		sc.HomepageURL = req.AbsoluteURL(value)
+	case "members_url":
+		sc.MembersUrl = req.AbsoluteURL(value)
+	case "trailer_url":
+		sc.TrailerType = "url"
+		sc.TrailerSrc = req.AbsoluteURL(value)
+	}
+}
+
+// LoadGenericSceneScrapers reads the scraper definitions, json or yaml, in the scene_scrapers
+// folder of the app dir
+func LoadGenericSceneScrapers() []models.GenericSceneScraperConfig {
+	dir := filepath.Join(common.AppDir, "scene_scrapers")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		os.MkdirAll(dir, os.ModePerm)
+		// not loaded, to give an example only
+		os.WriteFile(filepath.Join(dir, "example.yaml.sample"), []byte(genericSceneScraperExample), 0644)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		log.Warnf("Error reading scene scrapers %s", err)
+		return nil
+	}
+
+	var out []models.GenericSceneScraperConfig
+	ids := map[string]bool{}
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name()))
+		if f.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			log.Warnf("Error reading scene scraper %s: %s", f.Name(), err)
+			continue
+		}
+		conf, err := ParseGenericSceneScraper(b, ext == ".json")
+		if err == nil && ids[conf.ID] {
+			err = fmt.Errorf("id %s is used by another scraper", conf.ID)
+		}
+		if err != nil {
+			log.Warnf("Skipping scene scraper %s: %s", f.Name(), err)
+			continue
+		}
+		ids[conf.ID] = true
+		out = append(out, conf)
+	}
+	return out
+}
+
+func ParseGenericSceneScraper(data []byte, isJson bool) (models.GenericSceneScraperConfig, error) {
+	var conf models.GenericSceneScraperConfig
+	if !isJson {
+		// go through json, so yaml definitions share the json field names of the rules
+		var tmp interface{}
+		if err := yaml.Unmarshal(data, &tmp); err != nil {
+			return conf, err
+		}
+		var err error
+		if data, err = json.Marshal(tmp); err != nil {
+			return conf, err
+		}
+	}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return conf, err
+	}
+
+	switch {
+	case conf.ID == "" || conf.Name == "":
+		return conf, fmt.Errorf("id and name are required")
+	case conf.Domain == "":
+		return conf, fmt.Errorf("domain is required")
+	case conf.ListURL == "" || conf.SceneLinkSelector == "":
+		return conf, fmt.Errorf("list_url and scene_link_selector are required")
+	}
+	for _, rule := range conf.Rules {
+		if !funk.ContainsString(genericSceneFields, rule.XbvrField) {
+			return conf, fmt.Errorf("unknown xbvr_field %s", rule.XbvrField)
+		}
+	}
+	return conf, nil
+}
+
+const genericSceneScraperExample = `# Copy to a .yaml or .json file in this folder and restart to add the scraper.
+# Selectors are css selectors, or gjson paths with is_json. xbvr_field is one of site_id (required),
+# title, synopsis, released (YYYY-MM-DD), duration, studio, covers, gallery, tags, cast, filenames,
+# homepage_url, members_url and trailer_url. Post processing functions are the ones of the actor
+# scrapers, eg RegexString, RegexReplaceAll, Replace or Parse Date.
+id: example
+name: Example VR
+avatar_url: https://example.com/favicon.png
+domain: example.com
+studio: Example
+list_url: https://example.com/videos?page=1
+next_page_selector: a.pagination-next
+scene_link_selector: div.video-card a.title
+rules:
+  - xbvr_field: site_id
+    selector: link[rel="canonical"]
+    result_type: attr
+    attribute: href
+    post_processing:
+      - post_processing: RegexString
+        params: ['/videos/(\d+)', "1"]
+  - xbvr_field: title
+    selector: h1
+  - xbvr_field: released
+    selector: span.date
+    post_processing:
+      - post_processing: Parse Date
+        params: ["Jan 2, 2006"]
+  - xbvr_field: duration
+    selector: span.duration
+  - xbvr_field: covers
+    selector: video
+    result_type: attr
+    attribute: poster
+  - xbvr_field: tags
+    selector: ul.tags a
+  - xbvr_field: cast
+    selector: a.model-name
+`
+
+func registerGenericSceneScrapers() {
+	for _, conf := range LoadGenericSceneScrapers() {
+		conf := conf
+		scrape := func(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, limitScraping bool) error {
+			return GenericSceneScraper(wg, updateSite, knownScenes, out, singleSceneURL, conf, limitScraping)
+		}
+		if conf.MasterSiteId == "" {
+			registerScraper(conf.ID, conf.Name, conf.AvatarURL, conf.Domain, scrape)
+		} else {
+			registerAlternateScraper(conf.ID, conf.Name, conf.AvatarURL, conf.Domain, conf.MasterSiteId, scrape)
+		}
+	}
+}
@@ -20,9 +20,9 @@ func GroobyVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out ch
 	allowedDomains := []string{"groobyvr.com", "www.groobyvr.com"}
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector(allowedDomains...)
-	siteCollector := createCollector(allowedDomains...)
-	vodCollector := createCollector(allowedDomains...)
+	sceneCollector := createCollector(scraperID, allowedDomains...)
+	siteCollector := createCollector(scraperID, allowedDomains...)
+	vodCollector := createCollector(scraperID, allowedDomains...)
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -10,7 +10,7 @@ import (
 )
 
 func ScrapeJavDB(out *[]models.ScrapedScene, queryString string) {
-	sceneCollector := createCollector("www.javdatabase.com")
+	sceneCollector := createCollector("", "www.javdatabase.com")
 
 	sceneCollector.OnHTML(`html`, func(html *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
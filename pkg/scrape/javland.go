@@ -9,7 +9,7 @@ import (
 )
 
 func ScrapeJavLand(out *[]models.ScrapedScene, queryString string) {
-	sceneCollector := createCollector("jav.land")
+	sceneCollector := createCollector("", "jav.land")
 
 	sceneCollector.OnHTML(`html`, func(html *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
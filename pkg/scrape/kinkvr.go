@@ -19,8 +19,8 @@ func KinkVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 	siteID := "KinkVR"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("www.kink.com", "kink.com")
-	siteCollector := createCollector("www.kink.com", "kink.com")
+	sceneCollector := createCollector(scraperID, "www.kink.com", "kink.com")
+	siteCollector := createCollector(scraperID, "www.kink.com", "kink.com")
 
 	setAgeGateCookie := func(r *colly.Request) {
 		r.Headers.Set("Cookie", "age_gate_accepted=1")
@@ -29,8 +29,8 @@ func LethalHardcoreSite(wg *models.ScrapeWG, updateSite bool, knownScenes []stri
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("lethalhardcorevr.com", "whorecraftvr.com")
-	siteCollector := createCollector("lethalhardcorevr.com", "whorecraftvr.com")
+	sceneCollector := createCollector(scraperID, "lethalhardcorevr.com", "whorecraftvr.com")
+	siteCollector := createCollector(scraperID, "lethalhardcorevr.com", "whorecraftvr.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -17,9 +17,9 @@ func LittleCaprice(wg *models.ScrapeWG, updateSite bool, knownScenes []string, o
 	siteID := "Little Caprice Dreams"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("www.littlecaprice-dreams.com")
-	siteCollector := createCollector("www.littlecaprice-dreams.com")
-	galleryCollector := cloneCollector(sceneCollector)
+	sceneCollector := createCollector(scraperID, "www.littlecaprice-dreams.com")
+	siteCollector := createCollector(scraperID, "www.littlecaprice-dreams.com")
+	galleryCollector := cloneCollector(sceneCollector, scraperID)
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
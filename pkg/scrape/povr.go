@@ -19,8 +19,8 @@ func POVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<-
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("povr.com")
-	siteCollector := createCollector("povr.com")
+	sceneCollector := createCollector(scraperID, "povr.com")
+	siteCollector := createCollector(scraperID, "povr.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -176,7 +176,7 @@ func addPOVRScraper(id string, name string, company string, avatarURL string, cu
 	}
 }
 
-func init() {
+func registerPOVRScrapers() {
 	registerScraper("povr-single_scene", "POVR - Other Studios", "", "povr.com", func(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, limitScraping bool) error {
 		return POVR(wg, updateSite, knownScenes, out, singleSceneURL, "", "", "", "", singeScrapeAdditionalInfo, limitScraping, "")
 	})
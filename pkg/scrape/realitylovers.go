@@ -15,8 +15,8 @@ func RealityLoversSite(wg *models.ScrapeWG, updateSite bool, knownScenes []strin
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector(domain)
-	siteCollector := createCollector(domain)
+	sceneCollector := createCollector(scraperID, domain)
+	siteCollector := createCollector(scraperID, domain)
 
 	// These cookies are needed for age verification.
 	siteCollector.OnRequest(func(r *colly.Request) {
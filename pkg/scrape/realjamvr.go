@@ -20,8 +20,8 @@ func RealJamSite(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector(domain)
-	siteCollector := createCollector(domain)
+	sceneCollector := createCollector(scraperID, domain)
+	siteCollector := createCollector(scraperID, domain)
 
 	c := siteCollector.Cookies(domain)
 	cookie := http.Cookie{Name: "age_confirmed", Value: "Tru", Domain: domain, Path: "/", Expires: time.Now().Add(time.Hour)}
@@ -34,7 +34,7 @@ func addRealVRScraper(id string, name string, company string, avatarURL string,
 	}
 }
 
-func init() {
+func registerRealVRScrapers() {
 	registerScraper("realvr-single_scene", "RealVR - Other Studios", "", "realvr.com", func(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, limitScraping bool) error {
 		return BadoinkSite(wg, updateSite, knownScenes, out, singleSceneURL, "", "", "", "", singeScrapeAdditionalInfo, limitScraping, "", false)
 	})
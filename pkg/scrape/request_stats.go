@@ -0,0 +1,59 @@
+package scrape
+
+import (
+	"strconv"
+	"sync"
+)
+
+// requestStats counts, per scraper, the pages its colly collectors fetched and the requests that
+// failed. Scrapers using their own http clients are not counted.
+var requestStats = struct {
+	sync.Mutex
+	pages  map[string]int
+	errors map[string]map[string]int
+}{pages: map[string]int{}, errors: map[string]map[string]int{}}
+
+func ResetRequestStats() {
+	requestStats.Lock()
+	defer requestStats.Unlock()
+
+	requestStats.pages = map[string]int{}
+	requestStats.errors = map[string]map[string]int{}
+}
+
+func countPage(scraperID string) {
+	if scraperID == "" {
+		return
+	}
+	requestStats.Lock()
+	defer requestStats.Unlock()
+
+	requestStats.pages[scraperID]++
+}
+
+// countRequestError counts a failed request by status, 0 being a request without response
+func countRequestError(scraperID string, status int) {
+	if scraperID == "" {
+		return
+	}
+	requestStats.Lock()
+	defer requestStats.Unlock()
+
+	if requestStats.errors[scraperID] == nil {
+		requestStats.errors[scraperID] = map[string]int{}
+	}
+	requestStats.errors[scraperID][strconv.Itoa(status)]++
+}
+
+// RequestStatsForScraper returns the pages a scraper fetched and its failed requests by status since
+// the last reset
+func RequestStatsForScraper(scraperID string) (int, map[string]int) {
+	requestStats.Lock()
+	defer requestStats.Unlock()
+
+	errors := map[string]int{}
+	for status, count := range requestStats.errors[scraperID] {
+		errors[status] = count
+	}
+	return requestStats.pages[scraperID], errors
+}
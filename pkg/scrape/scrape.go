@@ -22,7 +22,9 @@ var log = &common.Log
 
 var UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/73.0.3683.103 Safari/537.36"
 
-func createCollector(domains ...string) *colly.Collector {
+// createCollector returns a collector for the domains, counting its requests for the scraper with
+// the id. Collectors outside of site scrapes pass an empty id and aren't counted.
+func createCollector(scraperID string, domains ...string) *colly.Collector {
 	c := colly.NewCollector(
 		colly.AllowedDomains(domains...),
 		colly.CacheDir(getScrapeCacheDir()),
@@ -39,7 +41,7 @@ func createCollector(domains ...string) *colly.Collector {
 		log.Errorf("Error visiting %s %s", r.Request.URL, err)
 	})
 
-	c = createCallbacks(c)
+	c = createCallbacks(c, scraperID)
 
 	// see if the domain has a limit and set it
 	for _, domain := range domains {
@@ -64,13 +66,13 @@ func createCollector(domains ...string) *colly.Collector {
 	return c
 }
 
-func cloneCollector(c *colly.Collector) *colly.Collector {
+func cloneCollector(c *colly.Collector, scraperID string) *colly.Collector {
 	x := c.Clone()
-	x = createCallbacks(x)
+	x = createCallbacks(x, scraperID)
 	return x
 }
 
-func createCallbacks(c *colly.Collector) *colly.Collector {
+func createCallbacks(c *colly.Collector, scraperID string) *colly.Collector {
 	const maxRetries = 15
 
 	c.OnRequest(func(r *colly.Request) {
@@ -83,7 +85,12 @@ func createCallbacks(c *colly.Collector) *colly.Collector {
 		log.Infoln("visiting", r.URL.String())
 	})
 
+	c.OnResponse(func(r *colly.Response) {
+		countPage(scraperID)
+	})
+
 	c.OnError(func(r *colly.Response, err error) {
+		countRequestError(scraperID, r.StatusCode)
 		attempt := r.Ctx.GetAny("attempt").(int)
 
 		if r.StatusCode == 429 {
@@ -110,6 +117,18 @@ func getScrapeCacheDir() string {
 	return common.ScrapeCacheDir
 }
 
+// Init registers the scrapers listed in scrapers.json and the scene_scrapers directory, the
+// application directory has to be set up before
+func Init() {
+	registerGenericSceneScrapers()
+	registerPOVRScrapers()
+	registerRealVRScrapers()
+	registerSLRScrapers()
+	registerStashDBScrapers()
+	registerVRPHubScrapers()
+	registerVRPornScrapers()
+}
+
 func registerScraper(id string, name string, avatarURL string, domain string, f models.ScraperFunc) {
 	models.RegisterScraper(id, name, avatarURL, domain, f, "")
 }
@@ -197,7 +216,7 @@ func getTextFromHTMLWithSelector(data string, sel string) string {
 	return strings.TrimSpace(doc.Find(sel).Text())
 }
 func CreateCollector(domains ...string) *colly.Collector {
-	return createCollector(domains...)
+	return createCollector("", domains...)
 }
 
 func GetCoreDomain(domain string) string {
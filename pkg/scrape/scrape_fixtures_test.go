@@ -0,0 +1,478 @@
+package scrape
+
+// Scraper regression tests replay recorded HTTP traffic to the scrapers and compare the scenes they
+// emit with golden files. A scraper is covered once testdata/scrapers/<scraper id> holds its
+// fixtures: requests.json indexing the recorded responses, the response bodies and golden.json.
+// Scrapers without fixtures fail the test unless they are listed in scrapersWithoutFixtures.
+// An optional scraper.json sets {"single_scene_url": "..."} to scrape a single scene instead of
+// the first list page.
+//
+// Record or refresh the fixtures of a scraper from the live site with
+//
+//	go test ./pkg/scrape -run 'TestScrapers/vrhush$' -record
+//
+// and after an intended change rewrite the golden files from the recorded fixtures with -update.
+// Fixtures can also be written by hand after the markup of a site, -record replaces them with the
+// live pages.
+//
+// Requests go through net/http's default transport, as colly and plain http.Client do. Clients
+// with their own transport, like resty, are neither recorded nor replayed, in replay mode they are
+// pointed at a dead proxy so tests never reach the live sites.
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+var (
+	recordFixtures = flag.Bool("record", false, "record the scraper fixtures from the live sites")
+	updateGolden   = flag.Bool("update", false, "rewrite the golden files from the recorded fixtures")
+)
+
+const fixtureURLHeader = "X-Fixture-Url"
+
+type fixture struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	BodyHash    string `json:"body_hash,omitempty"` // requests with a body, eg graphql queries
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location,omitempty"`
+	File        string `json:"file"`
+}
+
+type fixtureSet struct {
+	dir      string
+	mu       sync.Mutex
+	fixtures []fixture
+	missing  []string
+}
+
+func loadFixtures(dir string) (*fixtureSet, error) {
+	s := &fixtureSet{dir: dir}
+	b, err := os.ReadFile(filepath.Join(dir, "requests.json"))
+	if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(b, &s.fixtures)
+}
+
+func (s *fixtureSet) save() error {
+	b, err := json.MarshalIndent(s.fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, "requests.json"), b, 0644)
+}
+
+func (s *fixtureSet) find(method string, u string, bodyHash string) (fixture, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.fixtures {
+		if f.Method == method && f.URL == u && f.BodyHash == bodyHash {
+			return f, true
+		}
+	}
+	s.missing = append(s.missing, method+" "+u)
+	return fixture{}, false
+}
+
+// add stores a response, replacing an earlier one for the same request
+func (s *fixtureSet) add(f fixture, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sum := sha1.Sum([]byte(f.Method + " " + f.URL + " " + f.BodyHash))
+	f.File = hex.EncodeToString(sum[:8]) + fixtureExt(f.ContentType)
+	if err := os.WriteFile(filepath.Join(s.dir, f.File), body, 0644); err != nil {
+		return err
+	}
+	for i := range s.fixtures {
+		if s.fixtures[i].Method == f.Method && s.fixtures[i].URL == f.URL && s.fixtures[i].BodyHash == f.BodyHash {
+			s.fixtures[i] = f
+			return nil
+		}
+	}
+	s.fixtures = append(s.fixtures, f)
+	return nil
+}
+
+func fixtureExt(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.Contains(mediaType, "json"):
+		return ".json"
+	case strings.Contains(mediaType, "html"):
+		return ".html"
+	case strings.HasPrefix(mediaType, "text/"):
+		return ".txt"
+	}
+	return ".bin"
+}
+
+// ServeHTTP answers a request rewritten by replayTransport from the fixtures
+func (s *fixtureSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f, ok := s.find(r.Method, r.Header.Get(fixtureURLHeader), bodyHash(body))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	b, err := os.ReadFile(filepath.Join(s.dir, f.File))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if f.ContentType != "" {
+		w.Header().Set("Content-Type", f.ContentType)
+	}
+	if f.Location != "" {
+		w.Header().Set("Location", f.Location)
+	}
+	w.WriteHeader(f.Status)
+	w.Write(b)
+}
+
+func bodyHash(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha1.Sum(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	return body, err
+}
+
+// replayTransport sends every request to the local fixture server
+type replayTransport struct {
+	server    *url.URL
+	transport http.RoundTripper
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.server.Scheme
+	r.URL.Host = t.server.Host
+	r.Host = t.server.Host
+	r.Header.Set(fixtureURLHeader, req.URL.String())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.transport.RoundTrip(r)
+	if resp != nil {
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// recordTransport passes requests on to the live sites and stores the responses as fixtures
+type recordTransport struct {
+	fixtures  *fixtureSet
+	transport http.RoundTripper
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	f := fixture{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		BodyHash:    bodyHash(body),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Location:    resp.Header.Get("Location"),
+	}
+	return resp, t.fixtures.add(f, respBody)
+}
+
+// scrapeWithFixtures runs a scraper against the fixtures in dir, or records them with -record, and
+// returns the scenes it emitted sorted by scene id
+func scrapeWithFixtures(t *testing.T, dir string, scrape func(*models.ScrapeWG, chan<- models.ScrapedScene)) []models.ScrapedScene {
+	t.Helper()
+
+	fixtures, err := loadFixtures(dir)
+	if err != nil && !*recordFixtures {
+		t.Fatalf("loading fixtures: %v", err)
+	}
+
+	live := http.DefaultTransport
+	defer func() { http.DefaultTransport = live }()
+	if *recordFixtures {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		http.DefaultTransport = recordTransport{fixtures: fixtures, transport: live}
+	} else {
+		server := httptest.NewServer(fixtures)
+		defer server.Close()
+		serverURL, _ := url.Parse(server.URL)
+		http.DefaultTransport = replayTransport{server: serverURL, transport: &http.Transport{}}
+	}
+
+	// colly would answer repeated runs from its cache instead of the fixtures
+	DeleteScrapeCache()
+	defer DeleteScrapeCache()
+
+	out := make(chan models.ScrapedScene)
+	var scenes []models.ScrapedScene
+	done := make(chan struct{})
+	go func() {
+		for sc := range out {
+			scenes = append(scenes, sc)
+		}
+		close(done)
+	}()
+
+	wg := &models.ScrapeWG{}
+	wg.Add(1)
+	scrape(wg, out)
+	close(out)
+	<-done
+
+	if *recordFixtures {
+		if err := fixtures.save(); err != nil {
+			t.Fatal(err)
+		}
+	} else if len(fixtures.missing) > 0 {
+		t.Errorf("requests without a fixture, record them again with -record:\n%s", strings.Join(fixtures.missing, "\n"))
+	}
+
+	sort.Slice(scenes, func(i, j int) bool { return scenes[i].SceneID < scenes[j].SceneID })
+	return scenes
+}
+
+func checkGolden(t *testing.T, dir string, scenes []models.ScrapedScene) {
+	t.Helper()
+
+	fName := filepath.Join(dir, "golden.json")
+	if *recordFixtures || *updateGolden {
+		b, err := json.MarshalIndent(scenes, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fName, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	b, err := os.ReadFile(fName)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	var golden []models.ScrapedScene
+	if err := json.Unmarshal(b, &golden); err != nil {
+		t.Fatalf("parsing golden file: %v", err)
+	}
+	// compare the json form, the golden file can't tell nil from empty
+	b, _ = json.Marshal(scenes)
+	var got []models.ScrapedScene
+	json.Unmarshal(b, &got)
+	if diff := deep.Equal(golden, got); len(diff) > 0 {
+		t.Errorf("scenes differ from %s, run with -update if the change is intended:\n%s", fName, strings.Join(diff, "\n"))
+	}
+}
+
+type scraperTestOptions struct {
+	SingleSceneURL string `json:"single_scene_url"`
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !*recordFixtures {
+		os.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+		os.Setenv("HTTPS_PROXY", "http://127.0.0.1:1")
+	}
+	appDir, _ := os.MkdirTemp("", "xbvr-test")
+	os.Setenv("XBVR_APPDIR", appDir)
+	models.Init()
+	Init()
+
+	// the tables scrapers look up sites, known scenes and http settings in
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.KV{}, &models.Site{}, &models.Scene{}, &models.Actor{}, &models.ExternalReference{}, &models.ExternalReferenceLink{})
+
+	code := m.Run()
+	os.RemoveAll(appDir)
+	os.Exit(code)
+}
+
+// scrapersWithoutFixtures lists the scrapers that have no recorded fixtures yet. A scraper missing
+// from testdata/scrapers fails TestScrapers unless it is listed here, record its fixtures and drop it
+// from the list instead of adding new scrapers to it.
+var scrapersWithoutFixtures = map[string]bool{
+	"18vr": true, "amateurcouplesvr": true, "amateurvr3d": true, "amorevr": true, "arporn": true,
+	"astrodomina": true, "baberoticavr": true, "babevr": true, "badoinkvr": true, "blondehexe": true,
+	"blowvr": true, "blush-erotica": true, "brasilvr": true, "bravomodelsmedia": true, "bvr": true,
+	"caribbeancomvr": true, "casanova": true, "covert-japan": true, "cuties-vr": true, "czechar": true,
+	"czechvr-single_scene": true, "czechvrcasting": true, "czechvrfetish": true, "czechvrintimacy": true,
+	"dandy": true, "deepinsex": true, "deviantsvr": true, "ellielouisevr": true, "emilybloom": true,
+	"erotic-sinners": true, "fatp": true, "footsiebay": true, "fuckpassvr-native": true, "groobyvr": true,
+	"heathering": true, "herpovr": true, "istripper": true, "jackandjillvr": true, "jimmydraws": true,
+	"kinkvr": true, "kinkygirlsberlin": true, "kmpvr": true, "koalavr": true, "lethalhardcorevr": true,
+	"littlecaprice": true, "lustreality": true, "lustyvr": true, "manny-s": true, "milfvr": true,
+	"mongercash": true, "mugur-porn-vr": true, "mutiny-vr": true, "naughtyamericavr": true, "no2studiovr": true,
+	"noir": true, "only3xvr": true, "onlytease": true, "passthroughvr-33": true, "peeping-thom": true,
+	"pervrt": true, "petersmax": true, "pip-vr": true, "plushiesvr": true, "porncornvr": true,
+	"povcentralvr": true, "povr-originals": true, "povr-single_scene": true, "randysroadstop": true,
+	"realhotvr": true, "realitylovers": true, "realjamvr": true, "realteensvr": true,
+	"realvr-single_scene": true, "sexbabesvr": true, "single_scene-stashdb": true, "sinsvr": true,
+	"slr-jav-originals": true, "slr-originals": true, "slr-single_scene": true, "sodcreate": true,
+	"squeeze-vr": true, "stasyqvr": true, "stockingsvr": true, "strictlyglamourvr": true, "stripzvr": true,
+	"suckmevr": true, "swallowbay": true, "sweetlonglips": true, "taboo-vr-porn": true,
+	"tadpolexxxstudio": true, "thatrandomeditor": true, "tmavr": true, "tmwvrnet": true,
+	"tonightsgirlfriend": true, "transvr": true, "tranzvr": true, "tsvirtuallovers": true, "upclosevr": true,
+	"v1vr": true, "virtualpee": true, "virtualrealamateur": true, "virtualrealgay": true,
+	"virtualrealpassion": true, "virtualrealporn": true, "virtualrealtrans": true, "virtualxporn": true,
+	"vr-pornnow": true, "vr3000": true, "vrallure": true, "vrbangers": true, "vrbgay": true, "vrbtrans": true,
+	"vrclubz": true, "vrconk": true, "vrcosplayx": true, "vredging": true, "vrixxens": true,
+	"vrlab9division": true, "vrlatina": true, "vrmodels": true, "vroomed": true, "vrpfilms": true,
+	"vrphub-single_scene": true, "vrporn-single_scene": true, "vrpornjack": true, "vrsexperts": true,
+	"vrsexygirlz": true, "vrsolos": true, "vrspy": true, "vrstars": true, "vrvids": true, "wankitnowvr": true,
+	"whorecraftvr": true, "zexyvr": true, "zzvr": true,
+}
+
+func TestScrapers(t *testing.T) {
+	for _, scraper := range models.GetScrapers() {
+		scraper := scraper
+		t.Run(scraper.ID, func(t *testing.T) {
+			dir := filepath.Join("testdata", "scrapers", scraper.ID)
+			_, err := os.Stat(filepath.Join(dir, "requests.json"))
+			switch {
+			case err == nil && scrapersWithoutFixtures[scraper.ID]:
+				t.Fatal("fixtures recorded, remove the scraper from scrapersWithoutFixtures")
+			case err != nil && !*recordFixtures:
+				if scrapersWithoutFixtures[scraper.ID] {
+					t.Skip("no fixtures recorded yet")
+				}
+				t.Fatalf("no fixtures recorded in %v, record them with -record", dir)
+			}
+
+			var opts scraperTestOptions
+			if b, err := os.ReadFile(filepath.Join(dir, "scraper.json")); err == nil {
+				if err := json.Unmarshal(b, &opts); err != nil {
+					t.Fatalf("reading scraper.json: %v", err)
+				}
+			}
+
+			scenes := scrapeWithFixtures(t, dir, func(wg *models.ScrapeWG, out chan<- models.ScrapedScene) {
+				if err := scraper.Scrape(wg, false, []string{}, out, opts.SingleSceneURL, "", true); err != nil {
+					t.Errorf("scraper failed: %v", err)
+				}
+			})
+			checkGolden(t, dir, scenes)
+		})
+	}
+}
+
+// TestGenericSceneScraperFixtures runs the harness itself on a definition based scraper, with
+// fixtures written by hand for a made up site
+func TestGenericSceneScraperFixtures(t *testing.T) {
+	dir := filepath.Join("testdata", "generic")
+	b, err := os.ReadFile(filepath.Join(dir, "scraper.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := ParseGenericSceneScraper(b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *recordFixtures {
+		t.Skip("fixtures of a made up site can't be recorded")
+	}
+
+	scenes := scrapeWithFixtures(t, dir, func(wg *models.ScrapeWG, out chan<- models.ScrapedScene) {
+		GenericSceneScraper(wg, false, []string{}, out, "", conf, false)
+	})
+	if len(scenes) != 2 {
+		t.Fatalf("expected 2 scenes, got %d", len(scenes))
+	}
+	checkGolden(t, dir, scenes)
+}
+
+func TestReplayReportsMissingFixtures(t *testing.T) {
+	fixtures := &fixtureSet{dir: t.TempDir()}
+	server := httptest.NewServer(fixtures)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	client := &http.Client{Transport: replayTransport{server: serverURL, transport: &http.Transport{}}}
+	resp, err := client.Get("https://example.com/not-recorded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+	if fmt.Sprint(fixtures.missing) != "[GET https://example.com/not-recorded]" {
+		t.Errorf("unexpected missing fixtures %v", fixtures.missing)
+	}
+}
+
+func TestRequestStatsPerScraper(t *testing.T) {
+	if *recordFixtures {
+		t.Skip("only counts replayed requests")
+	}
+	var scraper models.Scraper
+	for _, s := range models.GetScrapers() {
+		if s.ID == "wankzvr" {
+			scraper = s
+		}
+	}
+
+	ResetRequestStats()
+	scrapeWithFixtures(t, filepath.Join("testdata", "scrapers", scraper.ID), func(wg *models.ScrapeWG, out chan<- models.ScrapedScene) {
+		scraper.Scrape(wg, false, []string{}, out, "", "", true)
+	})
+
+	// the list page and two scenes, none of it counts for the other studios on povr.com
+	if pages, errors := RequestStatsForScraper("wankzvr"); pages != 3 || len(errors) != 0 {
+		t.Errorf("expected 3 pages without errors, got %d %v", pages, errors)
+	}
+	if pages, _ := RequestStatsForScraper("povr-originals"); pages != 0 {
+		t.Errorf("expected no pages for another studio of the domain, got %d", pages)
+	}
+}
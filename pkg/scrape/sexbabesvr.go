@@ -21,8 +21,8 @@ func SexBabesVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out
 	siteID := "SexBabesVR"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("sexbabesvr.com")
-	siteCollector := createCollector("sexbabesvr.com")
+	sceneCollector := createCollector(scraperID, "sexbabesvr.com")
+	siteCollector := createCollector(scraperID, "sexbabesvr.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -21,8 +21,8 @@ func SinsVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 	siteID := "SinsVR"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("xsinsvr.com")
-	siteCollector := createCollector("xsinsvr.com")
+	sceneCollector := createCollector(scraperID, "xsinsvr.com")
+	siteCollector := createCollector(scraperID, "xsinsvr.com")
 
 	durationRegexes := []*regexp.Regexp{
 		regexp.MustCompile(`(?:(?P<h>\d+):)?(?P<m>\d+):(?P<s>\d+)`),           // e.g. 11:11, 1:11:11
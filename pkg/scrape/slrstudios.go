@@ -848,7 +848,7 @@ func addSLRScraper(id string, name string, company string, avatarURL string, cus
 	}
 }
 
-func init() {
+func registerSLRScrapers() {
 	var scrapers config.ScraperList
 	// scraper for single scenes with no existing scraper for the studio
 	registerScraper("slr-single_scene", "SLR - Other Studios", "", "sexlikereal.com", func(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, limitScraping bool) error {
@@ -101,7 +101,7 @@ func processScrapedScene(stashScene models.StashScene, masterSiteId string, comm
 	return sc
 }
 
-func init() {
+func registerStashDBScrapers() {
 	addStashScraper("single_scene", "Stashdb - Other", "https://stashapp.cc/images/stash.svg", "", "")
 	var scrapers config.ScraperList
 	scrapers.Load()
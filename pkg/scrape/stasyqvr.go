@@ -19,8 +19,8 @@ func StasyQVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out ch
 	siteID := "StasyQVR"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("stasyqvr.com")
-	siteCollector := createCollector("stasyqvr.com")
+	sceneCollector := createCollector(scraperID, "stasyqvr.com")
+	siteCollector := createCollector(scraperID, "stasyqvr.com")
 	siteCollector.MaxDepth = 5
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
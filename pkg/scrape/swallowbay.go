@@ -19,8 +19,8 @@ func SwallowBay(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out
 	siteID := "SwallowBay"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("swallowbay.com")
-	siteCollector := createCollector("swallowbay.com")
+	sceneCollector := createCollector(scraperID, "swallowbay.com")
+	siteCollector := createCollector(scraperID, "swallowbay.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -19,8 +19,8 @@ func TmwVRnet(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out ch
 	siteID := "TmwVRnet"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("tmwvrnet.com")
-	siteCollector := createCollector("tmwvrnet.com")
+	sceneCollector := createCollector(scraperID, "tmwvrnet.com")
+	siteCollector := createCollector(scraperID, "tmwvrnet.com")
 	siteCollector.MaxDepth = 5
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
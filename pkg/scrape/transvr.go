@@ -20,8 +20,8 @@ func TransVR(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out cha
 	allowedDomains := []string{"transvr.com", "www.transvr.com", "www.groobyod.com"}
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector(allowedDomains...)
-	siteCollector := createCollector(allowedDomains...)
+	sceneCollector := createCollector(scraperID, allowedDomains...)
+	siteCollector := createCollector(scraperID, allowedDomains...)
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -17,8 +17,8 @@ func VirtualPee(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out
 	siteID := "VirtualPee"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("virtualpee.com")
-	siteCollector := createCollector("virtualpee.com")
+	sceneCollector := createCollector(scraperID, "virtualpee.com")
+	siteCollector := createCollector(scraperID, "virtualpee.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
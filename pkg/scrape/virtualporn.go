@@ -20,8 +20,8 @@ func Project1ServiceAPI(wg *models.ScrapeWG, updateSite bool, knownScenes []stri
 
 	logScrapeStart(siteData.scraperID, siteData.siteID)
 	nextApiUrl := ""
-	siteCollector := createCollector(siteData.baseURL)
-	apiCollector := createCollector("site-api.project1service.com")
+	siteCollector := createCollector(siteData.scraperID, siteData.baseURL)
+	apiCollector := createCollector(siteData.scraperID, "site-api.project1service.com")
 	offset := 0
 	apiCollector.OnResponse(func(r *colly.Response) {
 		sceneListJson := gjson.ParseBytes(r.Body)
@@ -217,8 +217,8 @@ func init() {
 
 // one off conversion routine called by migrations.go
 func UpdateVirtualPornIds() {
-	collector := createCollector("virtualporn.com")
-	apiCollector := createCollector("site-api.project1service.com")
+	collector := createCollector("", "virtualporn.com")
+	apiCollector := createCollector("", "site-api.project1service.com")
 	offset := 0
 	sceneCnt := 0
 
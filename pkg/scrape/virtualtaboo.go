@@ -19,8 +19,8 @@ func VirtualTaboo(wg *models.ScrapeWG, updateSite bool, knownScenes []string, ou
 	siteID := "VirtualTaboo"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("virtualtaboo.com")
-	siteCollector := createCollector("virtualtaboo.com")
+	sceneCollector := createCollector(scraperID, "virtualtaboo.com")
+	siteCollector := createCollector(scraperID, "virtualtaboo.com")
 
 	durationRegEx := regexp.MustCompile(`(?:(\d+) hour(?:s)? )?(\d+) min`)
 	filenameRegEx := regexp.MustCompile(`^(.*)-vt\w+$`)
@@ -23,8 +23,8 @@ func VRAllure(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out ch
 	siteID := "VRAllure"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("vrallure.com")
-	siteCollector := createCollector("vrallure.com")
+	sceneCollector := createCollector(scraperID, "vrallure.com")
+	siteCollector := createCollector(scraperID, "vrallure.com")
 
 	// Regex for original resolution of gallery
 	reGetOriginal := regexp.MustCompile(`^(https?:\/\/b8h6h9v9\.ssl\.hwcdn\.net\/vra\/)(?:largethumbs|hugethumbs|rollover_large|rollover_huge)(\/.+)-c\d{3,4}x\d{3,4}(\.\w{3,4})$`)
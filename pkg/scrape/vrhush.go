@@ -21,8 +21,8 @@ func VRHush(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 	siteID := "VRHush"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("vrhush.com")
-	siteCollector := createCollector("vrhush.com")
+	sceneCollector := createCollector(scraperID, "vrhush.com")
+	siteCollector := createCollector(scraperID, "vrhush.com")
 	pageCnt := 1
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
@@ -19,8 +19,8 @@ func VRLatina(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out ch
 	siteID := "VRLatina"
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("vrlatina.com")
-	siteCollector := createCollector("vrlatina.com")
+	sceneCollector := createCollector(scraperID, "vrlatina.com")
+	siteCollector := createCollector(scraperID, "vrlatina.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := models.ScrapedScene{}
@@ -33,8 +33,8 @@ func VRPHub(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("vrphub.com")
-	siteCollector := createCollector("vrphub.com")
+	sceneCollector := createCollector(scraperID, "vrphub.com")
+	siteCollector := createCollector(scraperID, "vrphub.com")
 
 	sceneCollector.OnHTML(`html`, func(e *colly.HTMLElement) {
 		sc := e.Request.Ctx.GetAny("scene").(*models.ScrapedScene)
@@ -279,7 +279,7 @@ func addVRPHubScraper(id string, name string, company string, avatarURL string,
 	})
 }
 
-func init() {
+func registerVRPHubScrapers() {
 	registerScraper("vrphub-single_scene", "VRPHub - Other Studios", "", "vrphub.com", func(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, limitScraping bool) error {
 		return VRPHub(wg, updateSite, knownScenes, out, singleSceneURL, "", "", "", "", singeScrapeAdditionalInfo, limitScraping, noop)
 	})
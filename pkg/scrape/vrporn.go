@@ -21,7 +21,7 @@ func VRPorn(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	apiCollector := createCollector("vrporn.com")
+	apiCollector := createCollector(scraperID, "vrporn.com")
 
 	page := 1
 	apiCollector.OnResponse(func(r *colly.Response) {
@@ -51,13 +51,13 @@ func VRPorn(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan
 
 				site.GetIfExist(studioId)
 				if site.Name != "" {
-					log.Info("no vrporn scraper id using database %s", site.Name)
+					log.Infof("no vrporn scraper id using database %s", site.Name)
 					// the user has setup a custom site, use the name they specified
 					sc.Studio = site.Name
 				} else {
 					// the user has not setup a custom site, use the name from the api
 					sc.Studio = scene.Get("studio.name").String()
-					log.Info("no vrporn scraper id using api %s", sc.Studio)
+					log.Infof("no vrporn scraper id using api %s", sc.Studio)
 				}
 			}
 
@@ -190,7 +190,7 @@ func addVRPornScraper(id string, name string, company string, avatarURL string,
 	}
 }
 
-func init() {
+func registerVRPornScrapers() {
 	registerScraper("vrporn-single_scene", "VRPorn - Other Studios", "", "vrporn.com", func(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, limitScraping bool) error {
 		return VRPorn(wg, updateSite, knownScenes, out, singleSceneURL, "", "", "", "", singeScrapeAdditionalInfo, limitScraping, "")
 	})
@@ -33,8 +33,8 @@ func VRSpy(wg *models.ScrapeWG, updateSite bool, knownScenes []string, out chan<
 	scrapeSuccessful := false
 
 	allowedDomains := []string{domain, "www." + domain}
-	sceneCollector := createCollector(allowedDomains...)
-	siteCollector := createCollector(allowedDomains...)
+	sceneCollector := createCollector(scraperID, allowedDomains...)
+	siteCollector := createCollector(scraperID, allowedDomains...)
 
 	cookies := []*http.Cookie{
 		{
@@ -17,8 +17,8 @@ func TwoWebMediaSite(wg *models.ScrapeWG, updateSite bool, knownScenes []string,
 	defer wg.Done()
 	logScrapeStart(scraperID, siteID)
 
-	sceneCollector := createCollector("wankitnowvr.com", "zexyvr.com")
-	siteCollector := createCollector("wankitnowvr.com", "zexyvr.com")
+	sceneCollector := createCollector(scraperID, "wankitnowvr.com", "zexyvr.com")
+	siteCollector := createCollector(scraperID, "wankitnowvr.com", "zexyvr.com")
 
 	// Regex preparation
 	reDateDuration := regexp.MustCompile(`Released\son\s(.*)\n+\s+Duration\s+:\s+(\d+):\d+`)
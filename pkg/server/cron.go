@@ -18,6 +18,7 @@ var previewTask cron.EntryID
 var actorScrapeTask cron.EntryID
 var stashdbScrapeTask cron.EntryID
 var linkScenesTask cron.EntryID
+var mobileTask cron.EntryID
 
 func SetupCron() {
 	cronInstance = cron.New()
@@ -48,6 +49,10 @@ func SetupCron() {
 		log.Println(fmt.Sprintf("Setup Link Scenes Task %v", formatCronSchedule(config.CronSchedule(config.Config.Cron.LinkScenesSchedule))))
 		linkScenesTask, _ = cronInstance.AddFunc(formatCronSchedule(config.CronSchedule(config.Config.Cron.LinkScenesSchedule)), linkScenesCron)
 	}
+	if config.Config.Cron.MobileSchedule.Enabled {
+		log.Println(fmt.Sprintf("Setup Mobile Rendition Task %v", formatCronSchedule(config.CronSchedule(config.Config.Cron.MobileSchedule))))
+		mobileTask, _ = cronInstance.AddFunc(formatCronSchedule(config.CronSchedule(config.Config.Cron.MobileSchedule)), generateMobileCron)
+	}
 	cronInstance.Start()
 
 	go tasks.CalculateCacheSizes()
@@ -70,6 +75,9 @@ func SetupCron() {
 	if config.Config.Cron.LinkScenesSchedule.RunAtStartDelay > 0 {
 		time.AfterFunc(time.Duration(config.Config.Cron.LinkScenesSchedule.RunAtStartDelay)*time.Minute, linkScenesCron)
 	}
+	if config.Config.Cron.MobileSchedule.RunAtStartDelay > 0 {
+		time.AfterFunc(time.Duration(config.Config.Cron.MobileSchedule.RunAtStartDelay)*time.Minute, generateMobileCron)
+	}
 
 	log.Println(fmt.Sprintf("Next Rescrape Task at %v", cronInstance.Entry(rescrapTask).Next))
 	log.Println(fmt.Sprintf("Next Rescan Task at %v", cronInstance.Entry(rescanTask).Next))
@@ -77,6 +85,7 @@ func SetupCron() {
 	log.Println(fmt.Sprintf("Next Actor Rescripe Task at %v", cronInstance.Entry(actorScrapeTask).Next))
 	log.Println(fmt.Sprintf("Next Stashdb Rescrape Task at %v", cronInstance.Entry(stashdbScrapeTask).Next))
 	log.Println(fmt.Sprintf("Next Link Scenes Task at %v", cronInstance.Entry(linkScenesTask).Next))
+	log.Println(fmt.Sprintf("Next Mobile Rendition Task at %v", cronInstance.Entry(mobileTask).Next))
 }
 
 func scrapeCron() {
@@ -88,7 +97,7 @@ func scrapeCron() {
 
 func rescanCron() {
 	if !session.HasActiveSession() {
-		tasks.RescanVolumes(-1)
+		tasks.RescanUnwatchedVolumes()
 	}
 	log.Println(fmt.Sprintf("Next Rescan Task at %v", cronInstance.Entry(rescanTask).Next))
 }
@@ -131,6 +140,27 @@ func generatePreviewCron() {
 	}
 	log.Println(fmt.Sprintf("Next Preview Generation Task at %v", cronInstance.Entry(previewTask).Next))
 }
+
+var mobileGenerateInProgress = false
+
+func generateMobileCron() {
+	if !session.HasActiveSession() && !mobileGenerateInProgress {
+		mobileGenerateInProgress = true
+		defer func() {
+			mobileGenerateInProgress = false
+		}()
+
+		if !config.Config.Cron.MobileSchedule.UseRange {
+			tasks.GenerateMobileRenditions(nil)
+		} else {
+			endTime := calcEndTime(config.Config.Cron.MobileSchedule.HourStart, config.Config.Cron.MobileSchedule.HourEnd, config.Config.Cron.MobileSchedule.MinuteStart)
+			log.Infof("Mobile Rendition Generation will stop at %v", endTime)
+			tasks.GenerateMobileRenditions(&endTime)
+		}
+	}
+	log.Println(fmt.Sprintf("Next Mobile Rendition Task at %v", cronInstance.Entry(mobileTask).Next))
+}
+
 func formatCronSchedule(schedule config.CronSchedule) string {
 	// 	this routine will format a crontab range description, https://crontab.guru is a good tool to decode the range description generated
 	// 	if the start hour > end hour then the time range will extend across midnight into the next day
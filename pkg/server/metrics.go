@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/session"
+)
+
+// libraryCollector reads the library counts from the database whenever Prometheus scrapes
+type libraryCollector struct {
+	scenes         *prometheus.Desc
+	files          *prometheus.Desc
+	unmatchedFiles *prometheus.Desc
+	size           *prometheus.Desc
+	sessions       *prometheus.Desc
+}
+
+func newLibraryCollector() *libraryCollector {
+	return &libraryCollector{
+		scenes:         prometheus.NewDesc("xbvr_library_scenes", "Scenes by state: all, available, scripted and hidden.", []string{"state"}, nil),
+		files:          prometheus.NewDesc("xbvr_library_files", "Files per volume and type.", []string{"volume", "type"}, nil),
+		unmatchedFiles: prometheus.NewDesc("xbvr_library_unmatched_files", "Files per volume not matched to a scene.", []string{"volume"}, nil),
+		size:           prometheus.NewDesc("xbvr_library_size_bytes", "Total size of the files per volume.", []string{"volume"}, nil),
+		sessions:       prometheus.NewDesc("xbvr_playback_sessions_active", "Watch sessions in progress.", nil, nil),
+	}
+}
+
+func (c *libraryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.scenes
+	ch <- c.files
+	ch <- c.unmatchedFiles
+	ch <- c.size
+	ch <- c.sessions
+}
+
+func (c *libraryCollector) Collect(ch chan<- prometheus.Metric) {
+	db, _ := models.GetCommonDB()
+
+	var scenes struct {
+		Total     int
+		Available int
+		Scripted  int
+		Hidden    int
+	}
+	db.Raw(`select count(*) as total,
+		coalesce(sum(case when is_available then 1 else 0 end), 0) as available,
+		coalesce(sum(case when is_scripted then 1 else 0 end), 0) as scripted,
+		coalesce(sum(case when is_hidden then 1 else 0 end), 0) as hidden
+		from scenes where deleted_at is null`).Scan(&scenes)
+	ch <- prometheus.MustNewConstMetric(c.scenes, prometheus.GaugeValue, float64(scenes.Total), "all")
+	ch <- prometheus.MustNewConstMetric(c.scenes, prometheus.GaugeValue, float64(scenes.Available), "available")
+	ch <- prometheus.MustNewConstMetric(c.scenes, prometheus.GaugeValue, float64(scenes.Scripted), "scripted")
+	ch <- prometheus.MustNewConstMetric(c.scenes, prometheus.GaugeValue, float64(scenes.Hidden), "hidden")
+
+	var files []struct {
+		Path      string
+		Type      string
+		Count     int
+		Unmatched int
+		Size      int64
+	}
+	db.Raw(`select volumes.path, files.type, count(*) as count,
+		coalesce(sum(case when files.scene_id = 0 then 1 else 0 end), 0) as unmatched,
+		coalesce(sum(files.size), 0) as size
+		from files join volumes on files.volume_id = volumes.id
+		group by volumes.path, files.type`).Scan(&files)
+
+	unmatched := map[string]int{}
+	size := map[string]int64{}
+	for _, f := range files {
+		ch <- prometheus.MustNewConstMetric(c.files, prometheus.GaugeValue, float64(f.Count), f.Path, f.Type)
+		unmatched[f.Path] += f.Unmatched
+		size[f.Path] += f.Size
+	}
+	for path, count := range unmatched {
+		ch <- prometheus.MustNewConstMetric(c.unmatchedFiles, prometheus.GaugeValue, float64(count), path)
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(size[path]), path)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(len(session.ActiveSessions())))
+}
+
+// requests are grouped by the api they belong to, or by the first segment of the path for the
+// other handlers, anything else is "other"
+var metricsGroups = []string{"/ui", "/img", "/imghm", "/download", "/myfiles", "/ws", "/metrics"}
+
+// metricsHandler records the latency of the requests it passes on, by api group
+func metricsHandler(next http.Handler) http.Handler {
+	var handlers sync.Map
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := requestGroup(r.URL.Path)
+		h, ok := handlers.Load(group)
+		if !ok {
+			h, _ = handlers.LoadOrStore(group, promhttp.InstrumentHandlerDuration(
+				common.HTTPRequestDuration.MustCurryWith(prometheus.Labels{"group": group}), next))
+		}
+		h.(http.Handler).ServeHTTP(w, r)
+	})
+}
+
+func requestGroup(path string) string {
+	for _, g := range metricsGroups {
+		if path == g || strings.HasPrefix(path, g+"/") {
+			return strings.TrimPrefix(g, "/")
+		}
+	}
+	return "other"
+}
+
+func registerMetrics() {
+	for _, ws := range restful.RegisteredWebServices() {
+		if ws.RootPath() != "/" {
+			metricsGroups = append(metricsGroups, ws.RootPath())
+		}
+	}
+
+	commonDb, _ := models.GetCommonDB()
+	common.MetricsRegistry.MustRegister(
+		newLibraryCollector(),
+		collectors.NewDBStatsCollector(commonDb.DB(), "common"),
+	)
+
+	authHandle("/metrics", common.IsUIAuthEnabled(), common.GetUISecret, promhttp.HandlerFor(common.MetricsRegistry, promhttp.HandlerOpts{}))
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
@@ -28,14 +29,14 @@ import (
 	"github.com/xbapps/xbvr/pkg/config"
 	"github.com/xbapps/xbvr/pkg/migrations"
 	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/scrape"
 	"github.com/xbapps/xbvr/pkg/session"
 	"github.com/xbapps/xbvr/pkg/tasks"
 	"github.com/xbapps/xbvr/ui"
 )
 
 var (
-	wsAddr = common.WsAddr
-	log    = &common.Log
+	log = &common.Log
 )
 
 func authHandle(pattern string, authEnabled bool, authSecret auth.SecretProvider, handler http.Handler) {
@@ -52,6 +53,10 @@ func authHandle(pattern string, authEnabled bool, authSecret auth.SecretProvider
 func StartServer(version, commit, branch, date string) {
 	common.CurrentVersion = version
 
+	flag.Parse()
+	models.Init()
+	scrape.Init()
+
 	config.LoadConfig()
 	common.CopyXbvrData()
 
@@ -91,7 +96,10 @@ func StartServer(version, commit, branch, date string) {
 	restful.Add(api.FilesResource{}.WebService())
 	restful.Add(api.DeoVRResource{}.WebService())
 	restful.Add(api.HeresphereResource{}.WebService())
+	restful.Add(api.RemoteResource{}.WebService())
 	restful.Add(api.PlaylistResource{}.WebService())
+	restful.Add(api.UserResource{}.WebService())
+	restful.Add(api.TagRuleResource{}.WebService())
 	restful.Add(api.AkaResource{}.WebService())
 	restful.Add(api.TagGroupResource{}.WebService())
 	restful.Add(api.ExternalReference{}.WebService())
@@ -136,6 +144,9 @@ func StartServer(version, commit, branch, date string) {
 	}
 	restful.Add(restfulspec.NewOpenAPIService(restConfig))
 
+	// Prometheus
+	registerMetrics()
+
 	// Static files
 	authHandle("/ui/", common.IsUIAuthEnabled(), common.GetUISecret, http.FileServer(ui.GetFileSystem(common.EnvConfig.Debug)))
 
@@ -160,7 +171,7 @@ func StartServer(version, commit, branch, date string) {
 	r.PathPrefix("/").Handler(http.DefaultServeMux)
 
 	// CORS
-	handler := cors.Default().Handler(r)
+	handler := metricsHandler(cors.Default().Handler(r))
 
 	// WAMP router
 	routerConfig := &router.Config{
@@ -183,14 +194,14 @@ func StartServer(version, commit, branch, date string) {
 	// Run websocket server.
 	wss := router.NewWebsocketServer(wampRouter)
 	wss.AllowOrigins([]string{"*"})
-	wsCloser, err := wss.ListenAndServe(wsAddr)
+	wsCloser, err := wss.ListenAndServe(common.WsAddr)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer wsCloser.Close()
 
 	// Proxy websocket
-	wsURL, err := url.Parse("ws://" + wsAddr)
+	wsURL, err := url.Parse("ws://" + common.WsAddr)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+// HeresphereEvent is a playback event HereSphere posts to the eventServer of a video
+type HeresphereEvent struct {
+	Username      string  `json:"username"`
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	Event         int     `json:"event"`
+	Time          float64 `json:"time"` // milliseconds
+	Speed         float64 `json:"speed"`
+	UTC           float64 `json:"utc"`
+	ConnectionKey string  `json:"connectionKey"`
+}
+
+const (
+	HeresphereOpen  = 0
+	HerespherePlay  = 1
+	HerespherePause = 2
+	HeresphereClose = 3
+)
+
+// HereSphere only reports changes of the playback state, paused sessions are kept this long
+const heresphereIdleTimeout = 30 * 60
+
+// TrackSessionFromHeresphere applies a playback event of a client playing file f of a scene. Seeks
+// are reported as play or pause events at the new position.
+func TrackSessionFromHeresphere(client string, userID uint, f models.File, ev HeresphereEvent) {
+	if f.SceneID == 0 {
+		return
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	s := sessions[client]
+	if s == nil || s.SceneID != f.SceneID {
+		if ev.Event == HeresphereClose {
+			return
+		}
+		playerUsers[strings.SplitN(client, " ", 2)[0]] = userID
+		s = newWatchSession(client, "heresphere", f.SceneID)
+		if s == nil {
+			return
+		}
+	}
+	// events are more precise than the file requests the session may have started with
+	s.Source = "heresphere"
+	if s.FileID != f.ID {
+		recordHeatmap(s)
+		s.FileID = f.ID
+		s.Duration = f.VideoDuration
+		s.savedPosition = 0
+		// the position is of the previous part, nothing was played in this one yet
+		s.IsPlaying = false
+		s.Position = 0
+	}
+
+	position := ev.Time / 1000
+	speed := ev.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	// the time played since the last event, longer jumps are seeks
+	if s.IsPlaying && position > s.Position && position-s.Position <= time.Since(s.End).Seconds()*speed+2 {
+		markPlayed(s, s.Position, position)
+	}
+
+	s.IsPlaying = ev.Event == HerespherePlay
+	s.Position = position
+	s.End = time.Now()
+	savePosition(s, false)
+
+	if ev.Event == HeresphereClose {
+		watchSessionFlush(s)
+	}
+}
+
+// StreamedFileID is the file a client last requested of a scene, 0 if it doesn't play the scene
+func StreamedFileID(client string, sceneID uint) uint {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	if s := sessions[client]; s != nil && s.SceneID == sceneID {
+		return s.FileID
+	}
+	return 0
+}
@@ -3,7 +3,9 @@ package session
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/xbapps/xbvr/pkg/common"
@@ -25,11 +27,99 @@ const FINISHED = 2
 var DeoPlayerHost = ""
 var DeoRequestHost = ""
 
+var deoConnected atomic.Bool
+
+// DeoCommand changes the playback of the connected DeoVR, fields left unset don't change
+type DeoCommand struct {
+	Path          *string  `json:"path,omitempty"`
+	CurrentTime   *float64 `json:"currentTime,omitempty"`
+	PlaybackSpeed *float64 `json:"playbackSpeed,omitempty"`
+	PlayerState   *int     `json:"playerState,omitempty"`
+}
+
+type queuedCommand struct {
+	ID      uint64
+	Command DeoCommand
+}
+
+var (
+	deoCommands  = make(chan queuedCommand, 16)
+	deoCommandID atomic.Uint64
+)
+
+// QueueDeoCommand queues a command for the connected DeoVR and returns its id, the outcome is
+// published on remote.command once it was sent
+func QueueDeoCommand(c DeoCommand) (uint64, error) {
+	if !deoConnected.Load() {
+		return 0, errors.New("DeoVR isn't connected")
+	}
+
+	q := queuedCommand{ID: deoCommandID.Add(1), Command: c}
+	select {
+	case deoCommands <- q:
+		return q.ID, nil
+	default:
+		return 0, errors.New("too many DeoVR commands queued")
+	}
+}
+
+// publishCommand sends the outcome of a command to the UI
+func publishCommand(c queuedCommand, err error) {
+	msg := map[string]interface{}{
+		"id":      c.ID,
+		"command": c.Command,
+		"sent":    err == nil,
+	}
+	if err != nil {
+		msg["error"] = err.Error()
+	}
+	go common.PublishWS("remote.command", msg)
+}
+
+// failQueuedCommands drops the commands queued for a connection that was lost
+func failQueuedCommands() {
+	for {
+		select {
+		case c := <-deoCommands:
+			publishCommand(c, errors.New("DeoVR disconnected"))
+		default:
+			return
+		}
+	}
+}
+
+// publishState sends the state of the DeoVR remote and the list of sessions in progress to the UI
+func publishState() {
+	active := ActiveSessions()
+	state := map[string]interface{}{
+		"connected": deoConnected.Load(),
+		"sessions":  active,
+	}
+	if deoConnected.Load() {
+		state["deovrHost"] = DeoPlayerHost
+		for _, s := range active {
+			if s.Client == deoRemoteClient() {
+				state["isPlaying"] = s.IsPlaying
+				state["currentPosition"] = s.Position
+				state["sessionStart"] = s.Start
+				state["sessionEnd"] = s.End
+				state["currentFileID"] = s.FileID
+				state["currentSceneID"] = s.SceneID
+			}
+		}
+	}
+	common.PublishWS("remote.state", state)
+}
+
+func deoRemoteClient() string {
+	return ClientKey(DeoPlayerHost, "DeoVR remote")
+}
+
 func DeoRemote() {
 	for {
-		go common.PublishWS("remote.state", map[string]interface{}{
-			"connected": false,
-		})
+		deoConnected.Store(false)
+		failQueuedCommands()
+		go publishState()
 
 		err := deoLoop()
 		if err != nil {
@@ -50,6 +140,7 @@ func deoLoop() error {
 	}
 
 	common.Log.Info("Connected to DeoVR")
+	deoConnected.Store(true)
 
 	for {
 		// Read
@@ -75,7 +166,7 @@ func deoLoop() error {
 			}
 
 			packet := decodePacket(recvBuf)
-			go TrackSessionFromRemote(packet)
+			go TrackSessionFromRemote(deoRemoteClient(), packet)
 		}
 
 		// Write
@@ -85,26 +176,26 @@ func deoLoop() error {
 		}
 
 		// Check if there's command queued, otherwise send ping packet
-		packet := encodePacket(DeoPacket{})
-		_, err = conn.Write(packet)
-		if err != nil {
-			return err
+		select {
+		case c := <-deoCommands:
+			_, err = conn.Write(encodePacket(c.Command))
+			if err != nil {
+				publishCommand(c, err)
+				return err
+			}
+			publishCommand(c, nil)
+		default:
+			_, err = conn.Write(encodePacket(DeoPacket{}))
+			if err != nil {
+				return err
+			}
 		}
 
-		go common.PublishWS("remote.state", map[string]interface{}{
-			"connected":       true,
-			"deovrHost":       DeoPlayerHost,
-			"isPlaying":       isPlaying,
-			"currentPosition": currentPosition,
-			"sessionStart":    lastSessionStart,
-			"sessionEnd":      lastSessionEnd,
-			"currentFileID":   currentFileID,
-			"currentSceneID":  currentSceneID,
-		})
+		go publishState()
 	}
 }
 
-func encodePacket(packet DeoPacket) []byte {
+func encodePacket(packet interface{}) []byte {
 	data, _ := json.Marshal(packet)
 	header := make([]byte, 4)
 	binary.LittleEndian.PutUint32(header, uint32(len(data)))
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/config"
+)
+
+func TestDeoCommandQueue(t *testing.T) {
+	if _, err := QueueDeoCommand(DeoCommand{}); err == nil {
+		t.Fatal("command queued without a connected DeoVR")
+	}
+
+	// DeoVR listens on a fixed port
+	l, err := net.Listen("tcp", "127.0.0.1:23554")
+	if err != nil {
+		t.Skipf("remote port unavailable: %v", err)
+	}
+	defer l.Close()
+
+	DeoPlayerHost = "127.0.0.1"
+	config.Config.Interfaces.DeoVR.RemoteEnabled = true
+	defer func() { config.Config.Interfaces.DeoVR.RemoteEnabled = false }()
+
+	received := make(chan map[string]interface{}, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			// an empty status packet, then read what xbvr sends back
+			if _, err := conn.Write(make([]byte, 4)); err != nil {
+				return
+			}
+			header := make([]byte, 4)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, binary.LittleEndian.Uint32(header))
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			var packet map[string]interface{}
+			json.Unmarshal(body, &packet)
+			if len(packet) > 0 {
+				received <- packet
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	go deoLoop()
+
+	for start := time.Now(); !deoConnected.Load(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("remote didn't connect")
+		}
+	}
+
+	state := PLAYING
+	position := 0.0
+	if _, err := QueueDeoCommand(DeoCommand{PlayerState: &state, CurrentTime: &position}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case packet := <-received:
+		// playing and the start of the video are zero values, they must still be sent
+		if packet["playerState"] != 0.0 || packet["currentTime"] != 0.0 || len(packet) != 2 {
+			t.Errorf("packet %v, want play from the start", packet)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("command wasn't sent")
+	}
+}
@@ -1,205 +1,342 @@
 package session
 
 import (
-	"encoding/json"
-	"fmt"
+	"math"
+	"net"
 	"net/url"
-	"os"
-	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/engagement"
 	"github.com/xbapps/xbvr/pkg/models"
 )
 
+// Session is the playback of a scene by one client. Each client, a player on a device, has at most
+// one session, which is stored as a models.History record once it starts.
+type Session struct {
+	Client    string    `json:"client"`
+	Source    string    `json:"source"`
+	UserID    uint      `json:"user_id"`
+	HistoryID uint      `json:"history_id"`
+	SceneID   uint      `json:"scene_id"`
+	FileID    uint      `json:"file_id"`
+	IsPlaying bool      `json:"is_playing"`
+	Position  float64   `json:"position"`
+	Duration  float64   `json:"duration"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+
+	heatmap       []int
+	savedPosition float64
+
+	// file sessions only learn where playback continues from when the player seeks
+	playedFrom float64
+	playedAt   time.Time
+}
+
+// positions are stored once they moved this many seconds from the last stored one
+const positionSaveInterval = 10
+
 var (
-	sessionSource      string
-	isPlaying          bool
-	currentPosition    float64
-	currentFileID      int
-	currentSceneID     uint
-	lastSessionID      uint
-	lastSessionSceneID uint
-	lastSessionStart   time.Time
-	lastSessionEnd     time.Time
+	sessionsMu sync.Mutex
+	sessions   = map[string]*Session{}
+
+	// profiles players logged in with, by ip address
+	playerUsers = map[string]uint{}
 )
 
-var currentSessionHeatmap []int
+// PlayerUserID is the profile the player last logged in with, watch sessions of clients that
+// didn't log in themselves are recorded for it
+var PlayerUserID uint = models.DefaultUserID
+
+// ClientKey identifies a client by the ip address and user agent of its requests
+func ClientKey(remoteAddr string, userAgent string) string {
+	return clientIP(remoteAddr) + " " + userAgent
+}
+
+func clientIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// SetPlayerUser records the profile a player logged in with, for the sessions of its address
+func SetPlayerUser(remoteAddr string, userID uint) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	playerUsers[clientIP(remoteAddr)] = userID
+	PlayerUserID = userID
+}
 
 func HasActiveSession() bool {
-	return lastSessionID != 0
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	return len(sessions) > 0
 }
 
-func TrackSessionFromFile(f models.File, doNotTrack string) {
-	sessionSource = "file"
+// ActiveSessions returns a copy of the sessions in progress, oldest first
+func ActiveSessions() []Session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
 
-	if f.SceneID != 0 && doNotTrack != "true" {
-		if lastSessionSceneID != f.SceneID {
-			newWatchSession(f.SceneID)
-		}
+	return activeSessions()
+}
 
-		lastSessionEnd = time.Now()
+func activeSessions() []Session {
+	out := []Session{}
+	for _, s := range sessions {
+		c := *s
+		c.heatmap = nil
+		out = append(out, c)
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
 }
 
-func FinishTrackingFromFile(doNotTrack string) {
-	lastSessionEnd = time.Now()
-	if doNotTrack != "true" {
-		watchSessionFlush()
+// TrackSessionFromFile tracks a client streaming a file, offset is the start of the requested byte
+// range. Players request a new range whenever they seek, so it locates the playback position.
+func TrackSessionFromFile(client string, f models.File, offset int64, doNotTrack string) {
+	if f.SceneID == 0 || doNotTrack == "true" {
+		return
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	s := sessions[client]
+	if s == nil || s.SceneID != f.SceneID {
+		s = newWatchSession(client, "file", f.SceneID)
+		if s == nil {
+			return
+		}
+	}
+	if s.FileID != f.ID {
+		recordHeatmap(s)
+		s.FileID = f.ID
+		s.Duration = f.VideoDuration
+		s.savedPosition = 0
+		s.playedAt = time.Time{}
+	}
+	s.End = time.Now()
+
+	// the start and end of a file are read for its headers and index, not for playback
+	if f.Size > 0 && f.VideoDuration > 0 && offset > f.Size/100 && offset < f.Size/100*98 {
+		s.Position = float64(offset) / float64(f.Size) * f.VideoDuration
+		savePosition(s, false)
+		markPlayedUntilNow(s)
+		s.playedFrom = s.Position
+		s.playedAt = s.End
+	} else if s.playedAt.IsZero() && offset <= f.Size/100 {
+		s.playedFrom = 0
+		s.playedAt = s.End
 	}
 }
 
-func TrackSessionFromRemote(packet DeoPacket) {
-	if packet.Path == "" || packet.Duration == 0 {
+func FinishTrackingFromFile(client string, doNotTrack string) {
+	if doNotTrack == "true" {
 		return
 	}
 
-	sessionSource = "deovr"
-	isPlaying = packet.PlayerState == PLAYING
-	currentPosition = packet.CurrentTime
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	if s := sessions[client]; s != nil {
+		s.End = time.Now()
+		watchSessionFlush(s)
+	}
+}
+
+func TrackSessionFromRemote(client string, packet DeoPacket) {
+	if packet.Path == "" || packet.Duration == 0 {
+		return
+	}
 
 	tmpPath, err := url.Parse(packet.Path)
 	if err != nil {
 		return
 	}
 	tmp := strings.Split(tmpPath.Path, "/")
-	tmpCurrentFileID, err := strconv.Atoi(tmp[len(tmp)-1])
+	fileID, err := strconv.Atoi(tmp[len(tmp)-1])
 	if err != nil {
 		return
 	}
 
-	// Currently playing file has changed
-	if tmpCurrentFileID != currentFileID {
-		// Get scene ID
-		currentFileID = tmpCurrentFileID
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	s := sessions[client]
 
+	// Currently playing file has changed
+	if s == nil || s.FileID != uint(fileID) {
 		f := models.File{}
 		db, _ := models.GetDB()
-		_ = db.First(&f, currentFileID).Error
-		defer db.Close()
+		_ = db.First(&f, fileID).Error
+		db.Close()
 
 		// Create new session
-		if lastSessionSceneID != f.SceneID {
-			newWatchSession(f.SceneID)
+		if s == nil || s.SceneID != f.SceneID {
+			s = newWatchSession(client, "deovr", f.SceneID)
+			if s == nil {
+				return
+			}
+		} else {
+			// the next part of a multipart scene
+			recordHeatmap(s)
 		}
-
-		currentSessionHeatmap = make([]int, int(packet.Duration))
+		s.FileID = uint(fileID)
+		s.heatmap = make([]int, int(packet.Duration))
+		s.savedPosition = 0
 	}
 
+	s.IsPlaying = packet.PlayerState == PLAYING
+	s.Position = packet.CurrentTime
+	s.Duration = packet.Duration
+	savePosition(s, false)
+
 	// Keep session alive if Deo is playing
-	if packet.PlayerState == PLAYING {
-		lastSessionEnd = time.Now()
+	if s.IsPlaying {
+		s.End = time.Now()
 
 		position := int(packet.CurrentTime)
-		if position > 0 && position < len(currentSessionHeatmap) {
-			currentSessionHeatmap[position] = currentSessionHeatmap[position] + 1
+		if position > 0 && position < len(s.heatmap) {
+			s.heatmap[position] = s.heatmap[position] + 1
 		}
 	}
 }
 
+// CheckForDeadSession ends the sessions of clients that stopped playing
 func CheckForDeadSession() {
-	var timeout float64
-	if sessionSource == "file" {
-		timeout = 60
-	} else {
-		timeout = 5
-	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	for _, s := range sessions {
+		var timeout float64
+		switch s.Source {
+		case "file":
+			timeout = 60
+		case "heresphere":
+			// no events arrive while a video plays through
+			timeout = heresphereIdleTimeout
+			if s.IsPlaying {
+				timeout = s.Duration - s.Position + 60
+			}
+		default:
+			timeout = 5
+		}
 
-	if time.Since(lastSessionEnd).Seconds() > timeout && lastSessionSceneID != 0 && HasActiveSession() {
-		watchSessionFlush()
-		lastSessionID = 0
-		lastSessionSceneID = 0
+		if time.Since(s.End).Seconds() > timeout {
+			watchSessionFlush(s)
+		}
 	}
 }
 
-func newWatchSession(sceneID uint) {
-	if HasActiveSession() {
-		watchSessionFlush()
+// newWatchSession ends the current session of the client and starts a new one, the caller holds sessionsMu
+func newWatchSession(client string, source string, sceneID uint) *Session {
+	if s := sessions[client]; s != nil {
+		watchSessionFlush(s)
 	}
 
-	lastSessionSceneID = sceneID
-	lastSessionStart = time.Now()
-
-	obj := models.History{SceneID: sceneID, TimeStart: lastSessionStart}
-	obj.Save()
+	userID, ok := playerUsers[strings.SplitN(client, " ", 2)[0]]
+	if !ok {
+		userID = PlayerUserID
+	}
 
 	var scene models.Scene
 	err := scene.GetIfExistByPK(sceneID)
-	if err == nil {
-		scene.LastOpened = time.Now()
-		scene.Save()
-	} else {
-		return
+	if err != nil {
+		return nil
 	}
+	scene.LastOpened = time.Now()
+	scene.Save()
 
-	lastSessionID = obj.ID
-	currentSceneID = scene.ID
+	s := &Session{Client: client, Source: source, UserID: userID, SceneID: sceneID, Start: time.Now()}
+	s.End = s.Start
 
-	common.Log.Infof("New session #%v for scene #%v from %v", lastSessionID, lastSessionSceneID, sessionSource)
+	obj := models.History{UserID: userID, SceneID: sceneID, TimeStart: s.Start}
+	obj.Save()
+	s.HistoryID = obj.ID
+
+	sessions[client] = s
+	common.Log.Infof("New session #%v for scene #%v from %v (%v)", s.HistoryID, s.SceneID, source, client)
+	go publishState()
+	return s
 }
 
-func watchSessionFlush() {
-	var obj models.History
-	err := obj.GetIfExist(lastSessionID)
-	if err == nil {
-		obj.TimeEnd = lastSessionEnd
-		obj.Duration = time.Since(lastSessionStart).Seconds()
-		obj.Save()
-
-		var scene models.Scene
-		err := scene.GetIfExistByPK(lastSessionSceneID)
-		if err == nil {
-			if !scene.IsWatched {
-				scene.IsWatched = true
-				scene.Save()
-			}
-		}
+// watchSessionFlush stores the end of the session and removes it, the caller holds sessionsMu
+func watchSessionFlush(s *Session) {
+	delete(sessions, s.Client)
+	go publishState()
 
-		common.Log.Infof("Session #%v duration for scene #%v is %v", lastSessionID, lastSessionSceneID, time.Since(lastSessionStart).Seconds())
+	savePosition(s, true)
+	markPlayedUntilNow(s)
+	recordHeatmap(s)
 
-		// Dump heatmap
-		// TODO: handle multipart scenes
-		if !scene.IsMultipart && sessionSource == "deovr" {
-			err = dumpHeatmap(lastSessionSceneID, currentSessionHeatmap)
-			if err != nil {
-				common.Log.Error("Error while writing heatmap data", err)
-			}
-		}
+	var obj models.History
+	err := obj.GetIfExist(s.HistoryID)
+	if err != nil {
+		return
 	}
+	obj.TimeEnd = s.End
+	obj.Duration = time.Since(s.Start).Seconds()
+	obj.Save()
 
-	currentFileID = 0
-	currentSceneID = 0
-	lastSessionID = 0
-	lastSessionSceneID = 0
+	var scene models.Scene
+	err = scene.GetIfExistByPK(s.SceneID)
+	if err == nil {
+		scene.ApplyUserState(s.UserID)
+		scene.IsWatched = true
+		scene.TotalWatchTime = scene.GetTotalWatchTime(s.UserID)
+		scene.SaveUserState(s.UserID)
+	}
+
+	common.Log.Infof("Session #%v duration for scene #%v is %v", s.HistoryID, s.SceneID, time.Since(s.Start).Seconds())
 }
 
-func dumpHeatmap(sceneID uint, data []int) error {
-	path := path.Join(common.HeatmapDir, fmt.Sprintf("%v.json", sceneID))
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// Create new heatmap
-		dataOut, _ := json.Marshal(data)
-		os.WriteFile(path, dataOut, 0644)
-	} else {
-		// Update existing heatmap
-		b, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
+// savePosition stores the playback position of the session, the caller holds sessionsMu
+func savePosition(s *Session, force bool) {
+	if s.Position <= 0 || s.Position == s.savedPosition {
+		return
+	}
+	if !force && math.Abs(s.Position-s.savedPosition) < positionSaveInterval {
+		return
+	}
+	if err := models.SavePlaybackPosition(s.UserID, s.SceneID, s.FileID, s.Position, s.Duration); err != nil {
+		common.Log.Error("Error while saving playback position", err)
+		return
+	}
+	s.savedPosition = s.Position
+}
 
-		tmpHeatmap := make([]int, len(data))
-		err = json.Unmarshal(b, &tmpHeatmap)
-		if err != nil {
-			return err
-		}
+// markPlayedUntilNow counts the seconds a file session played since the last seek, assuming it
+// played through
+func markPlayedUntilNow(s *Session) {
+	if s.Source != "file" || s.playedAt.IsZero() {
+		return
+	}
+	markPlayed(s, s.playedFrom, s.playedFrom+time.Since(s.playedAt).Seconds())
+}
 
-		for k, v := range tmpHeatmap {
-			data[k] = data[k] + v
-		}
+// markPlayed counts a view of the seconds after from up to to in the file of the session
+func markPlayed(s *Session, from float64, to float64) {
+	if s.heatmap == nil && s.Duration > 0 {
+		s.heatmap = make([]int, int(s.Duration))
+	}
+	for i := int(from) + 1; i <= int(to) && i < len(s.heatmap); i++ {
+		s.heatmap[i] = s.heatmap[i] + 1
+	}
+}
 
-		dataOut, _ := json.Marshal(data)
-		os.WriteFile(path, dataOut, 0644)
+// recordHeatmap adds the seconds the session watched to the heatmap of its file, the caller holds sessionsMu
+func recordHeatmap(s *Session) {
+	if err := engagement.Record(s.FileID, s.Duration, s.heatmap); err != nil {
+		common.Log.Error("Error while writing heatmap data", err)
 	}
-	return nil
+	s.heatmap = nil
 }
@@ -0,0 +1,220 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/engagement"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+func TestMain(m *testing.M) {
+	appDir, _ := os.MkdirTemp("", "xbvr-test")
+	os.Setenv("XBVR_APPDIR", appDir)
+	models.Init()
+
+	// the tables sessions are recorded in
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.Scene{}, &models.File{}, &models.History{}, &models.UserScene{}, &models.SceneCuepoint{}, &models.Tag{}, &models.Actor{}, &models.PlaybackPosition{})
+
+	code := m.Run()
+	os.RemoveAll(appDir)
+	os.Exit(code)
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	var files []models.File
+	for _, id := range []string{"session-a", "session-b"} {
+		scene := models.Scene{SceneID: id}
+		commonDb.Create(&scene)
+		file := models.File{SceneID: scene.ID, Type: "video", Filename: id + ".mp4"}
+		commonDb.Create(&file)
+		files = append(files, file)
+	}
+
+	clients := []string{ClientKey("10.0.0.1:5000", "Quest"), ClientKey("10.0.0.2:5000", "Pico")}
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				TrackSessionFromFile(clients[i], files[i], 0, "")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	active := ActiveSessions()
+	if len(active) != 2 {
+		t.Fatalf("%v sessions, want one per client", len(active))
+	}
+	for _, s := range active {
+		if (s.Client == clients[0]) != (s.SceneID == files[0].SceneID) {
+			t.Errorf("session of %v plays scene %v", s.Client, s.SceneID)
+		}
+	}
+
+	// sessions expire on their own
+	sessionsMu.Lock()
+	sessions[clients[0]].End = time.Now().Add(-time.Hour)
+	sessionsMu.Unlock()
+	CheckForDeadSession()
+	if active := ActiveSessions(); len(active) != 1 || active[0].Client != clients[1] {
+		t.Fatalf("sessions after expiry %+v, want only %v", active, clients[1])
+	}
+
+	FinishTrackingFromFile(clients[1], "")
+	if HasActiveSession() {
+		t.Error("session still active after finishing")
+	}
+
+	var history []models.History
+	commonDb.Where("scene_id in (?)", []uint{files[0].SceneID, files[1].SceneID}).Find(&history)
+	if len(history) != 2 {
+		t.Fatalf("%v history records, want one per session", len(history))
+	}
+	for _, h := range history {
+		if h.TimeEnd.IsZero() {
+			t.Errorf("history %v was not ended", h.ID)
+		}
+	}
+}
+
+func TestPlaybackPositionFromRange(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	scene := models.Scene{SceneID: "session-resume"}
+	commonDb.Create(&scene)
+	file := models.File{SceneID: scene.ID, Type: "video", Filename: "session-resume.mp4", Size: 1000, VideoDuration: 100}
+	commonDb.Create(&file)
+
+	client := ClientKey("10.0.0.3:5000", "Quest")
+	TrackSessionFromFile(client, file, 0, "")
+	TrackSessionFromFile(client, file, 400, "")
+	// the index at the end of the file isn't a seek
+	TrackSessionFromFile(client, file, 995, "")
+	FinishTrackingFromFile(client, "")
+
+	pos, ok := models.GetResumePosition(models.DefaultUserID, scene.ID)
+	if !ok || pos.Position != 40 || pos.FileID != file.ID {
+		t.Fatalf("resume position %+v, want 40s into file %v", pos, file.ID)
+	}
+
+	// playing to the end finishes the scene
+	models.SavePlaybackPosition(models.DefaultUserID, scene.ID, file.ID, 99, 100)
+	if _, ok := models.GetResumePosition(models.DefaultUserID, scene.ID); ok {
+		t.Error("finished scene still offered for resume")
+	}
+}
+
+func TestHeresphereEvents(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	scene := models.Scene{SceneID: "session-heresphere"}
+	commonDb.Create(&scene)
+	file := models.File{SceneID: scene.ID, Type: "video", Filename: "session-heresphere.mp4", VideoDuration: 60}
+	commonDb.Create(&file)
+
+	client := ClientKey("10.0.0.4:5000", "HereSphere")
+	TrackSessionFromHeresphere(client, models.DefaultUserID, file, HeresphereEvent{Event: HeresphereOpen})
+	TrackSessionFromHeresphere(client, models.DefaultUserID, file, HeresphereEvent{Event: HerespherePlay, Time: 5000, Speed: 1})
+
+	// twenty seconds played, then a seek forward
+	sessionsMu.Lock()
+	sessions[client].End = time.Now().Add(-20 * time.Second)
+	sessionsMu.Unlock()
+	TrackSessionFromHeresphere(client, models.DefaultUserID, file, HeresphereEvent{Event: HerespherePause, Time: 25000, Speed: 1})
+	TrackSessionFromHeresphere(client, models.DefaultUserID, file, HeresphereEvent{Event: HerespherePlay, Time: 50000, Speed: 1})
+
+	sessionsMu.Lock()
+	s := sessions[client]
+	if s == nil || s.Source != "heresphere" {
+		sessionsMu.Unlock()
+		t.Fatalf("session %+v, want one from heresphere", s)
+	}
+	if s.heatmap[10] != 1 || s.heatmap[40] != 0 {
+		t.Errorf("heatmap %v, want 6s to 25s watched", s.heatmap)
+	}
+	sessionsMu.Unlock()
+
+	TrackSessionFromHeresphere(client, models.DefaultUserID, file, HeresphereEvent{Event: HeresphereClose, Time: 52000})
+	if HasActiveSession() {
+		t.Fatal("session still active after close")
+	}
+
+	var history models.History
+	commonDb.Where("scene_id = ?", scene.ID).First(&history)
+	if history.TimeEnd.IsZero() {
+		t.Error("history was not ended")
+	}
+	if pos, ok := models.GetResumePosition(models.DefaultUserID, scene.ID); !ok || pos.Position != 52 {
+		t.Errorf("resume position %+v, want 52s", pos)
+	}
+}
+
+func TestMultipartHeatmaps(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	scene := models.Scene{SceneID: "session-multipart", IsMultipart: true}
+	commonDb.Create(&scene)
+	var files []models.File
+	for _, name := range []string{"part1.mp4", "part2.mp4"} {
+		file := models.File{SceneID: scene.ID, Type: "video", Filename: name, VideoDuration: 30}
+		commonDb.Create(&file)
+		files = append(files, file)
+	}
+
+	client := ClientKey("10.0.0.5:5000", "DeoVR remote")
+	for _, f := range files {
+		for pos := 1.0; pos < 10; pos++ {
+			TrackSessionFromRemote(client, DeoPacket{Path: fmt.Sprintf("http://xbvr/api/dms/file/%v", f.ID), Duration: 30, CurrentTime: pos, PlayerState: PLAYING})
+		}
+	}
+	sessionsMu.Lock()
+	watchSessionFlush(sessions[client])
+	sessionsMu.Unlock()
+
+	for _, f := range files {
+		h, _ := engagement.Load(f.ID)
+		if h.Sessions != 1 || h.Counts[5] != 1 {
+			t.Errorf("heatmap of %v %+v, want the seconds the part was watched", f.Filename, h)
+		}
+	}
+}
+
+func TestHeresphereMultipart(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	scene := models.Scene{SceneID: "session-heresphere-multipart", IsMultipart: true}
+	commonDb.Create(&scene)
+	var files []models.File
+	for _, name := range []string{"hs-part1.mp4", "hs-part2.mp4"} {
+		file := models.File{SceneID: scene.ID, Type: "video", Filename: name, VideoDuration: 60}
+		commonDb.Create(&file)
+		files = append(files, file)
+	}
+
+	client := ClientKey("10.0.0.6:5000", "HereSphere")
+	for _, f := range files {
+		TrackSessionFromHeresphere(client, models.DefaultUserID, f, HeresphereEvent{Event: HerespherePlay, Time: 5000, Speed: 1})
+		sessionsMu.Lock()
+		sessions[client].End = time.Now().Add(-20 * time.Second)
+		sessionsMu.Unlock()
+		TrackSessionFromHeresphere(client, models.DefaultUserID, f, HeresphereEvent{Event: HerespherePause, Time: 25000, Speed: 1})
+	}
+	if id := StreamedFileID(client, scene.ID); id != files[1].ID {
+		t.Errorf("streamed file %v, want the second part %v", id, files[1].ID)
+	}
+	TrackSessionFromHeresphere(client, models.DefaultUserID, files[1], HeresphereEvent{Event: HeresphereClose, Time: 25000})
+
+	for _, f := range files {
+		h, _ := engagement.Load(f.ID)
+		if h.Sessions != 1 || h.Counts[10] != 1 {
+			t.Errorf("heatmap of %v %+v, want the seconds the part was watched", f.Filename, h)
+		}
+	}
+	if pos, ok := models.GetResumePosition(models.DefaultUserID, scene.ID); !ok || pos.FileID != files[1].ID || pos.Position != 25 {
+		t.Errorf("resume position %+v, want 25s into the second part", pos)
+	}
+}
@@ -103,7 +103,7 @@ func CleanTags() {
 	CountTags()
 }
 
-func runScrapers(knownScenes []string, toScrape string, updateSite bool, collectedScenes chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string) error {
+func runScrapers(knownScenes []string, toScrape string, updateSite bool, collectedScenes chan<- models.ScrapedScene, singleSceneURL string, singeScrapeAdditionalInfo string, runs *scrapeRuns) error {
 	defer scrape.DeleteScrapeCache()
 
 	scrapers := models.GetScrapers()
@@ -129,10 +129,14 @@ func runScrapers(knownScenes []string, toScrape string, updateSite bool, collect
 			for _, scraper := range scrapers {
 				if site.ID == scraper.ID {
 					wg.Add(1)
+					runs.start(scraper)
 					go func(scraper models.Scraper) {
-						scraper.Scrape(&wg, updateSite, knownScenes, collectedScenes, singleSceneURL, singeScrapeAdditionalInfo, site.LimitScraping)
+						done := common.TimeTask("scrape", scraper.ID)
+						err := scraper.Scrape(&wg, updateSite, knownScenes, collectedScenes, singleSceneURL, singeScrapeAdditionalInfo, site.LimitScraping)
+						done(err)
+						runs.finish(scraper.ID, err)
 						var site models.Site
-						err := site.GetIfExist(scraper.ID)
+						err = site.GetIfExist(scraper.ID)
 						if err != nil {
 							log.Error(err)
 							return
@@ -170,7 +174,7 @@ func sceneSliceAppender(collectedScenes *[]models.ScrapedScene, scenes <-chan mo
 	}
 }
 
-func sceneDBWriter(wg *sync.WaitGroup, i *uint64, scenes <-chan models.ScrapedScene, processedScenes *[]models.ScrapedScene, lock *sync.Mutex) {
+func sceneDBWriter(wg *sync.WaitGroup, i *uint64, scenes <-chan models.ScrapedScene, processedScenes *[]models.ScrapedScene, lock *sync.Mutex, runs *scrapeRuns) {
 	defer wg.Done()
 
 	commonDb, _ := models.GetCommonDB()
@@ -184,8 +188,17 @@ func sceneDBWriter(wg *sync.WaitGroup, i *uint64, scenes <-chan models.ScrapedSc
 			}
 		} else {
 			if scene.MasterSiteId == "" {
+				if runs != nil {
+					var count int
+					commonDb.Model(&models.Scene{}).Where(&models.Scene{SceneID: scene.SceneID}).Count(&count)
+					runs.addScene(scene, count == 0)
+				}
 				models.SceneCreateUpdateFromExternal(commonDb, scene)
 			} else {
+				if runs != nil {
+					var extref models.ExternalReference
+					runs.addScene(scene, extref.FindExternalId("alternate scene "+scene.ScraperID, scene.SceneID) != nil)
+				}
 				AddAlternateSceneSource(commonDb, scene)
 			}
 		}
@@ -241,8 +254,7 @@ func ReapplyEdits() {
 			name := a.NewValue[1:]
 			// Reapply Tag edits
 			if a.ChangedColumn == "tags" {
-				tagClean := models.ConvertTag(name)
-				if tagClean != "" {
+				for _, tagClean := range models.ConvertTag(name) {
 					var tag models.Tag
 					db.Where(&models.Tag{Name: tagClean}).FirstOrCreate(&tag)
 					if prefix == "-" {
@@ -332,12 +344,18 @@ func Scrape(toScrape string, singleSceneURL string, singeScrapeAdditionalInfo st
 		var processedScenes []models.ScrapedScene
 		var processedScenesLock sync.Mutex
 
+		// Only full site runs are recorded, a single scene says nothing about the health of a scraper
+		var runs *scrapeRuns
+		if singleSceneURL == "" {
+			runs = newScrapeRuns()
+		}
+
 		var wg sync.WaitGroup
 		wg.Add(1)
-		go sceneDBWriter(&wg, &sceneCount, collectedScenes, &processedScenes, &processedScenesLock)
+		go sceneDBWriter(&wg, &sceneCount, collectedScenes, &processedScenes, &processedScenesLock, runs)
 
 		// Start scraping
-		if e := runScrapers(knownScenes, toScrape, true, collectedScenes, singleSceneURL, singeScrapeAdditionalInfo); e != nil {
+		if e := runScrapers(knownScenes, toScrape, true, collectedScenes, singleSceneURL, singeScrapeAdditionalInfo, runs); e != nil {
 			tlog.Info(e)
 		} else {
 			// Notify DB Writer threads that there are no more scenes
@@ -346,6 +364,8 @@ func Scrape(toScrape string, singleSceneURL string, singeScrapeAdditionalInfo st
 			// Wait for DB Writer threads to complete
 			wg.Wait()
 
+			runs.save()
+
 			// Send a signal to clean up the progress bars just in case
 			log.WithField("task", "scraperProgress").Info("DONE")
 
@@ -371,6 +391,10 @@ func Scrape(toScrape string, singleSceneURL string, singeScrapeAdditionalInfo st
 				MatchAlternateSources()
 			}
 
+			if config.Config.Storage.Sidecars.Enabled {
+				WriteSidecars(nil)
+			}
+
 			tlog.Infof("Scraped %v new scenes in %s",
 				sceneCount,
 				time.Since(t0).Round(time.Second))
@@ -454,7 +478,7 @@ func ScrapeTPDB(apiToken string, sceneUrl string) {
 		err := scrape.ScrapeTPDB(knownScenes, &collectedScenes, apiToken, sceneUrl)
 
 		if err != nil {
-			tlog.Errorf(err.Error())
+			tlog.Errorf("%v", err)
 		} else if len(collectedScenes) > 0 {
 			// At this point we know the API Token is correct, so we will save
 			// it to the config store
@@ -498,7 +522,7 @@ func ExportBundle() {
 		var scrapedScenes []models.ScrapedScene
 		go sceneSliceAppender(&scrapedScenes, collectedScenes)
 
-		runScrapers(knownScenes, "_enabled", false, collectedScenes, "", "")
+		runScrapers(knownScenes, "_enabled", false, collectedScenes, "", "", nil)
 
 		out := ContentBundle{
 			Timestamp:     time.Now().UTC(),
@@ -632,7 +656,8 @@ func BackupBundle(inclAllSites bool, onlyIncludeOfficalSites bool, inclScenes bo
 
 				err = db.Preload("Files").
 					Preload("Cuepoints").
-					Preload("History").
+					// bundles carry the personal state of the default profile
+					Preload("History", "user_id = ?", models.DefaultUserID).
 					// do not export tag groups  or they will load back as real tags not tag groups
 					Preload("Tags", "substr(name, 1, 10)<>'tag group:'").
 					// do not export aka actors or they will load back as real actors not aka groups
@@ -662,6 +687,7 @@ func BackupBundle(inclAllSites bool, onlyIncludeOfficalSites bool, inclScenes bo
 					backupFileLinkList = append(backupFileLinkList, BackupFileLink{SceneID: scene.SceneID, Files: scene.Files})
 				}
 				if inclScenes {
+					scene.ApplyUserState(models.DefaultUserID)
 					scene.Files = []models.File{}
 					scene.Cuepoints = []models.SceneCuepoint{}
 					scene.History = []models.History{}
@@ -743,6 +769,7 @@ func BackupBundle(inclAllSites bool, onlyIncludeOfficalSites bool, inclScenes bo
 		var actors []models.Actor
 		if inclActors {
 			db.Find(&actors)
+			models.ApplyUserActorState(db, models.DefaultUserID, actors)
 		}
 
 		var actionActors []models.ActionActor
@@ -969,11 +996,13 @@ func RestoreScenes(scenes []models.Scene, inclAllSites bool, selectedSites []mod
 		if found.ID == 0 { // id = 0 is a new record
 			scene.ID = 0 // dont use the id from json
 			models.SaveWithRetry(db, &scene)
+			scene.SaveUserState(models.DefaultUserID)
 			addedCnt++
 		} else {
 			if overwrite {
 				scene.ID = found.ID // use the Id from the existing db record
 				models.SaveWithRetry(db, &scene)
+				scene.SaveUserState(models.DefaultUserID)
 				addedCnt++
 			}
 		}
@@ -1097,20 +1126,21 @@ func RestoreHistory(sceneHistoryList []BackupSceneHistory, inclAllSites bool, se
 			}
 		}
 		var found models.Scene
-		db.Preload("History").Where(&models.Scene{SceneID: histories.SceneID}).First(&found)
+		db.Preload("History", "user_id = ?", models.DefaultUserID).Where(&models.Scene{SceneID: histories.SceneID}).First(&found)
 		if found.ID == 0 || len(histories.History)+len(found.History) == 0 {
 			continue
 		} else {
 			changed := false
 			for i, cp := range histories.History {
 				cp.SceneID = found.ID
+				cp.UserID = models.DefaultUserID
 				cp.ID = 0
 				histories.History[i] = cp
 			}
 			if overwrite || len(found.History) == 0 {
 				if len(histories.History)+len(found.History) > 0 {
 					if len(found.History) > 0 {
-						err := db.Delete(&models.History{}, "scene_id = ?", found.ID).Error
+						err := db.Delete(&models.History{}, "scene_id = ? and user_id = ?", found.ID, models.DefaultUserID).Error
 						//models.SaveWithRetry(db, &del)
 						if err != nil {
 							tlog.Infof("Eror deleteing history")
@@ -1118,6 +1148,7 @@ func RestoreHistory(sceneHistoryList []BackupSceneHistory, inclAllSites bool, se
 					}
 					found.History = histories.History
 					models.SaveWithRetry(db, &found)
+					changed = true
 					addedCnt++
 				}
 			} else {
@@ -1133,6 +1164,11 @@ func RestoreHistory(sceneHistoryList []BackupSceneHistory, inclAllSites bool, se
 					addedCnt++
 				}
 			}
+			if changed {
+				found.ApplyUserState(models.DefaultUserID)
+				found.TotalWatchTime = found.GetTotalWatchTime(models.DefaultUserID)
+				found.SaveUserState(models.DefaultUserID)
+			}
 		}
 	}
 	tlog.Infof("%v Scenes with history restored", addedCnt)
@@ -1212,6 +1248,9 @@ func RestorePlaylist(playlists []models.Playlist, overwrite bool, db *gorm.DB) {
 		var found models.Playlist
 		db.Where(&models.Playlist{Name: playlist.Name, PlaylistType: playlist.PlaylistType}).First(&found)
 
+		if !playlist.IsSystem {
+			playlist.UserID = models.DefaultUserID
+		}
 		if found.ID == 0 { // id = 0 is a new record
 			playlist.ID = 0 // dont use the id from json
 			models.SaveWithRetry(db, &playlist)
@@ -1219,6 +1258,7 @@ func RestorePlaylist(playlists []models.Playlist, overwrite bool, db *gorm.DB) {
 		} else {
 			if overwrite {
 				playlist.ID = found.ID // use the Id from the existing db record
+				playlist.UserID = found.UserID
 				models.SaveWithRetry(db, &playlist)
 				addedCnt++
 			}
@@ -1344,6 +1384,7 @@ func CheckTagGroup(tagGroup *models.TagGroup, tag_group_tag_id uint, db *gorm.DB
 	}
 }
 
+// RenameTags applies the tag rules to the tags of all scenes
 func RenameTags() {
 	db, _ := models.GetDB()
 	defer db.Close()
@@ -1355,11 +1396,23 @@ func RenameTags() {
 		currentTags := make([]models.Tag, 0)
 		db.Model(&scenes[i]).Related(&currentTags, "Tags")
 
-		newTags := make([]models.Tag, 0)
+		var names []string
 		for j := range currentTags {
+			if strings.HasPrefix(currentTags[j].Name, "tag group:") {
+				// tag groups are managed by themselves
+				names = append(names, currentTags[j].Name)
+				continue
+			}
+			names = append(names, models.ConvertTag(currentTags[j].Name)...)
+		}
+
+		newTags := make([]models.Tag, 0)
+		seen := map[uint]bool{}
+		for _, name := range names {
 			nt := models.Tag{}
-			if models.ConvertTag(currentTags[j].Name) != "" {
-				db.Where(&models.Tag{Name: models.ConvertTag(currentTags[j].Name)}).FirstOrCreate(&nt)
+			db.Where(&models.Tag{Name: name}).FirstOrCreate(&nt)
+			if !seen[nt.ID] {
+				seen[nt.ID] = true
 				newTags = append(newTags, nt)
 			}
 		}
@@ -1533,6 +1586,7 @@ func RestoreActors(actorList []models.Actor, overwrite bool, db *gorm.DB) {
 		var actor models.Actor
 		db.Where("name = ?", bundleActor.Name).Find(&actor)
 		if actor.ID != 0 {
+			actor.ApplyUserState(models.DefaultUserID)
 			if overwrite || actor.ImageUrl == "" {
 				actor.ImageUrl = bundleActor.ImageUrl
 			}
@@ -1610,6 +1664,7 @@ func RestoreActors(actorList []models.Actor, overwrite bool, db *gorm.DB) {
 			}
 			updatedCnt += 1
 			actor.Save()
+			actor.SaveUserState(models.DefaultUserID)
 		}
 	}
 	tlog.Infof("Updated %v of %v actors", updatedCnt, len(actorList))
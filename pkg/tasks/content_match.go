@@ -0,0 +1,391 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+const (
+	// matches scoring at least autoMatchScore are linked during rescans, as long as no other
+	// candidate comes within autoMatchMargin of them
+	autoMatchScore  = 0.85
+	autoMatchMargin = 0.15
+
+	// candidates below suggestMatchScore are not worth showing
+	suggestMatchScore = 0.35
+	maxSuggestions    = 5
+
+	// tokens shared by more scenes than this are too common to select candidates with
+	maxTokenSceneCount = 2000
+)
+
+// SceneMatchCandidate is a scene that a file may belong to, with the reasons it was picked
+type SceneMatchCandidate struct {
+	SceneID  string   `json:"scene_id"`
+	Title    string   `json:"title"`
+	Site     string   `json:"site"`
+	CoverURL string   `json:"cover_url"`
+	Duration int      `json:"duration"`
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+
+	id uint
+}
+
+type FileMatchSuggestion struct {
+	File       models.File           `json:"file"`
+	Candidates []SceneMatchCandidate `json:"candidates"`
+}
+
+type matchScene struct {
+	id       uint
+	sceneID  string
+	title    string
+	site     string
+	coverURL string
+	duration int
+
+	titleTokens []string
+	cast        [][]string
+	siteKey     string
+}
+
+type matchSibling struct {
+	sceneID  uint
+	height   int
+	duration float64
+}
+
+// contentMatcher scores scenes against files by their oshash, duration, resolution and the
+// title, cast and site found in the filename. Scenes are loaded once, as a rescan may need to
+// match thousands of files.
+type contentMatcher struct {
+	scenes   map[uint]*matchScene
+	byToken  map[string][]uint
+	byOsHash map[string]uint
+	siblings []matchSibling
+}
+
+var (
+	matchTokenSplit = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	matchNoise      = regexp.MustCompile(`^(\d{3,4}p|\d{1,2}k|\d{3,4}x\d{3,4}|\d{2,3}fps|\d{2,3}m|v\d+)$`)
+	matchStopWords  = map[string]bool{
+		"the": true, "and": true, "with": true, "for": true, "you": true, "your": true, "her": true, "his": true,
+		"vr": true, "sbs": true, "lr": true, "tb": true, "3dh": true, "180": true, "360": true, "mkx200": true,
+		"mkx220": true, "fisheye": true, "fisheye190": true, "rf52": true, "vrca220": true, "h264": true,
+		"h265": true, "hevc": true, "x264": true, "x265": true, "av1": true, "oculus": true, "oculus5k": true,
+		"quest": true, "quest2": true, "quest3": true, "gearvr": true, "psvr": true, "pico": true,
+		"original": true, "alpha": true, "passthrough": true, "smartphone": true, "desktop": true,
+		"uhq": true, "hq": true, "mp4": true, "mkv": true, "part": true,
+	}
+)
+
+// matchTokens splits s into lower case words, dropping resolution, codec and projection tags
+func matchTokens(s string) []string {
+	var out []string
+	for _, t := range matchTokenSplit.Split(strings.ToLower(s), -1) {
+		if len(t) < 2 || matchStopWords[t] || matchNoise.MatchString(t) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func matchKey(s string) string {
+	return strings.Join(matchTokenSplit.Split(strings.ToLower(s), -1), "")
+}
+
+func newContentMatcher(db *gorm.DB) *contentMatcher {
+	m := &contentMatcher{
+		scenes:   map[uint]*matchScene{},
+		byToken:  map[string][]uint{},
+		byOsHash: map[string]uint{},
+	}
+
+	rows, err := db.Model(&models.Scene{}).Select("id, scene_id, title, site, cover_url, duration").Rows()
+	if err != nil {
+		log.Error(err)
+		return m
+	}
+	for rows.Next() {
+		s := &matchScene{}
+		if err := rows.Scan(&s.id, &s.sceneID, &s.title, &s.site, &s.coverURL, &s.duration); err != nil {
+			continue
+		}
+		s.titleTokens = matchTokens(s.title)
+		s.siteKey = matchKey(s.site)
+		m.scenes[s.id] = s
+	}
+	rows.Close()
+
+	rows, err = db.Table("scene_cast").Select("scene_cast.scene_id, actors.name").Joins("join actors on actors.id = scene_cast.actor_id").Rows()
+	if err == nil {
+		for rows.Next() {
+			var id uint
+			var name string
+			if rows.Scan(&id, &name) == nil && m.scenes[id] != nil {
+				m.scenes[id].cast = append(m.scenes[id].cast, matchTokens(name))
+			}
+		}
+		rows.Close()
+	}
+
+	m.indexTokens()
+
+	var files []models.File
+	db.Select("scene_id, os_hash, video_height, video_duration").Where("scene_id != 0 and type = ?", "video").Find(&files)
+	for _, f := range files {
+		if f.OsHash != "" {
+			m.byOsHash[f.OsHash] = f.SceneID
+		}
+		if f.VideoHeight > 0 && f.VideoDuration > 0 {
+			m.siblings = append(m.siblings, matchSibling{sceneID: f.SceneID, height: f.VideoHeight, duration: f.VideoDuration})
+		}
+	}
+	return m
+}
+
+// indexTokens indexes the scenes by the words of their title and cast, to pick candidates with
+func (m *contentMatcher) indexTokens() {
+	for id, s := range m.scenes {
+		seen := map[string]bool{}
+		tokens := append([]string{}, s.titleTokens...)
+		for _, name := range s.cast {
+			tokens = append(tokens, name...)
+		}
+		for _, t := range tokens {
+			if !seen[t] {
+				seen[t] = true
+				m.byToken[t] = append(m.byToken[t], id)
+			}
+		}
+	}
+}
+
+// candidates returns the scenes scoring at least minScore for f, best first
+func (m *contentMatcher) candidates(f models.File, minScore float64) []SceneMatchCandidate {
+	// the same video is already linked to a scene, no need to guess
+	if id, ok := m.byOsHash[f.OsHash]; ok && f.OsHash != "" && m.scenes[id] != nil {
+		return []SceneMatchCandidate{m.candidate(m.scenes[id], 1, []string{"oshash"})}
+	}
+
+	name := strings.TrimSuffix(f.Filename, filepath.Ext(f.Filename))
+	tokens := matchTokens(name)
+	if len(tokens) == 0 {
+		return nil
+	}
+	fileTokens := map[string]bool{}
+	for _, t := range tokens {
+		fileTokens[t] = true
+	}
+	fileKey := matchKey(name)
+
+	ids := map[uint]bool{}
+	for t := range fileTokens {
+		if len(m.byToken[t]) <= maxTokenSceneCount {
+			for _, id := range m.byToken[t] {
+				ids[id] = true
+			}
+		}
+	}
+
+	var out []SceneMatchCandidate
+	for id := range ids {
+		if score, reasons := m.score(f, m.scenes[id], fileTokens, fileKey); score >= minScore {
+			out = append(out, m.candidate(m.scenes[id], score, reasons))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score == out[j].Score {
+			return out[i].SceneID < out[j].SceneID
+		}
+		return out[i].Score > out[j].Score
+	})
+	return out
+}
+
+func (m *contentMatcher) score(f models.File, s *matchScene, fileTokens map[string]bool, fileKey string) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if len(s.titleTokens) > 0 {
+		found := 0
+		for _, t := range s.titleTokens {
+			if fileTokens[t] {
+				found++
+			}
+		}
+		if found > 0 {
+			score += 0.5 * float64(found) / float64(len(s.titleTokens))
+			reasons = append(reasons, fmt.Sprintf("title %v/%v", found, len(s.titleTokens)))
+		}
+	}
+
+	if len(s.cast) > 0 {
+		found := 0
+		for _, name := range s.cast {
+			if len(name) == 0 {
+				continue
+			}
+			all := true
+			for _, t := range name {
+				all = all && fileTokens[t]
+			}
+			// names are often written without spaces, eg JaneDoe
+			if all || strings.Contains(fileKey, strings.Join(name, "")) {
+				found++
+			}
+		}
+		if found > 0 {
+			score += 0.25 * float64(found) / float64(len(s.cast))
+			reasons = append(reasons, fmt.Sprintf("cast %v/%v", found, len(s.cast)))
+		}
+	}
+
+	if len(s.siteKey) > 3 && strings.Contains(fileKey, s.siteKey) {
+		score += 0.1
+		reasons = append(reasons, "site")
+	}
+
+	if s.duration > 0 && f.VideoDuration > 0 {
+		diff := math.Abs(f.VideoDuration/60 - float64(s.duration))
+		switch {
+		case diff <= 2:
+			score += 0.15
+			reasons = append(reasons, "duration")
+		case diff > 5:
+			score -= 0.2
+			reasons = append(reasons, "duration mismatch")
+		}
+	}
+
+	// another copy of the scene with the same resolution and length, eg a remux or re-download
+	if f.VideoHeight > 0 && f.VideoDuration > 0 {
+		for _, sib := range m.siblings {
+			if sib.sceneID == s.id && sib.height == f.VideoHeight && math.Abs(sib.duration-f.VideoDuration) < 2 {
+				score += 0.2
+				reasons = append(reasons, "same resolution and duration as linked file")
+				break
+			}
+		}
+	}
+
+	return math.Min(score, 1), reasons
+}
+
+func (m *contentMatcher) candidate(s *matchScene, score float64, reasons []string) SceneMatchCandidate {
+	return SceneMatchCandidate{
+		SceneID:  s.sceneID,
+		Title:    s.title,
+		Site:     s.site,
+		CoverURL: s.coverURL,
+		Duration: s.duration,
+		Score:    math.Round(score*100) / 100,
+		Reasons:  reasons,
+		id:       s.id,
+	}
+}
+
+// bestMatch returns the candidate a file can be linked to without asking the user
+func (m *contentMatcher) bestMatch(f models.File) (SceneMatchCandidate, bool) {
+	list := m.candidates(f, suggestMatchScore)
+	if len(list) == 0 || list[0].Score < autoMatchScore {
+		return SceneMatchCandidate{}, false
+	}
+	if len(list) > 1 && list[0].Score-list[1].Score < autoMatchMargin {
+		return SceneMatchCandidate{}, false
+	}
+	return list[0], true
+}
+
+// linkFileToScene assigns a file to a scene and remembers its filename, so the file is matched
+// by name again if it gets moved
+func linkFileToScene(db *gorm.DB, f *models.File, sceneID uint) models.Scene {
+	var scene models.Scene
+	scene.GetIfExistByPK(sceneID)
+
+	f.SceneID = scene.ID
+	f.Save()
+
+	var pfTxt []string
+	json.Unmarshal([]byte(scene.FilenamesArr), &pfTxt)
+	pfTxt = append(pfTxt, f.Filename)
+	tmp, _ := json.Marshal(pfTxt)
+	scene.FilenamesArr = string(tmp)
+	scene.Save()
+	models.AddAction(scene.SceneID, "match", "filenames_arr", scene.FilenamesArr)
+
+	scene.UpdateStatus()
+	return scene
+}
+
+// matchFilesByContent links the files that could not be matched by filename to the scene
+// they most likely belong to
+func matchFilesByContent(db *gorm.DB, files []models.File, tlog *logrus.Entry) {
+	var m *contentMatcher
+	matched := 0
+	for i := range files {
+		if files[i].SceneID != 0 || files[i].Type != "video" {
+			continue
+		}
+		if m == nil {
+			m = newContentMatcher(db)
+		}
+		if c, ok := m.bestMatch(files[i]); ok {
+			linkFileToScene(db, &files[i], c.id)
+			log.Infof("File %s matched to Scene %s by content (score %v: %v)", files[i].Filename, c.SceneID, c.Score, strings.Join(c.Reasons, ", "))
+			if files[i].OsHash != "" {
+				m.byOsHash[files[i].OsHash] = c.id
+			}
+			matched++
+		}
+	}
+	if matched > 0 {
+		tlog.Infof("Matched %v files to scenes by content", matched)
+	}
+}
+
+// SuggestFileMatches lists the likely scenes for unmatched video files, for the user to pick
+// from. Only the given file is checked when fileID is not 0.
+func SuggestFileMatches(fileID uint, limit int) []FileMatchSuggestion {
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	var files []models.File
+	tx := db.Where("scene_id = 0 and type = ?", "video")
+	if fileID != 0 {
+		tx = tx.Where("id = ?", fileID)
+	}
+	tx.Order("created_time desc").Find(&files)
+
+	out := []FileMatchSuggestion{}
+	if len(files) == 0 {
+		return out
+	}
+
+	m := newContentMatcher(db)
+	for _, f := range files {
+		list := m.candidates(f, suggestMatchScore)
+		if len(list) == 0 {
+			continue
+		}
+		if len(list) > maxSuggestions {
+			list = list[:maxSuggestions]
+		}
+		out = append(out, FileMatchSuggestion{File: f, Candidates: list})
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package tasks
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+type testMatchScene struct {
+	title    string
+	site     string
+	cast     []string
+	duration int
+}
+
+// newTestMatcher indexes scenes the way newContentMatcher does, without a database
+func newTestMatcher(scenes ...testMatchScene) *contentMatcher {
+	m := &contentMatcher{scenes: map[uint]*matchScene{}, byToken: map[string][]uint{}, byOsHash: map[string]uint{}}
+	for i, ts := range scenes {
+		s := &matchScene{
+			id:          uint(i + 1),
+			sceneID:     ts.title,
+			title:       ts.title,
+			site:        ts.site,
+			duration:    ts.duration,
+			titleTokens: matchTokens(ts.title),
+			siteKey:     matchKey(ts.site),
+		}
+		for _, name := range ts.cast {
+			s.cast = append(s.cast, matchTokens(name))
+		}
+		m.scenes[s.id] = s
+	}
+	m.indexTokens()
+	return m
+}
+
+func TestContentMatchScore(t *testing.T) {
+	scene := testMatchScene{title: "Hot Summer Day", site: "VRHush", cast: []string{"Jane Doe", "Mary Major"}, duration: 30}
+
+	tests := []struct {
+		name     string
+		file     models.File
+		siblings []matchSibling
+		score    float64
+		reasons  []string
+	}{
+		{"title", models.File{Filename: "Hot.Summer.Day.mp4"}, nil,
+			0.5, []string{"title 3/3"}},
+		{"part of the title", models.File{Filename: "Summer_Day.mp4"}, nil,
+			0.5 * 2 / 3, []string{"title 2/3"}},
+		{"cast by words", models.File{Filename: "Jane Doe - Hot Summer Day.mp4"}, nil,
+			0.5 + 0.25/2, []string{"title 3/3", "cast 1/2"}},
+		{"cast written without spaces", models.File{Filename: "JaneDoe_MaryMajor_Hot_Summer_Day.mp4"}, nil,
+			0.75, []string{"title 3/3", "cast 2/2"}},
+		{"site", models.File{Filename: "VRHush_Hot_Summer_Day_4K_180_LR.mp4"}, nil,
+			0.6, []string{"title 3/3", "site"}},
+		{"duration within 2 minutes", models.File{Filename: "Hot_Summer_Day.mp4", VideoDuration: 31 * 60}, nil,
+			0.65, []string{"title 3/3", "duration"}},
+		{"duration between 2 and 5 minutes off", models.File{Filename: "Hot_Summer_Day.mp4", VideoDuration: 34 * 60}, nil,
+			0.5, []string{"title 3/3"}},
+		{"duration mismatch", models.File{Filename: "Hot_Summer_Day.mp4", VideoDuration: 40 * 60}, nil,
+			0.3, []string{"title 3/3", "duration mismatch"}},
+		{"copy of a linked file", models.File{Filename: "Hot_Summer_Day.mp4", VideoHeight: 2880, VideoDuration: 1799},
+			[]matchSibling{{sceneID: 1, height: 2880, duration: 1800}},
+			0.85, []string{"title 3/3", "duration", "same resolution and duration as linked file"}},
+		{"linked file of another resolution", models.File{Filename: "Hot_Summer_Day.mp4", VideoHeight: 1920, VideoDuration: 1799},
+			[]matchSibling{{sceneID: 1, height: 2880, duration: 1800}},
+			0.65, []string{"title 3/3", "duration"}},
+		{"everything, capped at 1", models.File{Filename: "VRHush_JaneDoe_MaryMajor_Hot_Summer_Day.mp4", VideoHeight: 2880, VideoDuration: 1800},
+			[]matchSibling{{sceneID: 1, height: 2880, duration: 1800}},
+			1, []string{"title 3/3", "cast 2/2", "site", "duration", "same resolution and duration as linked file"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatcher(scene)
+			m.siblings = tt.siblings
+			list := m.candidates(tt.file, 0)
+			if len(list) != 1 {
+				t.Fatalf("expected the scene as the only candidate, got %+v", list)
+			}
+			if math.Abs(list[0].Score-math.Round(tt.score*100)/100) > 1e-9 {
+				t.Errorf("score = %v, want %v", list[0].Score, tt.score)
+			}
+			if !reflect.DeepEqual(list[0].Reasons, tt.reasons) {
+				t.Errorf("reasons = %v, want %v", list[0].Reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestContentMatchShortSiteIgnored(t *testing.T) {
+	m := newTestMatcher(testMatchScene{title: "Hot Summer Day", site: "VRB"})
+	list := m.candidates(models.File{Filename: "VRB_Hot_Summer_Day.mp4"}, 0)
+	if len(list) != 1 || list[0].Score != 0.5 {
+		t.Errorf("expected a site of 3 letters not to count, got %+v", list)
+	}
+}
+
+func TestContentMatchAutoLink(t *testing.T) {
+	summerDay := testMatchScene{title: "Hot Summer Day", site: "VRHush", cast: []string{"Jane Doe"}, duration: 30}
+
+	tests := []struct {
+		name   string
+		scenes []testMatchScene
+		file   models.File
+		linked string
+	}{
+		{"sure match", []testMatchScene{summerDay},
+			models.File{Filename: "VRHush_JaneDoe_Hot_Summer_Day.mp4", VideoDuration: 1800}, "Hot Summer Day"},
+		{"at the threshold", []testMatchScene{summerDay},
+			models.File{Filename: "VRHush_JaneDoe_Hot_Summer_Day.mp4"}, "Hot Summer Day"},
+		{"below the threshold", []testMatchScene{summerDay},
+			models.File{Filename: "JaneDoe_Hot_Summer_Day.mp4"}, ""},
+		{"runner-up far enough behind",
+			[]testMatchScene{summerDay, {title: "Hot Summer Night", site: "VRHush", cast: []string{"Jane Doe"}, duration: 30}},
+			models.File{Filename: "VRHush_JaneDoe_Hot_Summer_Day.mp4", VideoDuration: 1800}, "Hot Summer Day"},
+		{"runner-up within the margin",
+			[]testMatchScene{summerDay, {title: "Hot Summer Day Two", site: "VRHush", cast: []string{"Jane Doe"}, duration: 30}},
+			models.File{Filename: "VRHush_JaneDoe_Hot_Summer_Day.mp4", VideoDuration: 1800}, ""},
+		{"nothing in common", []testMatchScene{summerDay},
+			models.File{Filename: "Cold_Winter_Night.mp4"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := newTestMatcher(tt.scenes...).bestMatch(tt.file)
+			if tt.linked == "" && ok {
+				t.Errorf("expected no link, got %v (score %v)", c.SceneID, c.Score)
+			}
+			if tt.linked != "" && (!ok || c.SceneID != tt.linked) {
+				t.Errorf("expected a link to %v, got %v (score %v, linked %v)", tt.linked, c.SceneID, c.Score, ok)
+			}
+		})
+	}
+}
+
+func TestContentMatchOsHash(t *testing.T) {
+	m := newTestMatcher(testMatchScene{title: "Hot Summer Day"}, testMatchScene{title: "Cold Winter Night"})
+	m.byOsHash["0123456789abcdef"] = 2
+
+	c, ok := m.bestMatch(models.File{Filename: "Hot_Summer_Day.mp4", OsHash: "0123456789abcdef"})
+	if !ok || c.SceneID != "Cold Winter Night" || c.Score != 1 || !reflect.DeepEqual(c.Reasons, []string{"oshash"}) {
+		t.Errorf("expected the scene of the same video, got %+v (linked %v)", c, ok)
+	}
+}
@@ -0,0 +1,227 @@
+package tasks
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+type DuplicateFile struct {
+	ID            uint    `json:"id"`
+	VolumeID      uint    `json:"volume_id"`
+	VolumeType    string  `json:"volume_type"`
+	IsAvailable   bool    `json:"is_available"`
+	Path          string  `json:"path"`
+	Filename      string  `json:"filename"`
+	Size          int64   `json:"size"`
+	OsHash        string  `json:"oshash"`
+	VideoWidth    int     `json:"video_width"`
+	VideoHeight   int     `json:"video_height"`
+	VideoBitRate  int     `json:"video_bitrate"`
+	VideoDuration float64 `json:"duration"`
+}
+
+// DuplicateGroup holds copies of the same video. Exact duplicates share their oshash and size,
+// near duplicates are matched to the same scene and have the same length, eg another resolution.
+type DuplicateGroup struct {
+	Kind        string          `json:"kind"`
+	SceneID     string          `json:"scene_id"`
+	Title       string          `json:"title"`
+	Files       []DuplicateFile `json:"files"`
+	SuggestKeep uint            `json:"suggest_keep"`
+	Reclaimable int64           `json:"reclaimable"`
+}
+
+type DuplicateReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Groups      []DuplicateGroup `json:"groups"`
+	Reclaimable int64            `json:"reclaimable"`
+}
+
+// FindDuplicateFiles groups exact and near duplicate video files and stores the report, so it
+// can be reviewed without scanning the library again
+func FindDuplicateFiles() {
+	if !models.CheckLock("dedup") {
+		models.CreateLock("dedup")
+		defer models.RemoveLock("dedup")
+
+		tlog := log.WithFields(logrus.Fields{"task": "dedup"})
+		tlog.Infof("Looking for duplicate files")
+
+		db, _ := models.GetDB()
+		defer db.Close()
+
+		report := DuplicateReport{GeneratedAt: time.Now(), Groups: []DuplicateGroup{}}
+		inExactGroup := map[uint]bool{}
+
+		// Exact duplicates
+		var hashes []struct {
+			OsHash string
+			Size   int64
+		}
+		db.Model(&models.File{}).
+			Select("os_hash, size").
+			Where("type = ? and os_hash != ''", "video").
+			Group("os_hash, size").
+			Having("count(*) > 1").
+			Scan(&hashes)
+		for _, h := range hashes {
+			var files []models.File
+			db.Preload("Volume").Where("type = ? and os_hash = ? and size = ?", "video", h.OsHash, h.Size).Find(&files)
+			if len(files) < 2 {
+				continue
+			}
+			for _, f := range files {
+				inExactGroup[f.ID] = true
+			}
+			report.Groups = append(report.Groups, newDuplicateGroup("exact", files))
+		}
+
+		// Near duplicates
+		var sceneIDs []uint
+		db.Model(&models.File{}).
+			Where("type = ? and scene_id != 0 and video_duration > 0", "video").
+			Group("scene_id").
+			Having("count(*) > 1").
+			Pluck("scene_id", &sceneIDs)
+		for _, sceneID := range sceneIDs {
+			var files []models.File
+			db.Preload("Volume").Where("type = ? and scene_id = ? and video_duration > 0", "video", sceneID).Order("video_duration").Find(&files)
+
+			// files of different length are usually parts of a scene rather than copies
+			for _, cluster := range clusterByDuration(files) {
+				if len(cluster) < 2 || allInExactGroup(cluster, inExactGroup) {
+					continue
+				}
+				report.Groups = append(report.Groups, newDuplicateGroup("near", cluster))
+			}
+		}
+
+		for _, g := range report.Groups {
+			report.Reclaimable += g.Reclaimable
+		}
+		sort.SliceStable(report.Groups, func(i, j int) bool {
+			return report.Groups[i].Reclaimable > report.Groups[j].Reclaimable
+		})
+
+		data, _ := json.Marshal(report)
+		obj := models.KV{Key: "dedup_report", Value: string(data)}
+		obj.Save()
+
+		tlog.Infof("Found %v groups of duplicate files", len(report.Groups))
+	}
+}
+
+// GetDuplicateReport returns the report of the last FindDuplicateFiles run
+func GetDuplicateReport() DuplicateReport {
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	report := DuplicateReport{Groups: []DuplicateGroup{}}
+	var obj models.KV
+	if db.Where(&models.KV{Key: "dedup_report"}).First(&obj).Error == nil {
+		json.Unmarshal([]byte(obj.Value), &report)
+	}
+	return report
+}
+
+// clusterByDuration splits files sorted by duration into runs of files with the same length,
+// allowing for a few seconds of difference between encodes
+func clusterByDuration(files []models.File) [][]models.File {
+	var out [][]models.File
+	var cur []models.File
+	for _, f := range files {
+		if len(cur) > 0 {
+			first := cur[0].VideoDuration
+			if f.VideoDuration-first > math.Max(5, first*0.01) {
+				out = append(out, cur)
+				cur = nil
+			}
+		}
+		cur = append(cur, f)
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+func allInExactGroup(files []models.File, inExactGroup map[uint]bool) bool {
+	for _, f := range files {
+		if !inExactGroup[f.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+func newDuplicateGroup(kind string, files []models.File) DuplicateGroup {
+	g := DuplicateGroup{Kind: kind}
+
+	for _, f := range files {
+		g.Files = append(g.Files, DuplicateFile{
+			ID:            f.ID,
+			VolumeID:      f.VolumeID,
+			VolumeType:    f.Volume.Type,
+			IsAvailable:   f.Volume.IsAvailable,
+			Path:          f.Path,
+			Filename:      f.Filename,
+			Size:          f.Size,
+			OsHash:        f.OsHash,
+			VideoWidth:    f.VideoWidth,
+			VideoHeight:   f.VideoHeight,
+			VideoBitRate:  f.VideoBitRate,
+			VideoDuration: f.VideoDuration,
+		})
+	}
+
+	// the best copy goes first: highest resolution and bitrate, on a reachable local volume
+	sort.SliceStable(g.Files, func(i, j int) bool {
+		a, b := g.Files[i], g.Files[j]
+		if a.VideoWidth != b.VideoWidth {
+			return a.VideoWidth > b.VideoWidth
+		}
+		if a.VideoBitRate != b.VideoBitRate {
+			return a.VideoBitRate > b.VideoBitRate
+		}
+		if a.IsAvailable != b.IsAvailable {
+			return a.IsAvailable
+		}
+		if (a.VolumeType == "local") != (b.VolumeType == "local") {
+			return a.VolumeType == "local"
+		}
+		return a.ID < b.ID
+	})
+	g.SuggestKeep = g.Files[0].ID
+	for _, f := range g.Files[1:] {
+		g.Reclaimable += f.Size
+	}
+
+	if files[0].SceneID != 0 {
+		var scene models.Scene
+		if scene.GetIfExistByPK(files[0].SceneID) == nil {
+			g.SceneID = scene.SceneID
+			g.Title = scene.Title
+		}
+	}
+	return g
+}
+
+// IsDuplicateOf reports whether the last report lists keep and id as copies of each other
+func (r DuplicateReport) IsDuplicateOf(keep uint, id uint) bool {
+	for _, g := range r.Groups {
+		hasKeep, hasID := false, false
+		for _, f := range g.Files {
+			hasKeep = hasKeep || f.ID == keep
+			hasID = hasID || f.ID == id
+		}
+		if hasKeep && hasID {
+			return true
+		}
+	}
+	return false
+}
@@ -58,6 +58,7 @@ func GenerateHeatmaps(tlog *logrus.Entry) {
 	if !models.CheckLock("heatmaps") {
 		models.CreateLock("heatmaps")
 		defer models.RemoveLock("heatmaps")
+		defer common.TimeTask("heatmaps", "")(nil)
 
 		db, _ := models.GetDB()
 		defer db.Close()
@@ -69,7 +70,7 @@ func GenerateHeatmaps(tlog *logrus.Entry) {
 			if tlog != nil && (i%50) == 0 {
 				tlog.Infof("Generating heatmaps (%v/%v)", i+1, len(scriptfiles))
 			}
-			if file.Exists() {
+			if file.Volume.Type == "local" && file.Exists() {
 				path := file.GetPath()
 				if strings.HasSuffix(path, ".funscript") {
 					log.Infof("Rendering %v", file.Filename)
@@ -157,7 +158,7 @@ func RenderHeatmap(inputFile string, destFile string, width, height, numSegments
 
 	outpng, err := os.Create(destFile)
 	if err != nil {
-		return fmt.Errorf("Error storing png: " + err.Error())
+		return fmt.Errorf("Error storing png: %v", err)
 	}
 	defer outpng.Close()
 
@@ -0,0 +1,166 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/markphelps/optional"
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+// GenerateMobileRenditions transcodes the scenes of the playlists selected in the options into
+// lower resolution copies, for players streaming over Wi-Fi. A rendition is stored as a file of
+// type "mobile" in common.MobileDir and linked to the scene like any other file.
+func GenerateMobileRenditions(endTime *time.Time) {
+	if !models.CheckLock("mobile") {
+		models.CreateLock("mobile")
+		defer models.RemoveLock("mobile")
+
+		tlog := log.WithFields(logrus.Fields{"task": "mobile"})
+		tlog.Infof("Generating mobile renditions")
+
+		db, _ := models.GetDB()
+		defer db.Close()
+
+		removeMissingRenditions(db, tlog)
+
+		sceneIDs := mobileRenditionSceneIDs(db)
+		for i, id := range sceneIDs {
+			if endTime != nil && time.Now().After(*endTime) {
+				return
+			}
+
+			var count int
+			db.Model(&models.File{}).Where("scene_id = ? and type = ?", id, "mobile").Count(&count)
+			if count > 0 {
+				continue
+			}
+
+			var scene models.Scene
+			if scene.GetIfExistByPK(id) != nil {
+				continue
+			}
+			files, _ := scene.GetVideoFilesSorted(config.Config.Interfaces.Players.VideoSortSeq)
+			for _, file := range files {
+				if !file.Exists() {
+					continue
+				}
+				if file.VideoWidth <= config.Config.Library.Mobile.Width {
+					// already small enough to stream
+					break
+				}
+
+				tlog.Infof("Rendering mobile version of %v (%v/%v)", scene.SceneID, i+1, len(sceneIDs))
+				if err := renderMobileRendition(file, scene, tlog); err != nil {
+					tlog.Warn(err)
+					continue
+				}
+				break
+			}
+		}
+		tlog.Infof("Mobile renditions generated")
+	}
+}
+
+// mobileRenditionSceneIDs returns the ids of the scenes in the selected playlists, in playlist order
+func mobileRenditionSceneIDs(db *gorm.DB) []uint {
+	var out []uint
+	seen := map[uint]bool{}
+	for _, playlistID := range config.Config.Library.Mobile.Playlists {
+		var playlist models.Playlist
+		if db.Where("playlist_type = ?", "scene").First(&playlist, playlistID).Error != nil {
+			continue
+		}
+
+		var r models.RequestSceneList
+		if err := json.Unmarshal([]byte(playlist.SearchParams), &r); err != nil {
+			continue
+		}
+		r.Limit = optional.NewInt(100000)
+		for _, id := range models.QuerySceneIDs(r) {
+			sceneID, err := strconv.Atoi(id)
+			if err != nil || seen[uint(sceneID)] {
+				continue
+			}
+			seen[uint(sceneID)] = true
+			out = append(out, uint(sceneID))
+		}
+	}
+	return out
+}
+
+// removeMissingRenditions drops the files of renditions deleted from disk, so they get rendered again
+func removeMissingRenditions(db *gorm.DB, tlog *logrus.Entry) {
+	var files []models.File
+	db.Where("type = ?", "mobile").Find(&files)
+	for i := range files {
+		if !files[i].Exists() {
+			tlog.Infof("Removing missing rendition %v", files[i].GetPath())
+			db.Delete(&files[i])
+		}
+	}
+}
+
+func renderMobileRendition(src models.File, scene models.Scene, tlog *logrus.Entry) error {
+	input, err := GetVideoInput(src)
+	if err != nil {
+		return err
+	}
+
+	width := config.Config.Library.Mobile.Width
+	bitrate := config.Config.Library.Mobile.VideoBitrate
+	destFile := filepath.Join(common.MobileDir, scene.SceneID+".mp4")
+	tmpFile := destFile + ".part"
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", input,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", width),
+		"-c:v", "libx264", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-b:v", fmt.Sprintf("%dk", bitrate),
+		"-maxrate", fmt.Sprintf("%dk", bitrate*3/2),
+		"-bufsize", fmt.Sprintf("%dk", bitrate*2),
+		"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+		"-movflags", "+faststart",
+		"-f", "mp4", tmpFile,
+	}
+	out, err := buildCmd(GetBinPath("ffmpeg"), args...).CombinedOutput()
+	if err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("rendering %v failed: %v %s", scene.SceneID, err, strings.TrimSpace(string(out)))
+	}
+	if err := os.Rename(tmpFile, destFile); err != nil {
+		return err
+	}
+
+	fStat, err := os.Stat(destFile)
+	if err != nil {
+		return err
+	}
+	fl := models.File{
+		Path:        common.MobileDir,
+		Filename:    filepath.Base(destFile),
+		Type:        "mobile",
+		SceneID:     scene.ID,
+		Size:        fStat.Size(),
+		CreatedTime: time.Now(),
+		UpdatedTime: fStat.ModTime(),
+	}
+	probeVideoStream(destFile, fl.Filename, time.Second*10, &fl, tlog)
+	// the filename no longer carries the projection hints of the source
+	fl.VideoProjection = src.VideoProjection
+	fl.HasAlpha = false
+	fl.Save()
+
+	scene.UpdateStatus()
+	return nil
+}
@@ -0,0 +1,460 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+type OrganizeMove struct {
+	FileID  uint   `json:"file_id"`
+	SceneID string `json:"scene_id"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Error   string `json:"error,omitempty"`
+
+	file       models.File
+	toVolume   models.Volume
+	toPath     string
+	toFilename string
+}
+
+type OrganizeResult struct {
+	Batch  string         `json:"batch"`
+	DryRun bool           `json:"dry_run"`
+	Moves  []OrganizeMove `json:"moves"`
+}
+
+var (
+	organizeField   = regexp.MustCompile(`\{(\w+)(?::([^}]*))?\}`)
+	organizeInvalid = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+)
+
+// OrganizeFiles renames and moves the files of matched scenes to the location given by the
+// organizer template. Videos are moved together with their scripts, hsp and subtitle files.
+// With dryRun set the moves are only planned, so they can be previewed.
+func OrganizeFiles(sceneIDs []uint, dryRun bool) (OrganizeResult, error) {
+	result := OrganizeResult{DryRun: dryRun, Moves: []OrganizeMove{}}
+	if models.CheckLock("organize") {
+		return result, errors.New("organizer is already running")
+	}
+	models.CreateLock("organize")
+	defer models.RemoveLock("organize")
+
+	tlog := log.WithFields(logrus.Fields{"task": "organize"})
+
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	moves, err := planOrganize(db, config.Config.Storage.Organizer.Template, config.Config.Storage.Organizer.TargetVolume, sceneIDs)
+	if err != nil {
+		return result, err
+	}
+	if dryRun {
+		result.Moves = moves
+		return result, nil
+	}
+
+	result.Batch = time.Now().Format("20060102-150405")
+	tlog.Infof("Organizing %v files", len(moves))
+	for i := range moves {
+		m := &moves[i]
+		if m.Error != "" {
+			continue
+		}
+
+		journal := models.OrganizeJournal{
+			Batch:        result.Batch,
+			FileID:       m.file.ID,
+			FromVolumeID: m.file.VolumeID,
+			FromPath:     m.file.Path,
+			FromFilename: m.file.Filename,
+			ToVolumeID:   m.toVolume.ID,
+			ToPath:       m.toPath,
+			ToFilename:   m.toFilename,
+		}
+		if err := moveFile(db, &m.file, m.toVolume.ID, m.toPath, m.toFilename); err != nil {
+			m.Error = err.Error()
+			tlog.Errorf("Can't move %v: %v", m.From, err)
+			continue
+		}
+		journal.Save()
+		rememberFilename(m.file)
+	}
+	result.Moves = moves
+
+	tlog.Infof("Organizing complete")
+	common.PublishWS("state.change.optionsStorage", nil)
+	return result, nil
+}
+
+// UndoOrganize moves the files of an organizer run back to where they came from. The latest run
+// that wasn't undone yet is used when batch is empty.
+func UndoOrganize(batch string) (OrganizeResult, error) {
+	result := OrganizeResult{Moves: []OrganizeMove{}}
+	if models.CheckLock("organize") {
+		return result, errors.New("organizer is already running")
+	}
+	models.CreateLock("organize")
+	defer models.RemoveLock("organize")
+
+	tlog := log.WithFields(logrus.Fields{"task": "organize"})
+
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	if batch == "" {
+		var last models.OrganizeJournal
+		if db.Where("undone_at is null").Order("id desc").First(&last).Error != nil {
+			return result, errors.New("nothing to undo")
+		}
+		batch = last.Batch
+	}
+	result.Batch = batch
+
+	var entries []models.OrganizeJournal
+	db.Where("batch = ? and undone_at is null", batch).Order("id desc").Find(&entries)
+	tlog.Infof("Undoing organizer run %v (%v files)", batch, len(entries))
+
+	for _, entry := range entries {
+		var f models.File
+		if db.Preload("Volume").Where(&models.File{ID: entry.FileID}).First(&f).Error != nil {
+			continue
+		}
+		m := OrganizeMove{FileID: f.ID, From: f.GetPath(), To: filepath.Join(entry.FromPath, entry.FromFilename)}
+		if f.VolumeID != entry.ToVolumeID || f.Path != entry.ToPath || f.Filename != entry.ToFilename {
+			m.Error = "file was moved again since"
+		} else if err := moveFile(db, &f, entry.FromVolumeID, entry.FromPath, entry.FromFilename); err != nil {
+			m.Error = err.Error()
+			tlog.Errorf("Can't move %v back: %v", m.From, err)
+		} else {
+			now := time.Now()
+			entry.UndoneAt = &now
+			entry.Save()
+		}
+		result.Moves = append(result.Moves, m)
+	}
+
+	common.PublishWS("state.change.optionsStorage", nil)
+	return result, nil
+}
+
+// GetOrganizeJournal returns the most recent journal entries, newest first
+func GetOrganizeJournal(limit int) []models.OrganizeJournal {
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	entries := []models.OrganizeJournal{}
+	db.Order("id desc").Limit(limit).Find(&entries)
+	return entries
+}
+
+func planOrganize(db *gorm.DB, template string, targetVolumeID uint, sceneIDs []uint) ([]OrganizeMove, error) {
+	base := strings.TrimSuffix(filepath.ToSlash(template), ".{ext}")
+	if strings.TrimSpace(base) == "" {
+		return nil, errors.New("organizer template is empty")
+	}
+
+	var target models.Volume
+	if targetVolumeID != 0 {
+		if db.Where("id = ?", targetVolumeID).First(&target).Error != nil || target.Type != "local" {
+			return nil, errors.New("organizer target volume must be an existing local volume")
+		}
+	}
+
+	tx := db.Preload("Volume").Where("scene_id != 0")
+	if len(sceneIDs) > 0 {
+		tx = tx.Where("scene_id in (?)", sceneIDs)
+	}
+	var files []models.File
+	tx.Order("scene_id, id").Find(&files)
+
+	bySceneID := map[uint][]models.File{}
+	var order []uint
+	for _, f := range files {
+		// only files on local disks can be moved around
+		if f.Volume.Type != "local" || !f.Volume.IsAvailable {
+			continue
+		}
+		if _, ok := bySceneID[f.SceneID]; !ok {
+			order = append(order, f.SceneID)
+		}
+		bySceneID[f.SceneID] = append(bySceneID[f.SceneID], f)
+	}
+
+	moves := []OrganizeMove{}
+	planned := map[string]bool{}
+	for _, sceneID := range order {
+		var scene models.Scene
+		if scene.GetIfExistByPK(sceneID) != nil {
+			continue
+		}
+		rel, err := renderOrganizeTemplate(base, scene)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, planSceneMoves(scene, bySceneID[sceneID], rel, target, planned)...)
+	}
+	return moves, nil
+}
+
+// planSceneMoves names the files of a scene after rel. Companion files like scripts keep the
+// name of the video they belong to, so players still pick them up after the move.
+func planSceneMoves(scene models.Scene, files []models.File, rel string, target models.Volume, planned map[string]bool) []OrganizeMove {
+	var videos, companions []models.File
+	for _, f := range files {
+		if f.Type == "video" {
+			videos = append(videos, f)
+		} else {
+			companions = append(companions, f)
+		}
+	}
+
+	newBase := map[uint]string{}
+	for i, v := range videos {
+		newBase[v.ID] = rel
+		if i > 0 {
+			newBase[v.ID] = fmt.Sprintf("%v (%v)", rel, i+1)
+		}
+	}
+
+	var moves []OrganizeMove
+	add := func(f models.File, name string) {
+		vol := target
+		if vol.ID == 0 {
+			vol = f.Volume
+		}
+		to := filepath.Join(vol.Path, filepath.FromSlash(name))
+
+		m := OrganizeMove{
+			FileID:     f.ID,
+			SceneID:    scene.SceneID,
+			From:       f.GetPath(),
+			To:         to,
+			file:       f,
+			toVolume:   vol,
+			toPath:     filepath.Dir(to),
+			toFilename: filepath.Base(to),
+		}
+		if m.From == m.To {
+			return
+		}
+		if planned[to] {
+			m.Error = "another file is moved to the same name"
+		} else if _, err := os.Stat(to); err == nil {
+			m.Error = "target already exists"
+		}
+		planned[to] = true
+		moves = append(moves, m)
+	}
+
+	for _, v := range videos {
+		add(v, newBase[v.ID]+filepath.Ext(v.Filename))
+	}
+
+	sort.Slice(companions, func(i, j int) bool { return companions[i].ID < companions[j].ID })
+	unpaired := 0
+	for _, c := range companions {
+		// pair by name, eg "a.mp4" with "a.funscript", "a.roll.funscript" or "a.en.srt"
+		var best models.File
+		for _, v := range videos {
+			vBase := strings.TrimSuffix(v.Filename, filepath.Ext(v.Filename))
+			if strings.HasPrefix(c.Filename, vBase+".") && len(vBase) > len(strings.TrimSuffix(best.Filename, filepath.Ext(best.Filename))) {
+				best = v
+			}
+		}
+		if best.ID != 0 {
+			tail := strings.TrimPrefix(c.Filename, strings.TrimSuffix(best.Filename, filepath.Ext(best.Filename)))
+			add(c, newBase[best.ID]+tail)
+			continue
+		}
+
+		name := rel
+		if unpaired > 0 {
+			name = fmt.Sprintf("%v (%v)", rel, unpaired+1)
+		}
+		unpaired++
+		add(c, name+filepath.Ext(c.Filename))
+	}
+	return moves
+}
+
+// renderOrganizeTemplate fills in the fields of a template like "{site}/{release_date:2006}/{title}".
+// Dates take an optional Go time layout.
+func renderOrganizeTemplate(template string, scene models.Scene) (string, error) {
+	var cast []string
+	for _, c := range scene.Cast {
+		cast = append(cast, c.Name)
+	}
+	sort.Strings(cast)
+	if len(cast) > 3 {
+		cast = append(cast[:3], "et al")
+	}
+
+	var unknown []string
+	out := organizeField.ReplaceAllStringFunc(template, func(s string) string {
+		m := organizeField.FindStringSubmatch(s)
+		var v string
+		switch m[1] {
+		case "site":
+			v = scene.Site
+		case "studio":
+			v = scene.Studio
+		case "title":
+			v = scene.Title
+		case "cast":
+			v = strings.Join(cast, ", ")
+		case "scene_id":
+			v = scene.SceneID
+		case "release_date":
+			layout := m[2]
+			if layout == "" {
+				layout = "2006-01-02"
+			}
+			if !scene.ReleaseDate.IsZero() {
+				v = scene.ReleaseDate.Format(layout)
+			}
+		default:
+			unknown = append(unknown, m[1])
+		}
+		return sanitizePathSegment(v)
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown template fields: %v", strings.Join(unknown, ", "))
+	}
+
+	// drop empty directories, eg when a scene has no release date
+	var segments []string
+	for _, s := range strings.Split(out, "/") {
+		s = strings.Trim(strings.TrimSpace(s), ".")
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return "", errors.New("organizer template renders an empty path")
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+func sanitizePathSegment(s string) string {
+	s = organizeInvalid.ReplaceAllString(s, "")
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 150 {
+		s = strings.TrimSpace(s[:150])
+	}
+	return s
+}
+
+// moveFile moves a local file on disk and updates its record. Files are copied when the target
+// is on another device.
+func moveFile(db *gorm.DB, f *models.File, toVolumeID uint, toPath string, toFilename string) error {
+	from := filepath.Join(f.Path, f.Filename)
+	to := filepath.Join(toPath, toFilename)
+
+	if _, err := os.Stat(to); err == nil {
+		return errors.New("target already exists")
+	}
+	if err := os.MkdirAll(toPath, 0755); err != nil {
+		return err
+	}
+
+	err := os.Rename(from, to)
+	if errors.Is(err, syscall.EXDEV) {
+		err = copyAndRemove(from, to)
+	}
+	if err != nil {
+		return err
+	}
+
+	fromDir := f.Path
+	fromRoot := f.Volume.Path
+
+	// gorm saves the preloaded volume along and takes volume_id from it, so it has to be the target
+	if f.Volume.ID != toVolumeID {
+		var vol models.Volume
+		if err := db.Where("id = ?", toVolumeID).First(&vol).Error; err != nil {
+			return err
+		}
+		f.Volume = vol
+	}
+	f.VolumeID = toVolumeID
+	f.Path = toPath
+	f.Filename = toFilename
+	if err := models.SaveWithRetry(db, f); err != nil {
+		return err
+	}
+
+	removeEmptyDirs(fromDir, fromRoot)
+	return nil
+}
+
+func copyAndRemove(from string, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
+
+// removeEmptyDirs removes dir and its parents as long as they are empty, stopping at root
+func removeEmptyDirs(dir string, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if empty, err := models.IsDirectoryEmpty(dir); err != nil || !empty {
+			return
+		}
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// rememberFilename adds the new name of a file to its scene, so it is matched again by name
+// if the database is rebuilt
+func rememberFilename(f models.File) {
+	var scene models.Scene
+	if scene.GetIfExistByPK(f.SceneID) != nil {
+		return
+	}
+	var filenames []string
+	json.Unmarshal([]byte(scene.FilenamesArr), &filenames)
+	for _, fn := range filenames {
+		if fn == f.Filename {
+			return
+		}
+	}
+	filenames = append(filenames, f.Filename)
+	tmp, _ := json.Marshal(filenames)
+	scene.FilenamesArr = string(tmp)
+	scene.Save()
+}
@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+func TestOrganizeAcrossVolumes(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.Volume{}, &models.OrganizeJournal{})
+
+	source := models.Volume{Type: "local", Path: t.TempDir(), IsEnabled: true, IsAvailable: true}
+	commonDb.Create(&source)
+	target := models.Volume{Type: "local", Path: t.TempDir(), IsEnabled: true, IsAvailable: true}
+	commonDb.Create(&target)
+
+	scene := models.Scene{SceneID: "organize-1", Site: "Site", Title: "Title"}
+	commonDb.Create(&scene)
+	os.MkdirAll(filepath.Join(source.Path, "in"), 0755)
+	os.WriteFile(filepath.Join(source.Path, "in", "a.mp4"), []byte("video"), 0644)
+	file := models.File{SceneID: scene.ID, VolumeID: source.ID, Type: "video", Path: filepath.Join(source.Path, "in"), Filename: "a.mp4"}
+	commonDb.Create(&file)
+
+	config.Config.Storage.Organizer.Template = "{site}/{title}.{ext}"
+	config.Config.Storage.Organizer.TargetVolume = target.ID
+	defer func() {
+		config.Config.Storage.Organizer.Template = ""
+		config.Config.Storage.Organizer.TargetVolume = 0
+	}()
+
+	result, err := OrganizeFiles([]uint{scene.ID}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Moves) != 1 || result.Moves[0].Error != "" {
+		t.Fatalf("moves %+v, want the video moved", result.Moves)
+	}
+
+	var moved models.File
+	commonDb.First(&moved, file.ID)
+	if moved.VolumeID != target.ID || moved.Path != filepath.Join(target.Path, "Site") || moved.Filename != "Title.mp4" {
+		t.Fatalf("file record %v %v/%v, want it on volume %v", moved.VolumeID, moved.Path, moved.Filename, target.ID)
+	}
+	if _, err := os.Stat(filepath.Join(target.Path, "Site", "Title.mp4")); err != nil {
+		t.Fatal(err)
+	}
+
+	undo, err := UndoOrganize(result.Batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undo.Moves) != 1 || undo.Moves[0].Error != "" {
+		t.Fatalf("undo %+v, want the video moved back", undo.Moves)
+	}
+	commonDb.First(&moved, file.ID)
+	if moved.VolumeID != source.ID || moved.Filename != "a.mp4" {
+		t.Errorf("file record %v %v after undo, want it back on volume %v", moved.VolumeID, moved.Filename, source.ID)
+	}
+	if _, err := os.Stat(filepath.Join(source.Path, "in", "a.mp4")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(target.Path, "Site")); !os.IsNotExist(err) {
+		t.Error("emptied directory on the target volume was left behind")
+	}
+}
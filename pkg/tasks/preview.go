@@ -18,6 +18,7 @@ func GeneratePreviews(endTime *time.Time) {
 	if !models.CheckLock("previews") {
 		models.CreateLock("previews")
 		defer models.RemoveLock("previews")
+		defer common.TimeTask("previews", "")(nil)
 		log.Infof("Generating previews")
 		db, _ := models.GetDB()
 		defer db.Close()
@@ -0,0 +1,72 @@
+package tasks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/models"
+	"github.com/xbapps/xbvr/pkg/scrape"
+)
+
+// scrapeRuns collects the ScrapeRun of each site while a scrape is in progress. A nil
+// *scrapeRuns records nothing, for scrapes that are not site runs.
+type scrapeRuns struct {
+	sync.Mutex
+	runs map[string]*models.ScrapeRun
+}
+
+func newScrapeRuns() *scrapeRuns {
+	scrape.ResetRequestStats()
+	return &scrapeRuns{runs: map[string]*models.ScrapeRun{}}
+}
+
+func (s *scrapeRuns) start(scraper models.Scraper) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	s.runs[scraper.ID] = &models.ScrapeRun{SiteID: scraper.ID, StartedAt: time.Now()}
+}
+
+func (s *scrapeRuns) finish(siteID string, err error) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	if run, ok := s.runs[siteID]; ok {
+		run.FinishedAt = time.Now()
+		if err != nil {
+			run.Error = err.Error()
+		}
+	}
+}
+
+func (s *scrapeRuns) addScene(scene models.ScrapedScene, isNew bool) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	if run, ok := s.runs[scene.ScraperID]; ok {
+		run.AddScene(scene, isNew)
+	}
+}
+
+// save adds the request stats of each site, checks the runs for problems and stores them
+func (s *scrapeRuns) save() {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	for siteID, run := range s.runs {
+		run.Pages, run.HttpErrorCounts = scrape.RequestStatsForScraper(siteID)
+		models.FinishScrapeRun(run)
+	}
+}
@@ -105,12 +105,15 @@ func SearchIndex() {
 	if !models.CheckLock("index") {
 		models.CreateLock("index")
 		defer models.RemoveLock("index")
+		done := common.TimeTask("index", "")
+		defer done(nil)
 
 		tlog := log.WithFields(logrus.Fields{"task": "scrape"})
 
 		idx, err := NewIndex("scenes")
 		if err != nil {
 			log.Error(err)
+			done(err)
 			models.RemoveLock("index")
 			return
 		}
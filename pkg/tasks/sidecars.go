@@ -0,0 +1,377 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+// Kodi and Jellyfin read the details of a video from a movie nfo and artwork next to it. As several
+// videos can share a folder, the sidecars are named after the video: "a.nfo", "a-poster.jpg",
+// "a-fanart.jpg" and "a-fanart1.jpg" onwards for the rest of the gallery. Sidecars are only
+// rewritten when their content changes, images only when the scene got a different one.
+const sidecarGalleryImages = 10
+
+type nfoMovie struct {
+	XMLName    xml.Name    `xml:"movie"`
+	Title      string      `xml:"title"`
+	Plot       string      `xml:"plot,omitempty"`
+	Premiered  string      `xml:"premiered,omitempty"`
+	Year       int         `xml:"year,omitempty"`
+	Studio     string      `xml:"studio,omitempty"`
+	Runtime    int         `xml:"runtime,omitempty"`
+	Ratings    *nfoRatings `xml:"ratings,omitempty"`
+	UserRating int         `xml:"userrating,omitempty"`
+	PlayCount  int         `xml:"playcount,omitempty"`
+	UniqueID   nfoUniqueID `xml:"uniqueid"`
+	Genres     []string    `xml:"genre"`
+	Tags       []string    `xml:"tag"`
+	Actors     []nfoActor  `xml:"actor"`
+	Thumbs     []nfoThumb  `xml:"thumb"`
+	Fanart     *nfoFanart  `xml:"fanart,omitempty"`
+	Trailer    string      `xml:"trailer,omitempty"`
+	DateAdded  string      `xml:"dateadded,omitempty"`
+	Website    string      `xml:"website,omitempty"`
+}
+
+type nfoRatings struct {
+	Rating []nfoRating `xml:"rating"`
+}
+
+type nfoRating struct {
+	Name    string  `xml:"name,attr"`
+	Max     int     `xml:"max,attr"`
+	Default bool    `xml:"default,attr"`
+	Value   float64 `xml:"value"`
+}
+
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	Value   string `xml:",chardata"`
+}
+
+type nfoActor struct {
+	Name  string `xml:"name"`
+	Order int    `xml:"order"`
+	Thumb string `xml:"thumb,omitempty"`
+}
+
+type nfoThumb struct {
+	Aspect string `xml:"aspect,attr,omitempty"`
+	URL    string `xml:",chardata"`
+}
+
+type nfoFanart struct {
+	Thumbs []nfoThumb `xml:"thumb"`
+}
+
+type SidecarResult struct {
+	Files   int `json:"files"`
+	Written int `json:"written"`
+	Images  int `json:"images"`
+	Failed  int `json:"failed"`
+}
+
+// scenes edited while sidecars are written, they are written once the running pass finished
+var (
+	sidecarsMu        sync.Mutex
+	sidecarsQueued    = map[uint]bool{}
+	sidecarsQueuedAll bool
+)
+
+// WriteSidecars writes the nfo and artwork of the video files of the scenes, of all matched
+// scenes when sceneIDs is empty. Only files on local volumes get sidecars. While sidecars are
+// being written the scenes are queued for the running pass, and an empty result is returned.
+func WriteSidecars(sceneIDs []uint) SidecarResult {
+	if !startSidecars(sceneIDs) {
+		return SidecarResult{}
+	}
+	result := writeSidecars(sceneIDs)
+	finishSidecars()
+	return result
+}
+
+// startSidecars takes the sidecars lock, or queues the scenes if it is held
+func startSidecars(sceneIDs []uint) bool {
+	sidecarsMu.Lock()
+	defer sidecarsMu.Unlock()
+
+	if models.CheckLock("sidecars") {
+		if len(sceneIDs) == 0 {
+			sidecarsQueuedAll = true
+		}
+		for _, id := range sceneIDs {
+			sidecarsQueued[id] = true
+		}
+		return false
+	}
+	models.CreateLock("sidecars")
+	return true
+}
+
+// finishSidecars writes the sidecars of the scenes queued during the pass, then releases the lock
+func finishSidecars() {
+	for {
+		sidecarsMu.Lock()
+		if !sidecarsQueuedAll && len(sidecarsQueued) == 0 {
+			models.RemoveLock("sidecars")
+			sidecarsMu.Unlock()
+			return
+		}
+		var sceneIDs []uint
+		if !sidecarsQueuedAll {
+			for id := range sidecarsQueued {
+				sceneIDs = append(sceneIDs, id)
+			}
+		}
+		sidecarsQueued = map[uint]bool{}
+		sidecarsQueuedAll = false
+		sidecarsMu.Unlock()
+
+		writeSidecars(sceneIDs)
+	}
+}
+
+func writeSidecars(sceneIDs []uint) SidecarResult {
+	var result SidecarResult
+	tlog := log.WithFields(logrus.Fields{"task": "sidecars"})
+	if len(sceneIDs) == 0 {
+		tlog.Infof("Writing nfo and artwork sidecars")
+	}
+
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	q := db.Preload("Volume").
+		Joins("join volumes on files.volume_id = volumes.id").
+		Where("files.type = ? and files.scene_id != 0 and volumes.type = ? and volumes.is_available = ?", "video", "local", true)
+	if len(sceneIDs) > 0 {
+		q = q.Where("files.scene_id in (?)", sceneIDs)
+	}
+	var files []models.File
+	q.Order("files.scene_id").Find(&files)
+
+	var scene models.Scene
+	for _, file := range files {
+		if scene.ID != file.SceneID {
+			scene = models.Scene{}
+			if scene.GetIfExistByPK(file.SceneID) != nil {
+				continue
+			}
+			scene.ApplyUserState(models.DefaultUserID)
+		}
+
+		result.Files++
+		written, images, err := writeFileSidecars(file, scene)
+		if err != nil {
+			tlog.Warnf("Unable to write sidecars of %v: %v", file.GetPath(), err)
+			result.Failed++
+			continue
+		}
+		if written {
+			result.Written++
+		}
+		result.Images += images
+	}
+
+	if len(sceneIDs) == 0 || result.Written > 0 {
+		tlog.Infof("Wrote sidecars of %v of %v files, %v images, %v failed", result.Written, result.Files, result.Images, result.Failed)
+	}
+	return result
+}
+
+// UpdateSceneSidecars refreshes the sidecars of edited scenes in the background, if sidecars are kept up to date
+func UpdateSceneSidecars(sceneIDs ...uint) {
+	if config.Config.Storage.Sidecars.Enabled && len(sceneIDs) > 0 {
+		go WriteSidecars(sceneIDs)
+	}
+}
+
+func writeFileSidecars(file models.File, scene models.Scene) (bool, int, error) {
+	base := strings.TrimSuffix(file.GetPath(), filepath.Ext(file.Filename))
+	nfo := buildNFO(scene, file)
+
+	out, err := xml.MarshalIndent(nfo, "", "  ")
+	if err != nil {
+		return false, 0, err
+	}
+	out = append([]byte(xml.Header), out...)
+
+	var previous nfoMovie
+	existing, err := os.ReadFile(base + ".nfo")
+	if err == nil {
+		xml.Unmarshal(existing, &previous)
+	}
+
+	images := 0
+	writeImage := func(name string, url string, oldURL string, size string) error {
+		if url == "" {
+			os.Remove(name)
+			return nil
+		}
+		if _, err := os.Stat(name); err == nil && url == oldURL {
+			return nil
+		}
+		if err := downloadSidecarImage(url, size, name); err != nil {
+			return err
+		}
+		images++
+		return nil
+	}
+
+	if err := writeImage(base+"-poster.jpg", nfo.poster(), previous.poster(), "700x"); err != nil {
+		return false, images, err
+	}
+	newGallery, oldGallery := nfo.gallery(), previous.gallery()
+	for i := 0; i < sidecarGalleryImages; i++ {
+		name := base + "-fanart.jpg"
+		if i > 0 {
+			name = base + "-fanart" + strconv.Itoa(i) + ".jpg"
+		}
+		var url, oldURL string
+		if i < len(newGallery) {
+			url = newGallery[i]
+		}
+		if i < len(oldGallery) {
+			oldURL = oldGallery[i]
+		}
+		if err := writeImage(name, url, oldURL, "1920x"); err != nil {
+			return false, images, err
+		}
+	}
+
+	if !bytes.Equal(existing, out) {
+		if err := os.WriteFile(base+".nfo", out, 0644); err != nil {
+			return false, images, err
+		}
+		return true, images, nil
+	}
+	return false, images, nil
+}
+
+func buildNFO(scene models.Scene, file models.File) nfoMovie {
+	nfo := nfoMovie{
+		Title:     scene.Title,
+		Plot:      scene.Synopsis,
+		Studio:    scene.Studio,
+		Runtime:   scene.Duration,
+		UniqueID:  nfoUniqueID{Type: "xbvr", Default: true, Value: scene.SceneID},
+		Genres:    []string{},
+		Tags:      []string{},
+		Website:   scene.SceneURL,
+		DateAdded: file.CreatedTime.Format("2006-01-02 15:04:05"),
+	}
+	if nfo.Runtime == 0 && file.VideoDuration > 0 {
+		nfo.Runtime = int(file.VideoDuration / 60)
+	}
+	if !scene.ReleaseDate.IsZero() {
+		nfo.Premiered = scene.ReleaseDate.Format("2006-01-02")
+		nfo.Year = scene.ReleaseDate.Year()
+	}
+	if scene.StarRating > 0 {
+		nfo.Ratings = &nfoRatings{Rating: []nfoRating{{Name: "xbvr", Max: 5, Default: true, Value: scene.StarRating}}}
+		nfo.UserRating = int(scene.StarRating * 2)
+	}
+	if scene.IsWatched {
+		nfo.PlayCount = len(scene.History)
+		if nfo.PlayCount == 0 {
+			nfo.PlayCount = 1
+		}
+	}
+	if scene.TrailerType == "url" {
+		nfo.Trailer = scene.TrailerSource
+	}
+
+	for _, tag := range scene.Tags {
+		if strings.HasPrefix(tag.Name, "tag group:") {
+			continue
+		}
+		nfo.Genres = append(nfo.Genres, tag.Name)
+		nfo.Tags = append(nfo.Tags, tag.Name)
+	}
+	for i, actor := range scene.Cast {
+		if strings.HasPrefix(actor.Name, "aka:") {
+			continue
+		}
+		nfo.Actors = append(nfo.Actors, nfoActor{Name: actor.Name, Order: i, Thumb: actor.ImageUrl})
+	}
+
+	if scene.CoverURL != "" {
+		nfo.Thumbs = append(nfo.Thumbs, nfoThumb{Aspect: "poster", URL: scene.CoverURL})
+	}
+	var images []models.Image
+	json.Unmarshal([]byte(scene.Images), &images)
+	for _, img := range images {
+		if img.Type != "gallery" || img.URL == "" {
+			continue
+		}
+		if nfo.Fanart == nil {
+			nfo.Fanart = &nfoFanart{}
+		}
+		if len(nfo.Fanart.Thumbs) < sidecarGalleryImages {
+			nfo.Fanart.Thumbs = append(nfo.Fanart.Thumbs, nfoThumb{URL: img.URL})
+		}
+	}
+	return nfo
+}
+
+func (o nfoMovie) poster() string {
+	for _, t := range o.Thumbs {
+		if t.Aspect == "poster" {
+			return t.URL
+		}
+	}
+	return ""
+}
+
+func (o nfoMovie) gallery() []string {
+	var out []string
+	if o.Fanart != nil {
+		for _, t := range o.Fanart.Thumbs {
+			out = append(out, t.URL)
+		}
+	}
+	return out
+}
+
+// downloadSidecarImage saves an image as jpeg, going through the image proxy so cached images are reused
+func downloadSidecarImage(url string, size string, name string) error {
+	proxyURL := "http://127.0.0.1:" + strconv.Itoa(config.Config.Server.Port) + "/img/" + size + ",jpeg/" + strings.Replace(url, "://", ":/", -1)
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Get(proxyURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("image %v returned %v", url, resp.StatusCode)
+	}
+
+	tmp := name + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	out.Close()
+	return os.Rename(tmp, name)
+}
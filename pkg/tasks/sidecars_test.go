@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+func TestWriteFileSidecars(t *testing.T) {
+	dir := t.TempDir()
+	file := models.File{Path: dir, Filename: "scene.mp4", Type: "video", VideoDuration: 1800, Volume: models.Volume{Type: "local", Path: dir}}
+	scene := models.Scene{
+		SceneID:     "test-1",
+		Title:       "Title",
+		Synopsis:    "Plot",
+		Studio:      "Studio",
+		ReleaseDate: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+		StarRating:  4.5,
+		Tags:        []models.Tag{{Name: "tag"}, {Name: "tag group:group"}},
+		Cast:        []models.Actor{{Name: "Actor", ImageUrl: "https://example.com/actor.jpg"}},
+	}
+
+	written, _, err := writeFileSidecars(file, scene)
+	if err != nil || !written {
+		t.Fatalf("nfo not written: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "scene.nfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nfo nfoMovie
+	if err := xml.Unmarshal(b, &nfo); err != nil {
+		t.Fatal(err)
+	}
+	if nfo.Title != "Title" || nfo.Plot != "Plot" || nfo.Premiered != "2023-05-01" || nfo.Runtime != 30 || nfo.UserRating != 9 {
+		t.Errorf("unexpected nfo %+v", nfo)
+	}
+	if len(nfo.Genres) != 1 || nfo.Genres[0] != "tag" {
+		t.Errorf("genres = %v, want tag", nfo.Genres)
+	}
+	if len(nfo.Actors) != 1 || nfo.Actors[0].Thumb != "https://example.com/actor.jpg" {
+		t.Errorf("actors = %+v", nfo.Actors)
+	}
+
+	// unchanged scenes leave the sidecars alone
+	if written, _, _ := writeFileSidecars(file, scene); written {
+		t.Error("unchanged nfo was written again")
+	}
+	scene.Title = "New title"
+	if written, _, _ := writeFileSidecars(file, scene); !written {
+		t.Error("changed nfo was not written")
+	}
+}
+
+func TestWriteSidecarsQueuedDuringPass(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.Volume{})
+
+	dir := t.TempDir()
+	vol := models.Volume{Type: "local", Path: dir, IsEnabled: true, IsAvailable: true}
+	commonDb.Create(&vol)
+	scene := models.Scene{SceneID: "sidecars-queued", Title: "Queued"}
+	commonDb.Create(&scene)
+	commonDb.Create(&models.File{VolumeID: vol.ID, SceneID: scene.ID, Path: dir, Filename: "queued.mp4", Type: "video"})
+
+	// a full pass is running, the edited scene waits for it
+	if !startSidecars(nil) {
+		t.Fatal("sidecars lock already held")
+	}
+	if result := WriteSidecars([]uint{scene.ID}); result.Files != 0 {
+		t.Errorf("expected the scene to be queued, got %+v", result)
+	}
+	nfo := filepath.Join(dir, "queued.nfo")
+	if _, err := os.Stat(nfo); err == nil {
+		t.Fatal("nfo written while the pass was running")
+	}
+
+	finishSidecars()
+	if _, err := os.Stat(nfo); err != nil {
+		t.Errorf("nfo of the queued scene not written: %v", err)
+	}
+	if models.CheckLock("sidecars") {
+		t.Error("sidecars lock not released")
+	}
+}
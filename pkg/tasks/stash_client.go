@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// stashClient talks to the GraphQL api of a self hosted Stash server
+type stashClient struct {
+	url    string
+	apiKey string
+	http   *http.Client
+
+	// ids of the tags, performers and studios looked up during a sync, by kind and name
+	ids map[string]string
+}
+
+func newStashClient(url string, apiKey string) *stashClient {
+	url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+	if !strings.HasSuffix(url, "/graphql") {
+		url += "/graphql"
+	}
+	return &stashClient{url: url, apiKey: apiKey, http: &http.Client{Timeout: 60 * time.Second}, ids: map[string]string{}}
+}
+
+func (c *stashClient) call(query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("ApiKey", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stash returned %v: %s", resp.StatusCode, b)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("stash: %v", result.Errors[0].Message)
+	}
+	if out != nil {
+		return json.Unmarshal(result.Data, out)
+	}
+	return nil
+}
+
+// findOrCreate returns the id of the tag, performer or studio with the name, creating it if needed
+func (c *stashClient) findOrCreate(kind string, name string) (string, error) {
+	key := kind + ":" + strings.ToLower(name)
+	if id, ok := c.ids[key]; ok {
+		return id, nil
+	}
+
+	// findTags(tag_filter: ...) { tags { id } }, likewise for performers and studios
+	title := strings.ToUpper(kind[:1]) + kind[1:]
+	var found map[string]map[string][]struct {
+		ID string `json:"id"`
+	}
+	query := fmt.Sprintf(`query($name: String!) { find%[1]ss(%[2]s_filter: {name: {value: $name, modifier: EQUALS}}) { %[2]ss { id } } }`, title, kind)
+	if err := c.call(query, map[string]interface{}{"name": name}, &found); err != nil {
+		return "", err
+	}
+	if list := found["find"+title+"s"][kind+"s"]; len(list) > 0 {
+		c.ids[key] = list[0].ID
+		return list[0].ID, nil
+	}
+
+	var created map[string]struct {
+		ID string `json:"id"`
+	}
+	query = fmt.Sprintf(`mutation($name: String!) { %sCreate(input: {name: $name}) { id } }`, kind)
+	if err := c.call(query, map[string]interface{}{"name": name}, &created); err != nil {
+		return "", err
+	}
+	c.ids[key] = created[kind+"Create"].ID
+	return c.ids[key], nil
+}
@@ -0,0 +1,399 @@
+package tasks
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+// Scenes are matched with the scenes of a Stash server by the oshash of their video files, the Stash
+// scene id and what was synced last are kept in an external reference linked to the scene. Scene
+// details, cast, tags, watch sessions and cuepoints (as markers) are pushed to Stash. Ratings and
+// markers are synced both ways: whichever side changed since the last sync wins, xbvr if both did.
+// Ratings and watch sessions are those of the default profile, xbvr has no o-counter to push.
+const stashSceneSource = "stash scene"
+
+type stashSyncState struct {
+	Rating100 int               `json:"rating100"`
+	Plays     int               `json:"plays"`
+	Markers   []stashSyncMarker `json:"markers"`
+}
+
+type stashSyncMarker struct {
+	StashID    string  `json:"stash_id"`
+	CuepointID uint    `json:"cuepoint_id"`
+	Title      string  `json:"title"`
+	Seconds    float64 `json:"seconds"`
+	EndSeconds float64 `json:"end_seconds"`
+}
+
+type stashScene struct {
+	ID           string        `json:"id"`
+	Rating100    *int          `json:"rating100"`
+	SceneMarkers []stashMarker `json:"scene_markers"`
+}
+
+type stashMarker struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Seconds    float64  `json:"seconds"`
+	EndSeconds *float64 `json:"end_seconds"`
+}
+
+type StashSyncResult struct {
+	Scenes    int `json:"scenes"`
+	Matched   int `json:"matched"`
+	Unmatched int `json:"unmatched"`
+	Failed    int `json:"failed"`
+}
+
+func StashSync(url string, apiKey string) {
+	if !models.CheckLock("stash-sync") {
+		models.CreateLock("stash-sync")
+		defer models.RemoveLock("stash-sync")
+
+		tlog := log.WithField("task", "stash-sync")
+		tlog.Infof("Syncing with Stash at %v", url)
+
+		result, err := syncStash(newStashClient(url, apiKey), models.DefaultUserID)
+		if err != nil {
+			tlog.Errorf("Stash sync failed: %v", err)
+			return
+		}
+
+		// the server answered, keep the connection details
+		if config.Config.Vendor.Stash.URL != url || config.Config.Vendor.Stash.ApiKey != apiKey {
+			config.Config.Vendor.Stash.URL = url
+			config.Config.Vendor.Stash.ApiKey = apiKey
+			config.SaveConfig()
+		}
+
+		tlog.Infof("Synced %v of %v scenes with Stash, %v not found in Stash, %v failed", result.Matched, result.Scenes, result.Unmatched, result.Failed)
+	}
+}
+
+func syncStash(c *stashClient, userID uint) (StashSyncResult, error) {
+	var result StashSyncResult
+
+	// fail early when the server can't be reached
+	if err := c.call(`query { version { version } }`, nil, nil); err != nil {
+		return result, err
+	}
+
+	commonDb, _ := models.GetCommonDB()
+	var sceneIDs []uint
+	commonDb.Model(&models.File{}).
+		Where("type = ? and scene_id != 0 and os_hash != ?", "video", "").
+		Group("scene_id").
+		Pluck("scene_id", &sceneIDs)
+
+	for _, id := range sceneIDs {
+		var scene models.Scene
+		if scene.GetIfExistByPK(id) != nil {
+			continue
+		}
+		result.Scenes++
+
+		matched, err := syncStashScene(c, &scene, userID)
+		switch {
+		case err != nil:
+			log.WithField("task", "stash-sync").Warnf("Unable to sync %v: %v", scene.SceneID, err)
+			result.Failed++
+		case matched:
+			result.Matched++
+		default:
+			result.Unmatched++
+		}
+	}
+	return result, nil
+}
+
+// findStashScene returns the linked Stash scene id, or looks the scene up by the oshash of its files
+func findStashScene(c *stashClient, scene *models.Scene) (models.ExternalReference, error) {
+	var link models.ExternalReferenceLink
+	if links := link.FindByExternalSource("scenes", scene.ID, stashSceneSource); len(links) > 0 {
+		return links[0].ExternalReference, nil
+	}
+
+	var extref models.ExternalReference
+	for _, file := range scene.Files {
+		if file.Type != "video" || file.OsHash == "" {
+			continue
+		}
+		var found struct {
+			FindSceneByHash *struct {
+				ID string `json:"id"`
+			} `json:"findSceneByHash"`
+		}
+		err := c.call(`query($input: SceneHashInput!) { findSceneByHash(input: $input) { id } }`,
+			map[string]interface{}{"input": map[string]interface{}{"oshash": file.OsHash}}, &found)
+		if err != nil {
+			return extref, err
+		}
+		if found.FindSceneByHash != nil {
+			extref = models.ExternalReference{ExternalSource: stashSceneSource, ExternalId: found.FindSceneByHash.ID, ExternalURL: strings.TrimSuffix(c.url, "/graphql") + "/scenes/" + found.FindSceneByHash.ID}
+			extref.XbvrLinks = []models.ExternalReferenceLink{{InternalTable: "scenes", InternalDbId: scene.ID, InternalNameId: scene.SceneID,
+				ExternalSource: stashSceneSource, ExternalId: found.FindSceneByHash.ID}}
+			return extref, nil
+		}
+	}
+	return extref, nil
+}
+
+func syncStashScene(c *stashClient, scene *models.Scene, userID uint) (bool, error) {
+	extref, err := findStashScene(c, scene)
+	if err != nil || extref.ExternalId == "" {
+		return false, err
+	}
+
+	var state stashSyncState
+	if extref.ExternalData != "" {
+		json.Unmarshal([]byte(extref.ExternalData), &state)
+	}
+
+	var found struct {
+		FindScene *stashScene `json:"findScene"`
+	}
+	err = c.call(`query($id: ID!) { findScene(id: $id) { id rating100 scene_markers { id title seconds end_seconds } } }`,
+		map[string]interface{}{"id": extref.ExternalId}, &found)
+	if err != nil {
+		return false, err
+	}
+	if found.FindScene == nil {
+		// gone from Stash, match again next time
+		if extref.ID != 0 {
+			commonDb, _ := models.GetCommonDB()
+			commonDb.Where(&models.ExternalReferenceLink{ExternalReferenceID: extref.ID}).Delete(&models.ExternalReferenceLink{})
+			extref.Delete()
+		}
+		return false, nil
+	}
+	stash := found.FindScene
+
+	input, err := stashSceneInput(c, scene)
+	if err != nil {
+		return false, err
+	}
+	input["id"] = stash.ID
+
+	// ratings, the side changed since the last sync wins. Stash ratings are compared in the half
+	// stars xbvr keeps, a pulled 73 would otherwise be pushed back as 70.
+	scene.ApplyUserState(userID)
+	xbvrRating := int(math.Round(scene.StarRating * 20))
+	stashRating := 0
+	if stash.Rating100 != nil {
+		stashRating = int(math.Round(float64(*stash.Rating100)/10)) * 10
+	}
+	if stashRating != state.Rating100 && xbvrRating == state.Rating100 {
+		scene.StarRating = float64(stashRating) / 20
+		scene.SaveUserState(userID)
+		state.Rating100 = stashRating
+	} else if xbvrRating != state.Rating100 || xbvrRating != stashRating {
+		input["rating100"] = xbvrRating
+		state.Rating100 = xbvrRating
+	}
+
+	if err := c.call(`mutation($input: SceneUpdateInput!) { sceneUpdate(input: $input) { id } }`, map[string]interface{}{"input": input}, nil); err != nil {
+		return false, err
+	}
+
+	if err := syncStashPlays(c, scene, stash.ID, userID, &state); err != nil {
+		return false, err
+	}
+	if err := syncStashMarkers(c, scene, stash, &state); err != nil {
+		return false, err
+	}
+
+	b, _ := json.Marshal(state)
+	extref.ExternalData = string(b)
+	extref.ExternalDate = time.Now()
+	extref.AddUpdateWithId()
+	return true, nil
+}
+
+// stashSceneInput builds the details pushed to Stash, looking up or creating the studio, performers and tags
+func stashSceneInput(c *stashClient, scene *models.Scene) (map[string]interface{}, error) {
+	input := map[string]interface{}{
+		"title":   scene.Title,
+		"details": scene.Synopsis,
+	}
+	if scene.ReleaseDateText != "" {
+		input["date"] = scene.ReleaseDateText
+	}
+	if scene.SceneURL != "" {
+		input["urls"] = []string{scene.SceneURL}
+	}
+	if scene.Studio != "" {
+		id, err := c.findOrCreate("studio", scene.Studio)
+		if err != nil {
+			return nil, err
+		}
+		input["studio_id"] = id
+	}
+
+	performers := []string{}
+	for _, actor := range scene.Cast {
+		if strings.HasPrefix(actor.Name, "aka:") {
+			continue
+		}
+		id, err := c.findOrCreate("performer", actor.Name)
+		if err != nil {
+			return nil, err
+		}
+		performers = append(performers, id)
+	}
+	input["performer_ids"] = performers
+
+	tags := []string{}
+	for _, tag := range scene.Tags {
+		if strings.HasPrefix(tag.Name, "tag group:") {
+			continue
+		}
+		id, err := c.findOrCreate("tag", tag.Name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, id)
+	}
+	input["tag_ids"] = tags
+	return input, nil
+}
+
+// syncStashPlays adds the watch sessions that were not pushed yet as plays of the Stash scene
+func syncStashPlays(c *stashClient, scene *models.Scene, stashID string, userID uint, state *stashSyncState) error {
+	commonDb, _ := models.GetCommonDB()
+	var history []models.History
+	commonDb.Where("scene_id = ? and user_id = ?", scene.ID, userID).Order("time_start").Find(&history)
+	if len(history) <= state.Plays {
+		return nil
+	}
+
+	var times []string
+	for _, h := range history[state.Plays:] {
+		times = append(times, h.TimeStart.Format(time.RFC3339))
+	}
+	err := c.call(`mutation($id: ID!, $times: [Timestamp!]) { sceneAddPlay(id: $id, times: $times) { count } }`,
+		map[string]interface{}{"id": stashID, "times": times}, nil)
+	if err != nil {
+		return err
+	}
+	state.Plays = len(history)
+	return nil
+}
+
+// syncStashMarkers syncs cuepoints and markers both ways, using the pairs of the last sync to tell
+// which side added, changed or removed one
+func syncStashMarkers(c *stashClient, scene *models.Scene, stash *stashScene, state *stashSyncState) error {
+	commonDb, _ := models.GetCommonDB()
+
+	cuepoints := map[uint]models.SceneCuepoint{}
+	for _, cp := range scene.Cuepoints {
+		cuepoints[cp.ID] = cp
+	}
+	markers := map[string]stashMarker{}
+	for _, m := range stash.SceneMarkers {
+		markers[m.ID] = m
+	}
+
+	saveMarker := func(markerID string, cp models.SceneCuepoint) (string, error) {
+		tagID, err := c.findOrCreate("tag", cp.Name)
+		if err != nil {
+			return "", err
+		}
+		input := map[string]interface{}{"scene_id": stash.ID, "title": cp.Name, "seconds": cp.TimeStart, "primary_tag_id": tagID}
+		if cp.TimeEnd > 0 {
+			input["end_seconds"] = cp.TimeEnd
+		}
+		if markerID == "" {
+			var created struct {
+				SceneMarkerCreate struct {
+					ID string `json:"id"`
+				} `json:"sceneMarkerCreate"`
+			}
+			err = c.call(`mutation($input: SceneMarkerCreateInput!) { sceneMarkerCreate(input: $input) { id } }`, map[string]interface{}{"input": input}, &created)
+			return created.SceneMarkerCreate.ID, err
+		}
+		input["id"] = markerID
+		return markerID, c.call(`mutation($input: SceneMarkerUpdateInput!) { sceneMarkerUpdate(input: $input) { id } }`, map[string]interface{}{"input": input}, nil)
+	}
+	pair := func(markerID string, cp models.SceneCuepoint) stashSyncMarker {
+		return stashSyncMarker{StashID: markerID, CuepointID: cp.ID, Title: cp.Name, Seconds: cp.TimeStart, EndSeconds: cp.TimeEnd}
+	}
+	cuepointFromMarker := func(cp models.SceneCuepoint, m stashMarker) models.SceneCuepoint {
+		cp.SceneID = scene.ID
+		cp.Name = m.Title
+		cp.TimeStart = m.Seconds
+		cp.TimeEnd = 0
+		if m.EndSeconds != nil {
+			cp.TimeEnd = *m.EndSeconds
+		}
+		cp.Save()
+		return cp
+	}
+
+	var synced []stashSyncMarker
+	for _, p := range state.Markers {
+		cp, cpOk := cuepoints[p.CuepointID]
+		m, mOk := markers[p.StashID]
+		delete(cuepoints, p.CuepointID)
+		delete(markers, p.StashID)
+
+		switch {
+		case !cpOk && !mOk:
+		case !cpOk:
+			// removed in xbvr
+			if err := c.call(`mutation($id: ID!) { sceneMarkerDestroy(id: $id) }`, map[string]interface{}{"id": p.StashID}, nil); err != nil {
+				return err
+			}
+		case !mOk:
+			// removed in Stash
+			commonDb.Delete(&models.SceneCuepoint{}, cp.ID)
+		default:
+			end := 0.0
+			if m.EndSeconds != nil {
+				end = *m.EndSeconds
+			}
+			cpChanged := cp.Name != p.Title || !sameSeconds(cp.TimeStart, p.Seconds) || !sameSeconds(cp.TimeEnd, p.EndSeconds)
+			markerChanged := m.Title != p.Title || !sameSeconds(m.Seconds, p.Seconds) || !sameSeconds(end, p.EndSeconds)
+			if markerChanged && !cpChanged {
+				cp = cuepointFromMarker(cp, m)
+			} else if cpChanged {
+				if _, err := saveMarker(m.ID, cp); err != nil {
+					return err
+				}
+			}
+			synced = append(synced, pair(m.ID, cp))
+		}
+	}
+
+	// added since the last sync, in a stable order
+	var newCuepoints []models.SceneCuepoint
+	for _, cp := range cuepoints {
+		newCuepoints = append(newCuepoints, cp)
+	}
+	sort.Slice(newCuepoints, func(i, j int) bool { return newCuepoints[i].ID < newCuepoints[j].ID })
+	for _, cp := range newCuepoints {
+		id, err := saveMarker("", cp)
+		if err != nil {
+			return err
+		}
+		synced = append(synced, pair(id, cp))
+	}
+	for _, m := range stash.SceneMarkers {
+		if _, ok := markers[m.ID]; ok {
+			synced = append(synced, pair(m.ID, cuepointFromMarker(models.SceneCuepoint{}, m)))
+		}
+	}
+
+	state.Markers = synced
+	return nil
+}
+
+func sameSeconds(a float64, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
@@ -0,0 +1,204 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+func TestMain(m *testing.M) {
+	appDir, _ := os.MkdirTemp("", "xbvr-test")
+	os.Setenv("XBVR_APPDIR", appDir)
+	models.Init()
+
+	// the tables a sync reads and writes
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.Scene{}, &models.File{}, &models.SceneCuepoint{}, &models.History{}, &models.UserScene{},
+		&models.Tag{}, &models.Actor{}, &models.ExternalReference{}, &models.ExternalReferenceLink{}, &models.KV{})
+
+	code := m.Run()
+	os.RemoveAll(appDir)
+	os.Exit(code)
+}
+
+// stashStub answers the GraphQL requests of a sync from memory, looking at the operation named in the query
+type stashStub struct {
+	sync.Mutex
+	hashes    map[string]string
+	rating100 map[string]int
+	markers   map[string][]stashMarker
+	plays     map[string]int
+	updates   int
+	nextID    int
+}
+
+func newStashStub(t *testing.T) (*stashStub, *httptest.Server) {
+	stub := &stashStub{hashes: map[string]string{}, rating100: map[string]int{}, markers: map[string][]stashMarker{}, plays: map[string]int{}, nextID: 100}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Header.Get("ApiKey") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": stub.handle(req.Query, req.Variables)})
+	}))
+	t.Cleanup(srv.Close)
+	return stub, srv
+}
+
+func (s *stashStub) id() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+func (s *stashStub) handle(query string, vars map[string]interface{}) map[string]interface{} {
+	s.Lock()
+	defer s.Unlock()
+
+	input, _ := vars["input"].(map[string]interface{})
+	switch {
+	case strings.Contains(query, "version"):
+		return map[string]interface{}{"version": map[string]interface{}{"version": "v0.25.0"}}
+	case strings.Contains(query, "findSceneByHash"):
+		if id, ok := s.hashes[input["oshash"].(string)]; ok {
+			return map[string]interface{}{"findSceneByHash": map[string]interface{}{"id": id}}
+		}
+		return map[string]interface{}{"findSceneByHash": nil}
+	case strings.Contains(query, "findScene("):
+		id := vars["id"].(string)
+		return map[string]interface{}{"findScene": map[string]interface{}{"id": id, "rating100": s.rating100[id], "scene_markers": s.markers[id]}}
+	case strings.Contains(query, "findTags"), strings.Contains(query, "findPerformers"), strings.Contains(query, "findStudios"):
+		return map[string]interface{}{}
+	case strings.Contains(query, "Create(input: {name"):
+		return map[string]interface{}{"tagCreate": map[string]interface{}{"id": s.id()}, "performerCreate": map[string]interface{}{"id": s.id()}, "studioCreate": map[string]interface{}{"id": s.id()}}
+	case strings.Contains(query, "sceneUpdate"):
+		s.updates++
+		if r, ok := input["rating100"]; ok {
+			s.rating100[input["id"].(string)] = int(r.(float64))
+		}
+		return map[string]interface{}{"sceneUpdate": map[string]interface{}{"id": input["id"]}}
+	case strings.Contains(query, "sceneAddPlay"):
+		s.plays[vars["id"].(string)] += len(vars["times"].([]interface{}))
+		return map[string]interface{}{"sceneAddPlay": map[string]interface{}{"count": s.plays[vars["id"].(string)]}}
+	case strings.Contains(query, "sceneMarkerCreate"):
+		sceneID := input["scene_id"].(string)
+		m := stashMarker{ID: s.id(), Title: input["title"].(string), Seconds: input["seconds"].(float64)}
+		s.markers[sceneID] = append(s.markers[sceneID], m)
+		return map[string]interface{}{"sceneMarkerCreate": map[string]interface{}{"id": m.ID}}
+	case strings.Contains(query, "sceneMarkerUpdate"):
+		sceneID := input["scene_id"].(string)
+		for i, m := range s.markers[sceneID] {
+			if m.ID == input["id"] {
+				s.markers[sceneID][i].Title = input["title"].(string)
+				s.markers[sceneID][i].Seconds = input["seconds"].(float64)
+			}
+		}
+		return map[string]interface{}{"sceneMarkerUpdate": map[string]interface{}{"id": input["id"]}}
+	case strings.Contains(query, "sceneMarkerDestroy"):
+		for sceneID, markers := range s.markers {
+			var kept []stashMarker
+			for _, m := range markers {
+				if m.ID != vars["id"] {
+					kept = append(kept, m)
+				}
+			}
+			s.markers[sceneID] = kept
+		}
+		return map[string]interface{}{"sceneMarkerDestroy": true}
+	}
+	return nil
+}
+
+func TestStashSync(t *testing.T) {
+	stub, srv := newStashStub(t)
+	stub.hashes["0123456789abcdef"] = "1"
+
+	commonDb, _ := models.GetCommonDB()
+	scene := models.Scene{SceneID: "stash-test-1", Title: "Synced", Studio: "Studio", Cast: []models.Actor{{Name: "Actor"}}, Tags: []models.Tag{{Name: "tag"}}}
+	commonDb.Create(&scene)
+	commonDb.Create(&models.File{SceneID: scene.ID, Type: "video", Filename: "synced.mp4", OsHash: "0123456789abcdef"})
+	commonDb.Create(&models.SceneCuepoint{SceneID: scene.ID, Name: "intro", TimeStart: 10})
+	commonDb.Create(&models.History{UserID: models.DefaultUserID, SceneID: scene.ID, TimeStart: time.Now().Add(-time.Hour), TimeEnd: time.Now()})
+	scene.StarRating = 4
+	scene.SaveUserState(models.DefaultUserID)
+
+	unmatched := models.Scene{SceneID: "stash-test-2", Title: "Not in Stash"}
+	commonDb.Create(&unmatched)
+	commonDb.Create(&models.File{SceneID: unmatched.ID, Type: "video", Filename: "other.mp4", OsHash: "fedcba9876543210"})
+
+	c := newStashClient(srv.URL+"/", "key")
+	result, err := syncStash(c, models.DefaultUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 1 || result.Unmatched != 1 || result.Failed != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// pushed to Stash
+	if stub.rating100["1"] != 80 {
+		t.Errorf("rating100 = %v, want 80", stub.rating100["1"])
+	}
+	if len(stub.markers["1"]) != 1 || stub.markers["1"][0].Title != "intro" {
+		t.Errorf("markers = %+v, want the intro cuepoint", stub.markers["1"])
+	}
+	if stub.plays["1"] != 1 {
+		t.Errorf("plays = %v, want 1", stub.plays["1"])
+	}
+
+	// changed in Stash, pulled back
+	stub.rating100["1"] = 60
+	stub.markers["1"] = append(stub.markers["1"], stashMarker{ID: "200", Title: "outro", Seconds: 100})
+	if _, err := syncStash(c, models.DefaultUserID); err != nil {
+		t.Fatal(err)
+	}
+
+	var synced models.Scene
+	synced.GetIfExistByPK(scene.ID)
+	synced.ApplyUserState(models.DefaultUserID)
+	if synced.StarRating != 3 {
+		t.Errorf("star rating = %v, want 3", synced.StarRating)
+	}
+	if len(synced.Cuepoints) != 2 {
+		t.Errorf("cuepoints = %+v, want intro and outro", synced.Cuepoints)
+	}
+	if stub.plays["1"] != 1 {
+		t.Errorf("plays = %v, want the session pushed once", stub.plays["1"])
+	}
+
+	// finer than half stars in Stash, pulled rounded and not pushed back
+	stub.rating100["1"] = 73
+	for i := 0; i < 2; i++ {
+		if _, err := syncStash(c, models.DefaultUserID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	synced.ApplyUserState(models.DefaultUserID)
+	if synced.StarRating != 3.5 {
+		t.Errorf("star rating = %v, want 3.5", synced.StarRating)
+	}
+	if stub.rating100["1"] != 73 {
+		t.Errorf("rating100 = %v, want Stash's 73 kept", stub.rating100["1"])
+	}
+
+	// removed in xbvr, removed from Stash
+	commonDb.Where("scene_id = ? and name = ?", scene.ID, "intro").Delete(&models.SceneCuepoint{})
+	if _, err := syncStash(c, models.DefaultUserID); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.markers["1"]) != 1 || stub.markers["1"][0].Title != "outro" {
+		t.Errorf("markers = %+v, want only outro", stub.markers["1"])
+	}
+}
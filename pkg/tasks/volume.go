@@ -11,11 +11,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/djherbis/times"
 	"github.com/jinzhu/gorm"
 	"github.com/markphelps/optional"
+	"github.com/minio/minio-go/v7"
 	"github.com/sirupsen/logrus"
 	"github.com/thoas/go-funk"
 	"github.com/xbapps/xbvr/pkg/common"
@@ -23,6 +25,7 @@ import (
 	"github.com/xbapps/xbvr/pkg/ffprobe"
 	"github.com/xbapps/xbvr/pkg/models"
 	"github.com/xbapps/xbvr/pkg/scrape"
+	"github.com/xbapps/xbvr/pkg/webdav"
 )
 
 // The allowed video extensions are set in the config.go file as they now are user configurable
@@ -34,9 +37,20 @@ func getAllowedVideoExt() []string {
 }
 
 func RescanVolumes(id int) {
+	rescanVolumes(id, false)
+}
+
+// RescanUnwatchedVolumes is used by the periodic rescan, it skips local volumes that are kept
+// up to date by a filesystem watcher
+func RescanUnwatchedVolumes() {
+	rescanVolumes(-1, true)
+}
+
+func rescanVolumes(id int, skipWatched bool) {
 	if !models.CheckLock("rescan") {
 		models.CreateLock("rescan")
 		defer models.RemoveLock("rescan")
+		defer common.TimeTask("rescan", "")(nil)
 
 		tlog := log.WithFields(logrus.Fields{"task": "rescan"})
 		tlog.Infof("Start scanning volumes")
@@ -58,121 +72,34 @@ func RescanVolumes(id int) {
 
 			switch vol[i].Type {
 			case "local":
+				if skipWatched && IsVolumeWatched(vol[i].ID) {
+					tlog.Infof("Skipping %v, volume is being watched for changes", vol[i].Path)
+					continue
+				}
 				scanLocalVolume(vol[i], db, tlog)
 			case "putio":
 				scanPutIO(vol[i], db, tlog)
+			case "webdav":
+				scanWebDAV(vol[i], db, tlog)
+			case "s3":
+				scanS3(vol[i], db, tlog)
 			}
 		}
 
 		// Match Scene to File
 		var files []models.File
-		var scenes []models.Scene
-		var extrefs []models.ExternalReference
-
 		tlog.Infof("Matching Scenes to known filenames")
 		db.Model(&models.File{}).Where("files.scene_id = 0").Find(&files)
-
-		escape := func(s string) string {
-			var buffer bytes.Buffer
-			json.HTMLEscape(&buffer, []byte(s))
-			return buffer.String()
-		}
-
-		for i := range files {
-			unescapedFilename := path.Base(files[i].Filename)
-			filename := escape(unescapedFilename)
-			filename2 := strings.Replace(filename, ".funscript", ".mp4", -1)
-			filename3 := strings.Replace(filename, ".hsp", ".mp4", -1)
-			filename4 := strings.Replace(filename, ".srt", ".mp4", -1)
-			filename5 := strings.Replace(filename, ".cmscript", ".mp4", -1)
-			err := db.Where("filenames_arr LIKE ? OR filenames_arr LIKE ? OR filenames_arr LIKE ? OR filenames_arr LIKE ? OR filenames_arr LIKE ?", `%"`+filename+`"%`, `%"`+filename2+`"%`, `%"`+filename3+`"%`, `%"`+filename4+`"%`, `%"`+filename5+`"%`).Find(&scenes).Error
-			if err != nil {
-				log.Error(err, " when matching "+unescapedFilename)
-			}
-			if len(scenes) == 0 && config.Config.Advanced.UseAltSrcInFileMatching {
-				// check if the filename matches in external_reference record
-
-				db.Preload("XbvrLinks").Where("external_source like 'alternate scene %' and external_data LIKE ? OR external_data LIKE ? OR external_data LIKE ? OR external_data LIKE ? OR external_data LIKE ?", `%"`+filename+`%`, `%"`+filename2+`%`, `%"`+filename3+`%`, `%"`+filename4+`%`, `%"`+filename5+`%`).Find(&extrefs)
-				if len(extrefs) == 1 {
-					if len(extrefs[0].XbvrLinks) == 1 {
-						// the scene id will be the Internal DB Id from the associated link
-						var scene models.Scene
-						scene.GetIfExistByPK(extrefs[0].XbvrLinks[0].InternalDbId)
-						// Add File to the list of Scene filenames
-						var pfTxt []string
-						err = json.Unmarshal([]byte(scene.FilenamesArr), &pfTxt)
-						if err != nil {
-							continue
-						}
-						pfTxt = append(pfTxt, files[i].Filename)
-						tmp, err := json.Marshal(pfTxt)
-						if err == nil {
-							scene.FilenamesArr = string(tmp)
-						}
-						scene.Save()
-						scenes = append(scenes, scene)
-					}
-				}
-			}
-			if len(scenes) == 1 {
-				files[i].SceneID = scenes[0].ID
-				files[i].Save()
-				scenes[0].UpdateStatus()
-			} else {
-				if config.Config.Storage.MatchOhash && config.Config.Advanced.StashApiKey != "" {
-					hash := files[i].OsHash
-					if len(hash) < 16 {
-						// the has in xbvr is sometiomes < 16 pad with zeros
-						paddingLength := 16 - len(hash)
-						hash = strings.Repeat("0", paddingLength) + hash
-					}
-					queryVariable := `
-				{"input":{
-					"fingerprints": {					
-						"value": "` + hash + `",
-						"modifier": "INCLUDES"
-					},				
-					"page": 1
-				}
-				}`
-					// call Stashdb graphql searching for os_hash
-					stashMatches := scrape.GetScenePage(queryVariable)
-					for _, match := range stashMatches.Data.QueryScenes.Scenes {
-						if match.ID != "" {
-							var externalRefLink models.ExternalReferenceLink
-							db.Where(&models.ExternalReferenceLink{ExternalSource: "stashdb scene", ExternalId: match.ID}).First(&externalRefLink)
-							if externalRefLink.ID != 0 {
-								files[i].SceneID = externalRefLink.InternalDbId
-								files[i].Save()
-								var scene models.Scene
-								scene.GetIfExistByPK(externalRefLink.InternalDbId)
-
-								// add filename tyo the array
-								var pfTxt []string
-								json.Unmarshal([]byte(scene.FilenamesArr), &pfTxt)
-								pfTxt = append(pfTxt, files[i].Filename)
-								tmp, _ := json.Marshal(pfTxt)
-								scene.FilenamesArr = string(tmp)
-								scene.Save()
-								models.AddAction(scene.SceneID, "match", "filenames_arr", scene.FilenamesArr)
-
-								scene.UpdateStatus()
-								log.Infof("File %s matched to Scene %s matched using stashdb hash %s", path.Base(files[i].Filename), scene.SceneID, hash)
-							}
-						}
-					}
-				}
-			}
-
-			if (i % 50) == 0 {
-				tlog.Infof("Matching Scenes to known filenames (%v/%v)", i+1, len(files))
-			}
-		}
+		matchFilesToScenes(db, files, tlog)
 
 		tlog.Infof("Generating heatmaps")
 
 		GenerateHeatmaps(tlog)
 
+		if config.Config.Storage.Sidecars.Enabled {
+			WriteSidecars(nil)
+		}
+
 		tlog.Infof("Scanning complete")
 
 		// Inform UI about state change
@@ -211,11 +138,107 @@ func RescanVolumes(id int) {
 	}
 }
 
+// matchFilesToScenes links unmatched files to scenes by filename, alternate sources, the
+// StashDB oshash fingerprint lookup or finally by their content
+func matchFilesToScenes(db *gorm.DB, files []models.File, tlog *logrus.Entry) {
+	var scenes []models.Scene
+	var extrefs []models.ExternalReference
+
+	escape := func(s string) string {
+		var buffer bytes.Buffer
+		json.HTMLEscape(&buffer, []byte(s))
+		return buffer.String()
+	}
+
+	for i := range files {
+		unescapedFilename := path.Base(files[i].Filename)
+		filename := escape(unescapedFilename)
+		filename2 := strings.Replace(filename, ".funscript", ".mp4", -1)
+		filename3 := strings.Replace(filename, ".hsp", ".mp4", -1)
+		filename4 := strings.Replace(filename, ".srt", ".mp4", -1)
+		filename5 := strings.Replace(filename, ".cmscript", ".mp4", -1)
+		err := db.Where("filenames_arr LIKE ? OR filenames_arr LIKE ? OR filenames_arr LIKE ? OR filenames_arr LIKE ? OR filenames_arr LIKE ?", `%"`+filename+`"%`, `%"`+filename2+`"%`, `%"`+filename3+`"%`, `%"`+filename4+`"%`, `%"`+filename5+`"%`).Find(&scenes).Error
+		if err != nil {
+			log.Error(err, " when matching "+unescapedFilename)
+		}
+		if len(scenes) == 0 && config.Config.Advanced.UseAltSrcInFileMatching {
+			// check if the filename matches in external_reference record
+
+			db.Preload("XbvrLinks").Where("external_source like 'alternate scene %' and external_data LIKE ? OR external_data LIKE ? OR external_data LIKE ? OR external_data LIKE ? OR external_data LIKE ?", `%"`+filename+`%`, `%"`+filename2+`%`, `%"`+filename3+`%`, `%"`+filename4+`%`, `%"`+filename5+`%`).Find(&extrefs)
+			if len(extrefs) == 1 {
+				if len(extrefs[0].XbvrLinks) == 1 {
+					// the scene id will be the Internal DB Id from the associated link
+					var scene models.Scene
+					scene.GetIfExistByPK(extrefs[0].XbvrLinks[0].InternalDbId)
+					// Add File to the list of Scene filenames
+					var pfTxt []string
+					err = json.Unmarshal([]byte(scene.FilenamesArr), &pfTxt)
+					if err != nil {
+						continue
+					}
+					pfTxt = append(pfTxt, files[i].Filename)
+					tmp, err := json.Marshal(pfTxt)
+					if err == nil {
+						scene.FilenamesArr = string(tmp)
+					}
+					scene.Save()
+					scenes = append(scenes, scene)
+				}
+			}
+		}
+		if len(scenes) == 1 {
+			files[i].SceneID = scenes[0].ID
+			files[i].Save()
+			scenes[0].UpdateStatus()
+		} else {
+			if config.Config.Storage.MatchOhash && config.Config.Advanced.StashApiKey != "" {
+				hash := files[i].OsHash
+				if len(hash) < 16 {
+					// the has in xbvr is sometiomes < 16 pad with zeros
+					paddingLength := 16 - len(hash)
+					hash = strings.Repeat("0", paddingLength) + hash
+				}
+				queryVariable := `
+			{"input":{
+				"fingerprints": {					
+					"value": "` + hash + `",
+					"modifier": "INCLUDES"
+				},				
+				"page": 1
+			}
+			}`
+				// call Stashdb graphql searching for os_hash
+				stashMatches := scrape.GetScenePage(queryVariable)
+				for _, match := range stashMatches.Data.QueryScenes.Scenes {
+					if match.ID != "" {
+						var externalRefLink models.ExternalReferenceLink
+						db.Where(&models.ExternalReferenceLink{ExternalSource: "stashdb scene", ExternalId: match.ID}).First(&externalRefLink)
+						if externalRefLink.ID != 0 {
+							scene := linkFileToScene(db, &files[i], externalRefLink.InternalDbId)
+							log.Infof("File %s matched to Scene %s matched using stashdb hash %s", path.Base(files[i].Filename), scene.SceneID, hash)
+						}
+					}
+				}
+			}
+		}
+
+		if (i % 50) == 0 {
+			tlog.Infof("Matching Scenes to known filenames (%v/%v)", i+1, len(files))
+		}
+	}
+
+	if config.Config.Storage.MatchContent {
+		matchFilesByContent(db, files, tlog)
+	}
+}
+
 func scanLocalVolume(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
 	allowedVideoExt := getAllowedVideoExt()
 	if vol.IsMounted() {
+		// start watching before the walk, so changes made while walking are not missed
+		watchVolume(vol)
 
-		var videoProcList []string
+		var videoProcList []videoScanJob
 		var scriptProcList []string
 		var hspProcList []string
 		var subtitlesProcList []string
@@ -225,156 +248,28 @@ func scanLocalVolume(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
 			}
 			if !f.Mode().IsDir() {
 				// Make sure the filename should be considered
-				if !strings.HasPrefix(filepath.Base(path), ".") && funk.Contains(allowedVideoExt, strings.ToLower(filepath.Ext(path))) {
-					var fl models.File
-					err = db.Where(&models.File{Path: filepath.Dir(path), Filename: filepath.Base(path)}).First(&fl).Error
-
-					if err == gorm.ErrRecordNotFound || fl.VolumeID == 0 || fl.VideoDuration == 0 || fl.VideoProjection == "" || fl.Size != f.Size() || fl.OsHash == "" {
-						videoProcList = append(videoProcList, path)
+				switch localFileType(path, allowedVideoExt) {
+				case "video":
+					if fl, ok := videoNeedsScan(db, path, f); ok {
+						videoProcList = append(videoProcList, videoScanJob{path: path, file: fl})
 					}
-				}
-
-				if !strings.HasPrefix(filepath.Base(path), ".") && (filepath.Ext(path) == ".funscript" || strings.ToLower(filepath.Ext(path)) == ".cmscript") {
+				case "script":
 					scriptProcList = append(scriptProcList, path)
-				}
-				if !strings.HasPrefix(filepath.Base(path), ".") && filepath.Ext(path) == ".hsp" {
+				case "hsp":
 					hspProcList = append(hspProcList, path)
-				}
-				if !strings.HasPrefix(filepath.Base(path), ".") && (filepath.Ext(path) == ".srt" || filepath.Ext(path) == ".ssa" || filepath.Ext(path) == ".ass") {
+				case "subtitles":
 					subtitlesProcList = append(subtitlesProcList, path)
 				}
 			}
 			return nil
 		})
 
-		filenameSeparator := regexp.MustCompile("[ _.-]+")
-
-		for j, path := range videoProcList {
-			fStat, _ := os.Stat(path)
-			fTimes, err := times.Stat(path)
-			if err != nil {
-				tlog.Errorf("Can't get the modification/creation times for %s, error: %s", path, err)
-			}
-
-			var birthtime time.Time
-			if fTimes.HasBirthTime() {
-				birthtime = fTimes.BirthTime()
-			} else {
-				birthtime = fTimes.ModTime()
-			}
-			var fl models.File
-			db.Where(&models.File{
-				Path:     filepath.Dir(path),
-				Filename: filepath.Base(path),
-				Type:     "video",
-			}).FirstOrCreate(&fl)
-
-			fl.Size = fStat.Size()
-			fl.CreatedTime = birthtime
-			fl.UpdatedTime = fTimes.ModTime()
-			fl.VolumeID = vol.ID
-
-			hash, err := Hash(path)
-			if err == nil {
-				fl.OsHash = fmt.Sprintf("%x", hash)
-			}
-
-			ffdata, err := ffprobe.GetProbeData(path, time.Second*5)
-			if err != nil {
-				tlog.Error("Error running ffprobe", path, err)
-			} else {
-				vs := ffdata.GetFirstVideoStream()
-				if vs == nil {
-					tlog.Error("No video stream in file ", path)
-				} else {
-					if vs.BitRate != "" {
-						bitRate, _ := strconv.Atoi(vs.BitRate)
-						fl.VideoBitRate = bitRate
-					}
-					fl.VideoAvgFrameRate = vs.AvgFrameRate
-					fl.VideoCodecName = vs.CodecName
-					fl.VideoWidth = vs.Width
-					fl.VideoHeight = vs.Height
-					if dur, err := strconv.ParseFloat(vs.Duration, 64); err == nil {
-						fl.VideoDuration = dur
-					} else if ffdata.Format.DurationSeconds > 0.0 {
-						fl.VideoDuration = ffdata.Format.DurationSeconds
-					}
-					fl.HasAlpha = false
-
-					if vs.Height*2 == vs.Width || vs.Width > vs.Height {
-						fl.VideoProjection = "180_sbs"
-						nameparts := filenameSeparator.Split(strings.ToLower(filepath.Base(path)), -1)
-						for i, part := range nameparts {
-							if part == "mkx200" || part == "mkx220" || part == "rf52" || part == "fisheye190" || part == "vrca220" || part == "flat" {
-								fl.VideoProjection = part
-								break
-							} else if part == "fisheye" || part == "f180" || part == "180f" {
-								fl.VideoProjection = "fisheye"
-								break
-							} else if i < len(nameparts)-1 && (part+"_"+nameparts[i+1] == "mono_360" || part+"_"+nameparts[i+1] == "mono_180") {
-								fl.VideoProjection = nameparts[i+1] + "_mono"
-								break
-							} else if i < len(nameparts)-1 && (part+"_"+nameparts[i+1] == "360_mono" || part+"_"+nameparts[i+1] == "180_mono") {
-								fl.VideoProjection = part + "_mono"
-								break
-							}
-						}
-						if fl.VideoProjection == "mkx200" || fl.VideoProjection == "mkx220" || fl.VideoProjection == "rf52" || fl.VideoProjection == "fisheye190" || fl.VideoProjection == "vrca220" {
-							// alpha passthrough only works with fisheye projections
-							for _, part := range nameparts {
-								if part == "alpha" {
-									fl.HasAlpha = true
-									break
-								}
-							}
-						}
-					}
-
-					if vs.Height == vs.Width {
-						fl.VideoProjection = "360_tb"
-					}
-
-					fl.CalculateFramerate()
-				}
-			}
-
-			err = fl.Save()
-			if err != nil {
-				tlog.Errorf("New file %s, but got error %s", path, err)
-			}
-
-			tlog.Infof("Scanning %v (%v/%v)", vol.Path, j+1, len(videoProcList))
-		}
+		scanVideoFiles(videoProcList, vol, func(job *videoScanJob) error {
+			return probeLocalVideoFile(job.path, &job.file, vol, tlog)
+		}, tlog)
 
 		for _, path := range scriptProcList {
-			var fl models.File
-			db.Where(&models.File{
-				Path:     filepath.Dir(path),
-				Filename: filepath.Base(path),
-				Type:     "script",
-			}).FirstOrCreate(&fl)
-
-			fStat, _ := os.Stat(path)
-			fTimes, _ := times.Stat(path)
-
-			if fStat.Size() != fl.Size {
-				fl.Size = fStat.Size()
-				fl.HasHeatmap = false
-				fl.VideoDuration = 0.0
-			}
-
-			if fl.VideoDuration < 0.01 {
-				duration, err := getFunscriptDuration(path)
-				if err == nil {
-					fl.VideoDuration = duration
-				}
-			}
-
-			fl.CreatedTime = fTimes.ModTime()
-			fl.UpdatedTime = fTimes.ModTime()
-			fl.VolumeID = vol.ID
-			fl.Save()
+			scanLocalScriptFile(path, vol.ID, db)
 		}
 
 		for _, path := range hspProcList {
@@ -404,6 +299,282 @@ func scanLocalVolume(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
 	}
 }
 
+// localFileType classifies a path found on a local volume by the kind of models.File it
+// should become, or returns an empty string if the file should be ignored
+func localFileType(path string, allowedVideoExt []string) string {
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	switch {
+	case funk.Contains(allowedVideoExt, strings.ToLower(ext)):
+		return "video"
+	case ext == ".funscript" || strings.ToLower(ext) == ".cmscript":
+		return "script"
+	case ext == ".hsp":
+		return "hsp"
+	case ext == ".srt" || ext == ".ssa" || ext == ".ass":
+		return "subtitles"
+	}
+	return ""
+}
+
+// videoNeedsScan reports whether a video file is new, changed or still missing probe data. The
+// stored file is returned too, so the probe doesn't need to look it up again.
+func videoNeedsScan(db *gorm.DB, path string, f os.FileInfo) (models.File, bool) {
+	var fl models.File
+	err := db.Where(&models.File{Path: filepath.Dir(path), Filename: filepath.Base(path)}).First(&fl).Error
+	if err == gorm.ErrRecordNotFound {
+		fl = models.File{Path: filepath.Dir(path), Filename: filepath.Base(path), Type: "video"}
+	}
+	return fl, err == gorm.ErrRecordNotFound || fl.VolumeID == 0 || fl.VideoDuration == 0 || fl.VideoProjection == "" || fl.Size != f.Size() || fl.OsHash == ""
+}
+
+var filenameSeparator = regexp.MustCompile("[ _.-]+")
+
+type videoScanJob struct {
+	path string
+	file models.File
+}
+
+// scanVideoFiles hashes and probes video files with a pool of workers, as both are mostly
+// waiting on disk or network IO. Results are written to the database in batches by a single writer.
+func scanVideoFiles(jobs []videoScanJob, vol models.Volume, probe func(job *videoScanJob) error, tlog *logrus.Entry) {
+	workers := config.Config.Storage.ScanConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan videoScanJob)
+	probed := make(chan models.File, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				// files that went away since the listing are skipped
+				if err := probe(&job); err != nil {
+					tlog.Errorf("Can't read %s, error: %s", job.path, err)
+					continue
+				}
+				probed <- job.file
+			}
+		}()
+	}
+
+	var writerWg sync.WaitGroup
+	writerWg.Add(1)
+	go videoFileDBWriter(&writerWg, probed, vol, len(jobs), tlog)
+
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	close(probed)
+	writerWg.Wait()
+}
+
+func videoFileDBWriter(wg *sync.WaitGroup, files <-chan models.File, vol models.Volume, total int, tlog *logrus.Entry) {
+	defer wg.Done()
+
+	const batchSize = 50
+	batch := make([]models.File, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := models.SaveFiles(batch); err != nil {
+			tlog.Errorf("Failed to save %v files, error: %s", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	done := 0
+	lastMessage := time.Now()
+	for fl := range files {
+		batch = append(batch, fl)
+		if len(batch) == batchSize {
+			flush()
+		}
+
+		done++
+		if done == total || time.Since(lastMessage) > time.Duration(config.Config.Advanced.ProgressTimeInterval)*time.Second {
+			tlog.Infof("Scanning %v (%v/%v)", vol.Path, done, total)
+			lastMessage = time.Now()
+		}
+	}
+	flush()
+}
+
+func scanLocalVideoFile(path string, vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
+	fStat, err := os.Stat(path)
+	if err != nil {
+		tlog.Errorf("Can't read %s, error: %s", path, err)
+		return
+	}
+	fl, _ := videoNeedsScan(db, path, fStat)
+	if err := probeLocalVideoFile(path, &fl, vol, tlog); err != nil {
+		tlog.Errorf("Can't read %s, error: %s", path, err)
+		return
+	}
+
+	err = fl.Save()
+	if err != nil {
+		tlog.Errorf("New file %s, but got error %s", path, err)
+	}
+}
+
+// probeLocalVideoFile fills in the size, times, oshash and video stream details of a file, without saving it
+func probeLocalVideoFile(path string, fl *models.File, vol models.Volume, tlog *logrus.Entry) error {
+	fStat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	fTimes, err := times.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	var birthtime time.Time
+	if fTimes.HasBirthTime() {
+		birthtime = fTimes.BirthTime()
+	} else {
+		birthtime = fTimes.ModTime()
+	}
+	fl.Size = fStat.Size()
+	fl.CreatedTime = birthtime
+	fl.UpdatedTime = fTimes.ModTime()
+	fl.VolumeID = vol.ID
+
+	hash, err := Hash(path)
+	if err == nil {
+		fl.OsHash = fmt.Sprintf("%x", hash)
+	}
+
+	probeVideoStream(path, filepath.Base(path), time.Second*5, fl, tlog)
+	return nil
+}
+
+// GetVideoInput returns a path or URL ffmpeg can read a file from. Remote files are read over
+// signed or authenticated URLs, put.io files are not supported.
+func GetVideoInput(f models.File) (string, error) {
+	switch f.Volume.Type {
+	case "local":
+		return f.GetPath(), nil
+	case "webdav":
+		return f.Volume.GetWebDAVClient().AuthURL(f.GetPath()), nil
+	case "s3":
+		client, meta, err := f.Volume.GetS3Client()
+		if err != nil {
+			return "", err
+		}
+		u, err := client.PresignedGetObject(context.Background(), meta.Bucket, f.Volume.S3ObjectKey(&f), 6*time.Hour, nil)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("can't read files of %v volumes with ffmpeg", f.Volume.Type)
+}
+
+// probeVideoStream runs ffprobe on input, which can be a path or URL, and fills in the video stream details.
+// The projection is guessed from the stream dimensions and hints in the filename.
+func probeVideoStream(input string, filename string, timeout time.Duration, fl *models.File, tlog *logrus.Entry) {
+	ffdata, err := ffprobe.GetProbeData(input, timeout)
+	if err != nil {
+		tlog.Error("Error running ffprobe", filename, err)
+	} else {
+		vs := ffdata.GetFirstVideoStream()
+		if vs == nil {
+			tlog.Error("No video stream in file ", filename)
+		} else {
+			if vs.BitRate != "" {
+				bitRate, _ := strconv.Atoi(vs.BitRate)
+				fl.VideoBitRate = bitRate
+			}
+			fl.VideoAvgFrameRate = vs.AvgFrameRate
+			fl.VideoCodecName = vs.CodecName
+			fl.VideoWidth = vs.Width
+			fl.VideoHeight = vs.Height
+			if dur, err := strconv.ParseFloat(vs.Duration, 64); err == nil {
+				fl.VideoDuration = dur
+			} else if ffdata.Format.DurationSeconds > 0.0 {
+				fl.VideoDuration = ffdata.Format.DurationSeconds
+			}
+			fl.HasAlpha = false
+
+			if vs.Height*2 == vs.Width || vs.Width > vs.Height {
+				fl.VideoProjection = "180_sbs"
+				nameparts := filenameSeparator.Split(strings.ToLower(filename), -1)
+				for i, part := range nameparts {
+					if part == "mkx200" || part == "mkx220" || part == "rf52" || part == "fisheye190" || part == "vrca220" || part == "flat" {
+						fl.VideoProjection = part
+						break
+					} else if part == "fisheye" || part == "f180" || part == "180f" {
+						fl.VideoProjection = "fisheye"
+						break
+					} else if i < len(nameparts)-1 && (part+"_"+nameparts[i+1] == "mono_360" || part+"_"+nameparts[i+1] == "mono_180") {
+						fl.VideoProjection = nameparts[i+1] + "_mono"
+						break
+					} else if i < len(nameparts)-1 && (part+"_"+nameparts[i+1] == "360_mono" || part+"_"+nameparts[i+1] == "180_mono") {
+						fl.VideoProjection = part + "_mono"
+						break
+					}
+				}
+				if fl.VideoProjection == "mkx200" || fl.VideoProjection == "mkx220" || fl.VideoProjection == "rf52" || fl.VideoProjection == "fisheye190" || fl.VideoProjection == "vrca220" {
+					// alpha passthrough only works with fisheye projections
+					for _, part := range nameparts {
+						if part == "alpha" {
+							fl.HasAlpha = true
+							break
+						}
+					}
+				}
+			}
+
+			if vs.Height == vs.Width {
+				fl.VideoProjection = "360_tb"
+			}
+
+			fl.CalculateFramerate()
+		}
+	}
+}
+
+func scanLocalScriptFile(path string, volID uint, db *gorm.DB) {
+	var fl models.File
+	db.Where(&models.File{
+		Path:     filepath.Dir(path),
+		Filename: filepath.Base(path),
+		Type:     "script",
+	}).FirstOrCreate(&fl)
+
+	fStat, _ := os.Stat(path)
+	fTimes, _ := times.Stat(path)
+
+	if fStat.Size() != fl.Size {
+		fl.Size = fStat.Size()
+		fl.HasHeatmap = false
+		fl.VideoDuration = 0.0
+	}
+
+	if fl.VideoDuration < 0.01 {
+		duration, err := getFunscriptDuration(path)
+		if err == nil {
+			fl.VideoDuration = duration
+		}
+	}
+
+	fl.CreatedTime = fTimes.ModTime()
+	fl.UpdatedTime = fTimes.ModTime()
+	fl.VolumeID = volID
+	fl.Save()
+}
+
 func scanPutIO(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
 	allowedVideoExt := getAllowedVideoExt()
 	client := vol.GetPutIOClient()
@@ -467,6 +638,188 @@ func scanPutIO(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
 	vol.LastScan = time.Now()
 	vol.Save()
 }
+
+func scanWebDAV(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
+	allowedVideoExt := getAllowedVideoExt()
+	client := vol.GetWebDAVClient()
+
+	var remoteFiles []webdav.FileInfo
+	err := client.Walk("/", func(fi webdav.FileInfo) error {
+		if !fi.IsDir && localFileType(fi.Name, allowedVideoExt) != "" {
+			remoteFiles = append(remoteFiles, fi)
+		}
+		return nil
+	})
+	if err != nil {
+		tlog.Errorf("Can't list %v, error: %s", vol.Path, err)
+		vol.IsAvailable = false
+		vol.Save()
+		return
+	}
+
+	// Walk
+	var videoProcList []videoScanJob
+	currentFiles := map[string]bool{}
+	for _, fi := range remoteFiles {
+		dir := path.Dir(fi.Path)
+		currentFiles[fi.Path] = true
+
+		var fl models.File
+		err := db.Where(&models.File{VolumeID: vol.ID, Path: dir, Filename: fi.Name}).First(&fl).Error
+		fileType := localFileType(fi.Name, allowedVideoExt)
+		if err == nil && fl.Size == fi.Size && (fileType != "video" || (fl.OsHash != "" && fl.VideoDuration != 0)) {
+			continue
+		}
+
+		fl.Path = dir
+		fl.Filename = fi.Name
+		fl.Type = fileType
+		fl.Size = fi.Size
+		fl.CreatedTime = fi.ModTime
+		fl.UpdatedTime = fi.ModTime
+		fl.VolumeID = vol.ID
+
+		if fileType == "video" {
+			videoProcList = append(videoProcList, videoScanJob{path: fi.Path, file: fl})
+		} else {
+			fl.Save()
+		}
+	}
+
+	// only the blocks needed for the oshash and whatever ffprobe seeks to are downloaded
+	scanVideoFiles(videoProcList, vol, func(job *videoScanJob) error {
+		hash, err := HashReaderAt(client.ReaderAt(job.path), job.file.Size)
+		if err == nil {
+			job.file.OsHash = fmt.Sprintf("%x", hash)
+		}
+		probeVideoStream(client.AuthURL(job.path), job.file.Filename, time.Second*30, &job.file, tlog)
+		return nil
+	}, tlog)
+
+	var scene models.Scene
+	// Check if files are present in listing
+	allFiles := vol.Files()
+	for i := range allFiles {
+		if !currentFiles[path.Join(allFiles[i].Path, allFiles[i].Filename)] {
+			log.Info(allFiles[i].GetPath())
+			db.Delete(&allFiles[i])
+			if allFiles[i].SceneID != 0 {
+				scene.GetIfExistByPK(allFiles[i].SceneID)
+				scene.UpdateStatus()
+			}
+		}
+	}
+
+	// Update volume info
+	vol.IsAvailable = true
+	vol.LastScan = time.Now()
+	vol.Save()
+}
+
+func scanS3(vol models.Volume, db *gorm.DB, tlog *logrus.Entry) {
+	allowedVideoExt := getAllowedVideoExt()
+	client, meta, err := vol.GetS3Client()
+	if err != nil {
+		tlog.Errorf("Can't connect to %v, error: %s", vol.Path, err)
+		vol.IsAvailable = false
+		vol.Save()
+		return
+	}
+
+	prefix := strings.Trim(meta.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var objects []minio.ObjectInfo
+	for obj := range client.ListObjects(context.Background(), meta.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			err = obj.Err
+			break
+		}
+		if !strings.HasSuffix(obj.Key, "/") && localFileType(obj.Key, allowedVideoExt) != "" {
+			objects = append(objects, obj)
+		}
+	}
+	if err != nil {
+		tlog.Errorf("Can't list %v, error: %s", vol.Path, err)
+		vol.IsAvailable = false
+		vol.Save()
+		return
+	}
+
+	// Walk
+	var videoProcList []videoScanJob
+	currentFiles := map[string]bool{}
+	for _, obj := range objects {
+		currentFiles[obj.Key] = true
+
+		// file paths start with the volume path, followed by the "directories" below the prefix
+		rel := strings.TrimPrefix(obj.Key, prefix)
+		dir := vol.Path
+		if d := path.Dir(rel); d != "." {
+			dir += "/" + d
+		}
+		name := path.Base(rel)
+
+		var fl models.File
+		err := db.Where(&models.File{VolumeID: vol.ID, Path: dir, Filename: name}).First(&fl).Error
+		fileType := localFileType(name, allowedVideoExt)
+		if err == nil && fl.Size == obj.Size && (fileType != "video" || (fl.OsHash != "" && fl.VideoDuration != 0)) {
+			continue
+		}
+
+		fl.Path = dir
+		fl.Filename = name
+		fl.Type = fileType
+		fl.Size = obj.Size
+		fl.CreatedTime = obj.LastModified
+		fl.UpdatedTime = obj.LastModified
+		fl.VolumeID = vol.ID
+
+		if fileType == "video" {
+			videoProcList = append(videoProcList, videoScanJob{path: obj.Key, file: fl})
+		} else {
+			fl.Save()
+		}
+	}
+
+	// minio objects are read with ranged GETs, so only the blocks needed for the oshash are downloaded
+	scanVideoFiles(videoProcList, vol, func(job *videoScanJob) error {
+		obj, err := client.GetObject(context.Background(), meta.Bucket, job.path, minio.GetObjectOptions{})
+		if err == nil {
+			hash, err := HashReaderAt(obj, job.file.Size)
+			if err == nil {
+				job.file.OsHash = fmt.Sprintf("%x", hash)
+			}
+			obj.Close()
+		}
+		if u, err := client.PresignedGetObject(context.Background(), meta.Bucket, job.path, time.Hour, nil); err == nil {
+			probeVideoStream(u.String(), job.file.Filename, time.Second*30, &job.file, tlog)
+		}
+		return nil
+	}, tlog)
+
+	var scene models.Scene
+	// Check if files are present in listing
+	allFiles := vol.Files()
+	for i := range allFiles {
+		if !currentFiles[vol.S3ObjectKey(&allFiles[i])] {
+			log.Info(allFiles[i].GetPath())
+			db.Delete(&allFiles[i])
+			if allFiles[i].SceneID != 0 {
+				scene.GetIfExistByPK(allFiles[i].SceneID)
+				scene.UpdateStatus()
+			}
+		}
+	}
+
+	// Update volume info
+	vol.IsAvailable = true
+	vol.LastScan = time.Now()
+	vol.Save()
+}
+
 func RefreshSceneStatuses() {
 	// refreshes the status of all scenes
 	tlog := log.WithFields(logrus.Fields{"task": "rescan"})
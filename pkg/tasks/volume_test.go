@@ -0,0 +1,53 @@
+package tasks
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+func TestScanSkipsVanishedFiles(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.Volume{})
+
+	vol := models.Volume{Type: "local", Path: t.TempDir(), IsEnabled: true, IsAvailable: true}
+	commonDb.Create(&vol)
+
+	// listed, then deleted before it was probed
+	jobs := []videoScanJob{{path: filepath.Join(vol.Path, "gone.mp4"), file: models.File{Path: vol.Path, Filename: "gone.mp4"}}}
+	tlog := log.WithFields(logrus.Fields{"task": "rescan"})
+	scanVideoFiles(jobs, vol, func(job *videoScanJob) error {
+		return probeLocalVideoFile(job.path, &job.file, vol, tlog)
+	}, tlog)
+
+	var count int
+	commonDb.Model(&models.File{}).Where("path = ? and filename = ?", vol.Path, "gone.mp4").Count(&count)
+	if count != 0 {
+		t.Errorf("expected the vanished file to be skipped, %d saved", count)
+	}
+}
+
+func TestRemovePathKeepsSiblings(t *testing.T) {
+	commonDb, _ := models.GetCommonDB()
+	commonDb.AutoMigrate(&models.Volume{})
+
+	vol := models.Volume{Type: "local", Path: t.TempDir(), IsEnabled: true, IsAvailable: true}
+	commonDb.Create(&vol)
+
+	removed := filepath.Join(vol.Path, "Site_A")
+	commonDb.Create(&models.File{VolumeID: vol.ID, Path: removed, Filename: "a.mp4"})
+	commonDb.Create(&models.File{VolumeID: vol.ID, Path: filepath.Join(removed, "sub"), Filename: "b.mp4"})
+	commonDb.Create(&models.File{VolumeID: vol.ID, Path: filepath.Join(vol.Path, "Site-A", "sub"), Filename: "c.mp4"})
+	commonDb.Create(&models.File{VolumeID: vol.ID, Path: filepath.Join(vol.Path, "Site_AB"), Filename: "d.mp4"})
+
+	w := &volumeWatcher{vol: vol, tlog: log.WithFields(logrus.Fields{"task": "watch"})}
+	w.removePath(commonDb, removed)
+
+	var kept []models.File
+	commonDb.Where("volume_id = ?", vol.ID).Order("filename").Find(&kept)
+	if len(kept) != 2 || kept[0].Filename != "c.mp4" || kept[1].Filename != "d.mp4" {
+		t.Errorf("expected only the siblings kept, got %+v", kept)
+	}
+}
@@ -0,0 +1,316 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/xbapps/xbvr/pkg/common"
+	"github.com/xbapps/xbvr/pkg/config"
+	"github.com/xbapps/xbvr/pkg/models"
+)
+
+// files are only processed once no new events arrived for them during this period,
+// so partially downloaded or copied files are not probed and hashed over and over
+const volumeWatcherSettleTime = 10 * time.Second
+
+type volumeWatcher struct {
+	vol     models.Volume
+	watcher *fsnotify.Watcher
+	tlog    *logrus.Entry
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+	done    chan struct{}
+}
+
+var (
+	volumeWatchers   = map[uint]*volumeWatcher{}
+	volumeWatchersMu sync.Mutex
+)
+
+// watchVolume starts a filesystem watcher for a local volume, so it doesn't need to be walked again by
+// the periodic rescan. Volumes that can't be watched, eg because the inotify watch limit is reached,
+// are left to the periodic rescan. Note that changes made on the remote side of network shares are
+// usually not reported by the OS.
+func watchVolume(vol models.Volume) {
+	if !config.Config.Storage.WatchVolumes || IsVolumeWatched(vol.ID) {
+		return
+	}
+
+	w, err := newVolumeWatcher(vol)
+	if err != nil {
+		w.tlog.Warnf("Can't watch %v, falling back to periodic rescans: %v", vol.Path, err)
+		return
+	}
+
+	volumeWatchersMu.Lock()
+	volumeWatchers[vol.ID] = w
+	volumeWatchersMu.Unlock()
+
+	go w.run()
+	w.tlog.Infof("Watching %v for changes", vol.Path)
+}
+
+func StopVolumeWatcher(id uint) {
+	volumeWatchersMu.Lock()
+	defer volumeWatchersMu.Unlock()
+
+	if w, ok := volumeWatchers[id]; ok {
+		w.stop()
+		delete(volumeWatchers, id)
+	}
+}
+
+func StopVolumeWatchers() {
+	volumeWatchersMu.Lock()
+	defer volumeWatchersMu.Unlock()
+
+	for id, w := range volumeWatchers {
+		w.stop()
+		delete(volumeWatchers, id)
+	}
+}
+
+func IsVolumeWatched(id uint) bool {
+	volumeWatchersMu.Lock()
+	defer volumeWatchersMu.Unlock()
+
+	_, ok := volumeWatchers[id]
+	return ok
+}
+
+func newVolumeWatcher(vol models.Volume) (*volumeWatcher, error) {
+	w := &volumeWatcher{
+		vol:     vol,
+		tlog:    log.WithFields(logrus.Fields{"task": "rescan"}),
+		pending: map[string]time.Time{},
+		done:    make(chan struct{}),
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return w, err
+	}
+	w.watcher = watcher
+
+	// fsnotify is not recursive, every directory below the volume needs its own watch
+	if err := w.addTree(vol.Path, false); err != nil {
+		watcher.Close()
+		return w, err
+	}
+	return w, nil
+}
+
+func (w *volumeWatcher) stop() {
+	close(w.done)
+	w.watcher.Close()
+}
+
+// unwatch removes the watcher from the registry, the next cron rescan will walk the volume again
+func (w *volumeWatcher) unwatch() {
+	volumeWatchersMu.Lock()
+	defer volumeWatchersMu.Unlock()
+
+	if volumeWatchers[w.vol.ID] == w {
+		w.stop()
+		delete(volumeWatchers, w.vol.ID)
+	}
+}
+
+// addTree adds watches for dir and all directories below it. When queueFiles is set, the files
+// found are queued as well, as no events are received for files that were moved in together
+// with their directory.
+func (w *volumeWatcher) addTree(dir string, queueFiles bool) error {
+	return filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if f.IsDir() {
+			if path != dir && strings.HasPrefix(f.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return w.watcher.Add(path)
+		}
+		if queueFiles {
+			w.queue(path)
+		}
+		return nil
+	})
+}
+
+func (w *volumeWatcher) queue(path string) {
+	w.mu.Lock()
+	w.pending[path] = time.Now()
+	w.mu.Unlock()
+}
+
+func (w *volumeWatcher) run() {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			if err == fsnotify.ErrEventOverflow {
+				// events were dropped, only a full walk can catch up with what was missed
+				w.tlog.Warnf("Missed changes on %v, rescanning volume", w.vol.Path)
+				go RescanVolumes(int(w.vol.ID))
+			} else {
+				w.tlog.Error("Volume watcher error ", err)
+			}
+		case <-ticker.C:
+			w.processPending()
+		}
+	}
+}
+
+func (w *volumeWatcher) handleEvent(event fsnotify.Event) {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+		return
+	}
+
+	if event.Has(fsnotify.Create) {
+		if f, err := os.Stat(event.Name); err == nil && f.IsDir() {
+			if err := w.addTree(event.Name, true); err != nil {
+				w.tlog.Warnf("Can't watch %v, falling back to periodic rescans: %v", event.Name, err)
+				w.unwatch()
+			}
+			return
+		}
+	}
+
+	// removals and renames are queued too, processing checks whether the path still exists
+	w.queue(event.Name)
+}
+
+func (w *volumeWatcher) processPending() {
+	// leave the volume alone while a full rescan is running, queued paths are retried afterwards
+	if models.CheckLock("rescan") {
+		return
+	}
+
+	var ready []string
+	w.mu.Lock()
+	for path, last := range w.pending {
+		if time.Since(last) > volumeWatcherSettleTime {
+			ready = append(ready, path)
+			delete(w.pending, path)
+		}
+	}
+	w.mu.Unlock()
+
+	if len(ready) == 0 {
+		return
+	}
+
+	if !w.vol.IsMounted() {
+		// don't drop the files of an unmounted volume, CheckVolumes marks it unavailable instead
+		w.tlog.Warnf("%v is no longer mounted, stopped watching", w.vol.Path)
+		w.unwatch()
+		models.CheckVolumes()
+		return
+	}
+
+	db, _ := models.GetDB()
+	defer db.Close()
+
+	allowedVideoExt := getAllowedVideoExt()
+	scriptsChanged := false
+	var changed []string
+	for _, path := range ready {
+		f, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			w.removePath(db, path)
+			continue
+		}
+		if err != nil || f.IsDir() {
+			continue
+		}
+
+		switch localFileType(path, allowedVideoExt) {
+		case "video":
+			if _, ok := videoNeedsScan(db, path, f); !ok {
+				continue
+			}
+			w.tlog.Infof("Scanning %v", path)
+			scanLocalVideoFile(path, w.vol, db, w.tlog)
+		case "script":
+			scanLocalScriptFile(path, w.vol.ID, db)
+			scriptsChanged = true
+		case "hsp":
+			ScanLocalHspFile(path, w.vol.ID, 0)
+		case "subtitles":
+			ScanLocalSubtitlesFile(path, w.vol.ID, 0)
+		default:
+			continue
+		}
+		changed = append(changed, path)
+	}
+
+	if len(changed) > 0 {
+		var files []models.File
+		for _, path := range changed {
+			var fl models.File
+			if db.Where(&models.File{Path: filepath.Dir(path), Filename: filepath.Base(path)}).First(&fl).Error == nil {
+				if fl.SceneID == 0 {
+					files = append(files, fl)
+				} else {
+					var scene models.Scene
+					scene.GetIfExistByPK(fl.SceneID)
+					scene.UpdateStatus()
+				}
+			}
+		}
+		matchFilesToScenes(db, files, w.tlog)
+
+		if scriptsChanged {
+			GenerateHeatmaps(w.tlog)
+		}
+	}
+
+	// Inform UI about state change
+	common.PublishWS("state.change.optionsStorage", nil)
+}
+
+// removePath deletes the files stored for path, or for everything below it if path was a directory
+func (w *volumeWatcher) removePath(db *gorm.DB, path string) {
+	// a range instead of like, the _ and % of names would match siblings of the directory
+	dir := path + string(filepath.Separator)
+	var candidates []models.File
+	db.Where("volume_id = ? and ((path = ? and filename = ?) or path = ? or (path >= ? and path < ?))",
+		w.vol.ID, filepath.Dir(path), filepath.Base(path), path, dir, path+string(filepath.Separator+1)).Find(&candidates)
+
+	var files []models.File
+	for _, f := range candidates {
+		// the database may collate case insensitive
+		if f.Path == path || strings.HasPrefix(f.Path, dir) || (f.Path == filepath.Dir(path) && f.Filename == filepath.Base(path)) {
+			files = append(files, f)
+		}
+	}
+
+	var scene models.Scene
+	for i := range files {
+		w.tlog.Infof("Removed %v", files[i].GetPath())
+		db.Delete(&files[i])
+		if files[i].SceneID != 0 {
+			scene.GetIfExistByPK(files[i].SceneID)
+			scene.UpdateStatus()
+		}
+	}
+}
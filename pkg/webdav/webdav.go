@@ -0,0 +1,327 @@
+// Package webdav implements the small subset of WebDAV needed to scan and stream files from a
+// remote server: directory listings, ranged reads and proxying of playback requests.
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested path does not exist on the server
+	ErrNotFound = errors.New("webdav: not found")
+
+	// metadataTimeout limits listings and stat calls, file transfers are only limited by their context
+	metadataTimeout = 30 * time.Second
+)
+
+// Client talks to a single WebDAV share. All paths passed to the client are relative to BaseURL
+// and use forward slashes, eg "/Videos/scene.mp4".
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// FileInfo describes a file or collection returned by a listing
+type FileInfo struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	ETag    string
+}
+
+func NewClient(baseURL string, username string, password string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Username:   username,
+		Password:   password,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// URL returns the address of p on the server, without credentials
+func (c *Client) URL(p string) string {
+	segments := strings.Split(cleanPath(p), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return c.BaseURL + strings.Join(segments, "/")
+}
+
+// AuthURL returns the address of p with the credentials embedded, for tools like ffprobe that
+// can only be given a URL
+func (c *Client) AuthURL(p string) string {
+	u, err := url.Parse(c.URL(p))
+	if err != nil {
+		return c.URL(p)
+	}
+	if c.Username != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return u.String()
+}
+
+func (c *Client) NewRequest(ctx context.Context, method string, p string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.URL(p), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return req, nil
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+    <d:getetag/>
+  </d:prop>
+</d:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ETag          string `xml:"DAV: getetag"`
+			} `xml:"DAV: prop"`
+			Status string `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+func (c *Client) propfind(p string, depth string) ([]FileInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	req, err := c.NewRequest(ctx, "PROPFIND", p, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", depth)
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMultiStatus:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("webdav: PROPFIND %v returned %v", p, resp.Status)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []FileInfo
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		rel := strings.TrimPrefix(href.Path, strings.TrimSuffix(base.Path, "/"))
+		fi := FileInfo{Path: cleanPath(rel)}
+		fi.Name = path.Base(fi.Path)
+
+		for _, ps := range r.Propstats {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			fi.IsDir = ps.Prop.ResourceType.Collection != nil
+			fi.Size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			fi.ModTime, _ = http.ParseTime(ps.Prop.LastModified)
+			fi.ETag = strings.Trim(ps.Prop.ETag, `"`)
+		}
+		out = append(out, fi)
+	}
+	return out, nil
+}
+
+// Stat returns the details of a single file or collection
+func (c *Client) Stat(p string) (FileInfo, error) {
+	list, err := c.propfind(p, "0")
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if len(list) == 0 {
+		return FileInfo{}, ErrNotFound
+	}
+	return list[0], nil
+}
+
+// ReadDir lists the direct children of a collection
+func (c *Client) ReadDir(dir string) ([]FileInfo, error) {
+	list, err := c.propfind(dir, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	// the collection itself is part of the response
+	var out []FileInfo
+	for _, fi := range list {
+		if fi.Path != cleanPath(dir) {
+			out = append(out, fi)
+		}
+	}
+	return out, nil
+}
+
+// Walk calls fn for every file and collection below dir. Depth 1 listings are used, as many
+// servers refuse infinite depth PROPFIND requests.
+func (c *Client) Walk(dir string, fn func(FileInfo) error) error {
+	list, err := c.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range list {
+		if err := fn(fi); err != nil {
+			return err
+		}
+		if fi.IsDir {
+			if err := c.Walk(fi.Path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadAt reads len(buf) bytes of p starting at off with a ranged GET
+func (c *Client) ReadAt(p string, buf []byte, off int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	req, err := c.NewRequest(ctx, http.MethodGet, p, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(buf))-1))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// the server ignored the range, skip ahead to the requested offset
+		if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+			return 0, err
+		}
+	case http.StatusNotFound:
+		return 0, ErrNotFound
+	default:
+		return 0, fmt.Errorf("webdav: GET %v returned %v", p, resp.Status)
+	}
+	return io.ReadFull(resp.Body, buf)
+}
+
+// ReaderAt returns an io.ReaderAt reading p with ranged GETs
+func (c *Client) ReaderAt(p string) io.ReaderAt {
+	return remoteFile{client: c, path: p}
+}
+
+type remoteFile struct {
+	client *Client
+	path   string
+}
+
+func (f remoteFile) ReadAt(buf []byte, off int64) (int, error) {
+	return f.client.ReadAt(f.path, buf, off)
+}
+
+// Remove deletes a file from the server
+func (c *Client) Remove(p string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	req, err := c.NewRequest(ctx, http.MethodDelete, p, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("webdav: DELETE %v returned %v", p, resp.Status)
+	}
+	return nil
+}
+
+// headers passed on between the player and the server when proxying
+var (
+	proxyRequestHeaders  = []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match"}
+	proxyResponseHeaders = []string{"Accept-Ranges", "Content-Length", "Content-Range", "Content-Type", "ETag", "Last-Modified"}
+)
+
+// Proxy streams p to w, passing on range requests so players can seek
+func (c *Client) Proxy(w http.ResponseWriter, r *http.Request, p string) {
+	req, err := c.NewRequest(r.Context(), r.Method, p, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, h := range proxyRequestHeaders {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range proxyResponseHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
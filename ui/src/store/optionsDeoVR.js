@@ -28,6 +28,11 @@ const state = {
     video_sort_seq: '',
     script_sort_seq: '',
     subtitle_sort_seq: ''
+  },
+  hls: {
+    hls_enabled: true,
+    hls_max_transcodes: 2,
+    hls_cache_size_gb: 20
   }
 }
 
@@ -60,12 +65,15 @@ const actions = {
         state.players.subtitle_sort_seq = data.config.interfaces.players.subtitle_sort_seq
         state.heresphere.multitrack_cast_cuepoints = data.config.interfaces.heresphere.multitrack_cast_cuepoints
         state.heresphere.retain_non_hsp_cuepoints = data.config.interfaces.heresphere.retain_non_hsp_cuepoints
+        state.hls.hls_enabled = data.config.interfaces.hls.enabled
+        state.hls.hls_max_transcodes = data.config.interfaces.hls.max_transcodes
+        state.hls.hls_cache_size_gb = data.config.interfaces.hls.cache_size_gb
         state.loading = false        
       })
   },
   async save ({ state }, enabled) {
     state.loading = true
-    ky.put('/api/options/interface/deovr', { json: { ...state.deovr, ...state.heresphere, ...state.players } })
+    ky.put('/api/options/interface/deovr', { json: { ...state.deovr, ...state.heresphere, ...state.players, ...state.hls } })
       .json()
       .then(data => {
         state.loading = false
@@ -4,6 +4,12 @@ const state = {
   items: [],
   options: {
     match_ohash: false,
+    match_content: true,
+    watch_volumes: true,
+    scan_concurrency: 4,
+    organize_template: '',
+    organize_target: 0,
+    sidecars_enabled: false,
     forbidden_video_ext: [],
     video_ext: [],
     default_video_ext: [],
@@ -19,6 +25,12 @@ const actions = {
     .then(data => {
       state.items = data.volumes
       state.options.match_ohash = data.match_ohash
+      state.options.match_content = data.match_content
+      state.options.watch_volumes = data.watch_volumes
+      state.options.scan_concurrency = data.scan_concurrency
+      state.options.organize_template = data.organize_template
+      state.options.organize_target = data.organize_target
+      state.options.sidecars_enabled = data.sidecars_enabled
       state.options.forbidden_video_ext = data.forbidden_video_ext
       state.options.video_ext = data.video_ext
       state.options.default_video_ext = data.default_video_ext
@@ -4,6 +4,10 @@ const state = {
   tpdb: {
     apiToken: '',
   },
+  stash: {
+    url: '',
+    apiKey: '',
+  },
   scrapers: [],
 }
 
@@ -15,6 +19,8 @@ const actions = {
       .json()
       .then(data => {
         state.tpdb.apiToken = data.config.vendor.tpdb.apiToken
+        state.stash.url = data.config.vendor.stash.url
+        state.stash.apiKey = data.config.vendor.stash.apiKey
         state.scrapers = data.scrapers        
       })
   },
@@ -9,14 +9,16 @@ const state = {
   sessionEnd: '',
   currentFileID: 0,
   currentSceneID: 0,
+  sessions: [],
 
   currentScene: {},
-  history: []
+  history: [],
+  commands: []
 }
 
 const mutations = {
   setState (state, payload) {
-    const p = ['connected', 'deovrHost', 'isPlaying', 'sessionStart', 'sessionEnd', 'currentFileID', 'currentSceneID', 'currentPosition']
+    const p = ['connected', 'deovrHost', 'isPlaying', 'sessionStart', 'sessionEnd', 'currentFileID', 'currentSceneID', 'currentPosition', 'sessions']
     p.forEach(x => {
       if (payload[x]) {
         state[x] = payload[x]
@@ -28,6 +30,9 @@ const mutations = {
   },
   addToHistory (state, payload) {
     state.history.push(payload)
+  },
+  addCommand (state, payload) {
+    state.commands = [payload, ...state.commands].slice(0, 20)
   }
 }
 
@@ -42,6 +47,12 @@ const actions = {
     }
 
     commit('setState', payload)
+  },
+  async command ({ commit }, payload) {
+    return ky.post('/api/remote/command', { json: payload }).json()
+  },
+  processCommand ({ commit }, payload) {
+    commit('addCommand', payload)
   }
 }
 